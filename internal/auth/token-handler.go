@@ -9,14 +9,35 @@ import (
 )
 
 // RepaceAuthTokenPlaceholderInRequestHeader replaces the <auth-token> placeholder
-// in request headers with the corresponding token from the token store, if available.
-// Returns nothing.
+// in request headers (and test case override and scenario step headers) with
+// the corresponding token from the token store, if available. Scenario steps
+// which reference a request with an own pre-request use its token, the other
+// steps the token of the scenario pre-request. Returns nothing.
 func RepaceAuthTokenPlaceholderInRequestHeader(req *loader.APIRequest, tokenStore *TokenStore) {
-	const headerReplacementIndicator = "<auth-token>"
+	replaceAuthTokenPlaceholder(req.Request.Headers, req.PreRequestID, tokenStore)
+
+	for _, testCase := range req.TestCases {
+		if testCase.Request != nil {
+			replaceAuthTokenPlaceholder(testCase.Request.Headers, req.PreRequestID, tokenStore)
+		}
+	}
+
+	for _, step := range req.ScenarioSteps() {
+		lookupID := step.Target.PreRequestID
+		if lookupID == "" {
+			lookupID = req.PreRequestID
+		}
 
-	lookupID := req.PreRequestID
+		replaceAuthTokenPlaceholder(step.Target.Request.Headers, lookupID, tokenStore)
+	}
+}
+
+// replaceAuthTokenPlaceholder replaces the <auth-token> placeholder in the
+// headers (in-place) with the token of the auth request (lookupID).
+func replaceAuthTokenPlaceholder(headers []string, lookupID string, tokenStore *TokenStore) {
+	const headerReplacementIndicator = "<auth-token>"
 
-	for idx, header := range req.Request.Headers {
+	for idx, header := range headers {
 		if !strings.Contains(header, headerReplacementIndicator) {
 			continue
 		}
@@ -26,7 +47,7 @@ func RepaceAuthTokenPlaceholderInRequestHeader(req *loader.APIRequest, tokenStor
 
 			logger.Debugf(`Token "...%s" found for auth request "%s".`, lastTokenChars, lookupID)
 
-			req.Request.Headers[idx] = strings.ReplaceAll(header, headerReplacementIndicator, token)
+			headers[idx] = strings.ReplaceAll(header, headerReplacementIndicator, token)
 
 			break
 		}
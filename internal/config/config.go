@@ -2,7 +2,9 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"slices"
 
 	"github.com/sven-seyfert/apiprobe/internal/logger"
 )
@@ -14,8 +16,75 @@ type Heartbeat struct {
 	LastHeartbeatTime string `json:"lastHeartbeatTime"`
 }
 
+type Snapshots struct {
+	RequireApproval bool `json:"requireApproval"`
+}
+
+// AlertPolicy defines when failures are notified. Mode "always" (default)
+// notifies every failure, "consecutive" only after ConsecutiveFailures
+// failures in a row and "transitions" only on state changes (green to red).
+type AlertPolicy struct {
+	Mode                string  `json:"mode"`
+	ConsecutiveFailures int     `json:"consecutiveFailures"`
+	HistoryWindow       int     `json:"historyWindow"`
+	FlakyThreshold      float64 `json:"flakyThreshold"`
+}
+
+// Email defines the SMTP server of the email notification. Security is
+// "starttls" (default), "tls" (implicit TLS) or "none". Username and password
+// support '<secret-…>' placeholders. Recipients are defined per channel,
+// Attachments lists the report formats to attach ("json", "html", "junit").
+type Email struct {
+	Active      bool                `json:"active"`
+	Host        string              `json:"host"`
+	Port        int                 `json:"port"`
+	Security    string              `json:"security"`
+	Username    string              `json:"username"`
+	Password    string              `json:"password"`
+	From        string              `json:"from"`
+	Recipients  map[string][]string `json:"recipients"`
+	Attachments []string            `json:"attachments"`
+}
+
+// GenericWebhook defines a HTTP notification with a user-defined payload,
+// rendered from the Go text/template file PayloadTemplate. Method defaults
+// to POST. The webhook URLs (per channel) and header values support
+// '<secret-…>' placeholders.
+type GenericWebhook struct {
+	Name            string            `json:"name"`
+	Active          bool              `json:"active"`
+	Method          string            `json:"method"`
+	Headers         map[string]string `json:"headers"`
+	PayloadTemplate string            `json:"payloadTemplate"`
+	Webhooks        map[string]string `json:"webhooks"`
+}
+
+// Delivery defines the retries (with exponential backoff) and the timeout
+// of a notification delivery. Undelivered notifications are spooled to disk
+// and retried on the next run, until they are older than MaxSpoolAgeHours.
+type Delivery struct {
+	Retries          int `json:"retries"`
+	BackoffSeconds   int `json:"backoffSeconds"`
+	TimeoutSeconds   int `json:"timeoutSeconds"`
+	MaxSpoolAgeHours int `json:"maxSpoolAgeHours"`
+}
+
+// Route sends the matching issues to its channels (of all notifiers). An
+// issue matches if it matches every set criterion: one of the IDs, one of
+// the tags and one of the issue types ("request-error", "format-error",
+// "changed", "latency", "hook-error").
+type Route struct {
+	IDs      []string `json:"ids"`
+	Tags     []string `json:"tags"`
+	Issues   []string `json:"issues"`
+	Channels []string `json:"channels"`
+}
+
 type Notification struct {
-	WebEx *struct {
+	AlertPolicy AlertPolicy `json:"alertPolicy"`
+	Delivery    Delivery    `json:"delivery"`
+	Routes      []Route     `json:"routes"`
+	WebEx       *struct {
 		Active   bool              `json:"active"`
 		Webhooks map[string]string `json:"webhooks"`
 	} `json:"webEx"`
@@ -23,16 +92,29 @@ type Notification struct {
 		Active   bool              `json:"active"`
 		Webhooks map[string]string `json:"webhooks"`
 	} `json:"msTeams"`
+	Slack *struct {
+		Active   bool              `json:"active"`
+		Webhooks map[string]string `json:"webhooks"`
+	} `json:"slack"`
+	Email   *Email           `json:"email"`
+	Generic []GenericWebhook `json:"generic"`
 }
 
 type Config struct {
 	DebugMode    bool         `json:"debugMode"`
+	Paths        Paths        `json:"paths"`
 	Heartbeat    Heartbeat    `json:"heartbeat"`
+	Snapshots    Snapshots    `json:"snapshots"`
 	Notification Notification `json:"notification"`
+
+	// Workspace directory and the effective paths (see ResolvePaths).
+	Workspace string `json:"-"`
+	Locations Paths  `json:"-"`
 }
 
 // Load opens the JSON configuration file, decodes its contents into
-// a Config struct and returns the loaded configuration or an error.
+// a Config struct, validates the routes and returns the loaded
+// configuration or an error.
 func Load(filePath string) (*Config, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -52,5 +134,27 @@ func Load(filePath string) (*Config, error) {
 		return nil, err
 	}
 
+	if err = validateRoutes(cfg.Notification.Routes); err != nil {
+		logger.Errorf(`Failure validating config file "%s". Error: %v`, filePath, err)
+
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+// validateRoutes returns an error if a route contains an unknown issue type
+// (the issue types of the report package).
+func validateRoutes(routes []Route) error {
+	issueTypes := []string{"request-error", "format-error", "changed", "latency", "hook-error"}
+
+	for idx, route := range routes {
+		for _, issue := range route.Issues {
+			if !slices.Contains(issueTypes, issue) {
+				return fmt.Errorf(`route %d: unknown issue type "%s"`, idx+1, issue)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+)
+
+func TestLoadRejectsUnknownRouteIssue(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "apiprobe.json")
+
+	for issues, valid := range map[string]bool{
+		`["request-error", "hook-error"]`: true,
+		`["request-error", "timeout"]`:    false,
+	} {
+		content := `{"notification": {"routes": [{"issues": ` + issues + `, "channels": ["oncall"]}]}}`
+
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config file: %v", err)
+		}
+
+		_, err := config.Load(configFile)
+		if valid && err != nil {
+			t.Errorf("issues %s: unexpected error: %v", issues, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("issues %s: expected an error", issues)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Paths defines the locations of the files and directories APIProbe reads
+// and writes. Relative paths (of config keys) are relative to the workspace.
+type Paths struct {
+	Config    string `json:"-"`
+	Input     string `json:"input,omitempty"`
+	Output    string `json:"output,omitempty"`
+	Database  string `json:"database,omitempty"`
+	Seed      string `json:"seed,omitempty"`
+	Reports   string `json:"reports,omitempty"`
+	Logs      string `json:"logs,omitempty"`
+	Curl      string `json:"curl,omitempty"`
+	Spool     string `json:"spool,omitempty"`
+	Templates string `json:"templates,omitempty"`
+}
+
+// Environment variables of the workspace and the paths.
+const (
+	EnvWorkspace = "APIPROBE_WORKSPACE"
+	EnvConfig    = "APIPROBE_CONFIG"
+	EnvInput     = "APIPROBE_INPUT_DIR"
+	EnvOutput    = "APIPROBE_OUTPUT_DIR"
+	EnvDatabase  = "APIPROBE_DB"
+	EnvSeed      = "APIPROBE_SEED"
+	EnvReports   = "APIPROBE_REPORTS_DIR"
+	EnvLogs      = "APIPROBE_LOGS_DIR"
+	EnvCurl      = "APIPROBE_CURL"
+	EnvSpool     = "APIPROBE_SPOOL_DIR"
+	EnvTemplates = "APIPROBE_TEMPLATES_DIR"
+)
+
+// DefaultPaths returns the default layout of the workspace directory
+// (like './data/input' for the workspace '.').
+func DefaultPaths(workspace string) Paths {
+	return Paths{
+		Config:    filepath.Join(workspace, "config", "apiprobe.json"),
+		Input:     filepath.Join(workspace, "data", "input"),
+		Output:    filepath.Join(workspace, "data", "output"),
+		Database:  filepath.Join(workspace, "db", "store.db"),
+		Seed:      filepath.Join(workspace, "db", "seed.csv"),
+		Reports:   filepath.Join(workspace, "reports"),
+		Logs:      filepath.Join(workspace, "logs"),
+		Curl:      filepath.Join(workspace, "lib", "curl.exe"),
+		Spool:     filepath.Join(workspace, "spool"),
+		Templates: filepath.Join(workspace, "config", "templates"),
+	}
+}
+
+// Workspace returns the workspace directory of the flag, of the
+// APIPROBE_WORKSPACE environment variable or the working directory '.'.
+func Workspace(flagValue string) string {
+	return firstNonEmpty(flagValue, os.Getenv(EnvWorkspace), ".")
+}
+
+// EnvPaths returns the paths set by environment variables (empty if unset).
+func EnvPaths() Paths {
+	return Paths{
+		Config:    os.Getenv(EnvConfig),
+		Input:     os.Getenv(EnvInput),
+		Output:    os.Getenv(EnvOutput),
+		Database:  os.Getenv(EnvDatabase),
+		Seed:      os.Getenv(EnvSeed),
+		Reports:   os.Getenv(EnvReports),
+		Logs:      os.Getenv(EnvLogs),
+		Curl:      os.Getenv(EnvCurl),
+		Spool:     os.Getenv(EnvSpool),
+		Templates: os.Getenv(EnvTemplates),
+	}
+}
+
+// ResolvePaths returns the effective paths. For each location the first
+// set value wins: flag, environment variable, config key (relative to the
+// workspace) and finally the default layout of the workspace.
+func ResolvePaths(workspace string, flagPaths Paths, envPaths Paths, keyPaths Paths) Paths {
+	defaults := DefaultPaths(workspace)
+
+	resolve := func(flagValue string, envValue string, keyValue string, defaultValue string) string {
+		if keyValue != "" && !filepath.IsAbs(keyValue) {
+			keyValue = filepath.Join(workspace, keyValue)
+		}
+
+		return firstNonEmpty(flagValue, envValue, keyValue, defaultValue)
+	}
+
+	return Paths{
+		Config:    firstNonEmpty(flagPaths.Config, envPaths.Config, defaults.Config),
+		Input:     resolve(flagPaths.Input, envPaths.Input, keyPaths.Input, defaults.Input),
+		Output:    resolve(flagPaths.Output, envPaths.Output, keyPaths.Output, defaults.Output),
+		Database:  resolve(flagPaths.Database, envPaths.Database, keyPaths.Database, defaults.Database),
+		Seed:      resolve(flagPaths.Seed, envPaths.Seed, keyPaths.Seed, defaults.Seed),
+		Reports:   resolve(flagPaths.Reports, envPaths.Reports, keyPaths.Reports, defaults.Reports),
+		Logs:      resolve(flagPaths.Logs, envPaths.Logs, keyPaths.Logs, defaults.Logs),
+		Curl:      resolve(flagPaths.Curl, envPaths.Curl, keyPaths.Curl, defaults.Curl),
+		Spool:     resolve(flagPaths.Spool, envPaths.Spool, keyPaths.Spool, defaults.Spool),
+		Templates: resolve(flagPaths.Templates, envPaths.Templates, keyPaths.Templates, defaults.Templates),
+	}
+}
+
+// LoadWorkspace loads the config file of the workspace (or of the flag or
+// environment variable) and resolves the paths of the config.
+func LoadWorkspace(workspace string, flagPaths Paths) (*Config, error) {
+	envPaths := EnvPaths()
+	configFile := ResolvePaths(workspace, flagPaths, envPaths, Paths{}).Config //nolint:exhaustruct
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Workspace = workspace
+	cfg.Locations = ResolvePaths(workspace, flagPaths, envPaths, cfg.Paths)
+
+	return cfg, nil
+}
+
+// TemplateFile returns the path of a payload template. A bare file name
+// (like 'mattermost.tmpl') is located in the templates directory, other
+// relative paths are relative to the workspace.
+func (p Paths) TemplateFile(workspace string, templateFile string) string {
+	switch {
+	case filepath.IsAbs(templateFile):
+		return templateFile
+	case filepath.Base(templateFile) == templateFile:
+		return filepath.Join(p.Templates, templateFile)
+	default:
+		return filepath.Join(workspace, templateFile)
+	}
+}
+
+// firstNonEmpty returns the first non-empty value.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
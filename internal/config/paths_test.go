@@ -0,0 +1,36 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+)
+
+func TestResolvePathsPrecedence(t *testing.T) {
+	workspace := filepath.Join("srv", "shop")
+
+	flagPaths := config.Paths{Input: "flag-input"}                     //nolint:exhaustruct
+	envPaths := config.Paths{Input: "env-input", Output: "env-output"} //nolint:exhaustruct
+	keyPaths := config.Paths{Output: "key-output", Logs: "key-logs"}   //nolint:exhaustruct
+
+	paths := config.ResolvePaths(workspace, flagPaths, envPaths, keyPaths)
+
+	expected := map[string][2]string{
+		"input":    {paths.Input, "flag-input"},
+		"output":   {paths.Output, "env-output"},
+		"logs":     {paths.Logs, filepath.Join(workspace, "key-logs")},
+		"database": {paths.Database, filepath.Join(workspace, "db", "store.db")},
+		"config":   {paths.Config, filepath.Join(workspace, "config", "apiprobe.json")},
+	}
+
+	for name, values := range expected {
+		if values[0] != values[1] {
+			t.Errorf("%s: got %q, expected %q", name, values[0], values[1])
+		}
+	}
+
+	if file := paths.TemplateFile(workspace, "mattermost.tmpl"); file != filepath.Join(paths.Templates, "mattermost.tmpl") {
+		t.Errorf("template file: got %q", file)
+	}
+}
@@ -17,42 +17,100 @@ import (
 // retrieves the real secret from the database, deobfuscates it, and replaces the
 // placeholder. Returns an error immediately if any DB lookup fails.
 func HandleSecrets(filteredRequests []*loader.APIRequest, conn *sqlite.Conn) ([]*loader.APIRequest, error) {
-	for _, req := range filteredRequests {
-		newBody, err := replaceSecretInString(req.Request.PostBody, conn)
-		if err != nil {
-			return nil, err
-		}
+	return replaceSecrets(filteredRequests, conn, false)
+}
 
-		req.Request.PostBody = newBody
+// RedactSecrets works like HandleSecrets, but replaces the placeholders of
+// existing secrets by RedactedSecret instead of the real secret (e.g. to
+// print the requests). Unknown secrets stay placeholders.
+func RedactSecrets(filteredRequests []*loader.APIRequest, conn *sqlite.Conn) ([]*loader.APIRequest, error) {
+	return replaceSecrets(filteredRequests, conn, true)
+}
 
-		newAuth, err := replaceSecretInString(req.Request.BasicAuth, conn)
-		if err != nil {
-			return nil, err
+// RedactedSecret replaces secrets in printed requests.
+const RedactedSecret = "<redacted>"
+
+// TemplateSecret returns the secret lookup of the template function 'secret'
+// (see loader.SecretFunc): the real secret of a '<secret-<hash>>' placeholder
+// or RedactedSecret if redact is set. Unknown secrets are an error (kept as
+// placeholder if redact is set).
+func TemplateSecret(conn *sqlite.Conn, redact bool) loader.SecretFunc {
+	return func(placeholder string) (string, error) {
+		secretHash := ExtractSecretHash(placeholder)
+		if secretHash == "" {
+			return "", fmt.Errorf(`secret: invalid placeholder "%s", expected "<secret-<hash>>"`, placeholder)
 		}
 
-		req.Request.BasicAuth = newAuth
+		secret, err := db.SelectHash(conn, secretHash)
+		if err != nil {
+			return "", err
+		}
 
-		if err = replaceSecretInSlice(req.Request.Params, conn); err != nil {
-			return nil, err
+		switch {
+		case secret == "" && redact:
+			// Like RedactSecrets, unknown secrets stay placeholders.
+			return placeholder, nil
+		case secret == "":
+			return "", fmt.Errorf(`secret: secret "%s" not found`, secretHash)
+		case redact:
+			return RedactedSecret, nil
 		}
 
-		if err = replaceSecretInSlice(req.Request.Headers, conn); err != nil {
+		return Deobfuscate(secret), nil
+	}
+}
+
+// replaceSecrets replaces the secret placeholders of the requests by the
+// real or redacted secrets.
+func replaceSecrets(filteredRequests []*loader.APIRequest, conn *sqlite.Conn, redact bool) ([]*loader.APIRequest, error) {
+	for _, req := range filteredRequests {
+		if err := replaceSecretsInRequest(req, conn, redact); err != nil {
 			return nil, err
 		}
 
-		if err = replaceSecretInTestCases(req.TestCases, conn); err != nil {
-			return nil, err
+		for _, step := range req.ScenarioSteps() {
+			if err := replaceSecretsInRequest(step.Target, conn, redact); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return filteredRequests, nil
 }
 
+// replaceSecretsInRequest replaces the secret placeholders of a single
+// request (including its test cases) in-place.
+func replaceSecretsInRequest(req *loader.APIRequest, conn *sqlite.Conn, redact bool) error {
+	newBody, err := replaceSecretInString(req.Request.PostBody, conn, redact)
+	if err != nil {
+		return err
+	}
+
+	req.Request.PostBody = newBody
+
+	newAuth, err := replaceSecretInString(req.Request.BasicAuth, conn, redact)
+	if err != nil {
+		return err
+	}
+
+	req.Request.BasicAuth = newAuth
+
+	if err = replaceSecretInSlice(req.Request.Params, conn, redact); err != nil {
+		return err
+	}
+
+	if err = replaceSecretInSlice(req.Request.Headers, conn, redact); err != nil {
+		return err
+	}
+
+	return replaceSecretInTestCases(req.TestCases, conn, redact)
+}
+
 // replaceSecretInString searches a single string for '<secret-<hash>>'
 // patterns. For each found hash, it retrieves the secret from the database,
-// deobfuscates it, and replaces the placeholder in the string.
-// Returns an error if DB lookup fails.
-func replaceSecretInString(str string, conn *sqlite.Conn) (string, error) {
+// deobfuscates it, and replaces the placeholder in the string (by
+// RedactedSecret if redact is set). Returns an error if DB lookup fails.
+func replaceSecretInString(str string, conn *sqlite.Conn, redact bool) (string, error) {
 	const secretPrefix = "<secret-"
 
 	if !strings.Contains(str, secretPrefix) {
@@ -78,6 +136,10 @@ func replaceSecretInString(str string, conn *sqlite.Conn) (string, error) {
 		from := fmt.Sprintf("%s%s>", secretPrefix, secretHash)
 		to := Deobfuscate(secret)
 
+		if redact {
+			to = RedactedSecret
+		}
+
 		return strings.ReplaceAll(str, from, to), nil
 	}
 
@@ -89,9 +151,9 @@ func replaceSecretInString(str string, conn *sqlite.Conn) (string, error) {
 // replaceSecretInSlice iterates over a slice of strings, calls replaceSecretInString
 // on each element, and updates the slice in-place.
 // Returns the first error encountered, if any.
-func replaceSecretInSlice(reqSlice []string, conn *sqlite.Conn) error {
+func replaceSecretInSlice(reqSlice []string, conn *sqlite.Conn, redact bool) error {
 	for idx, val := range reqSlice {
-		newVal, err := replaceSecretInString(val, conn)
+		newVal, err := replaceSecretInString(val, conn, redact)
 		if err != nil {
 			return err
 		}
@@ -103,17 +165,16 @@ func replaceSecretInSlice(reqSlice []string, conn *sqlite.Conn) error {
 }
 
 // replaceSecretInTestCases iterates over all test cases and replaces secrets
-// in the ParamsData and PostBodyData fields in-place.
+// in the ParamsData, PostBodyData, Data and request override fields in-place.
 // Returns the first error encountered, if any.
-func replaceSecretInTestCases(testCases []loader.TestCases, conn *sqlite.Conn) error {
+func replaceSecretInTestCases(testCases []loader.TestCases, conn *sqlite.Conn, redact bool) error {
 	for idx := range testCases {
 		testCase := &testCases[idx]
 
 		var err error
 
-		if testCase.ParamsData != "" {
-			testCase.ParamsData, err = replaceSecretInString(testCase.ParamsData, conn)
-			if err != nil {
+		for _, params := range [][]string{testCase.ParamsData.Set, testCase.ParamsData.Add} {
+			if err = replaceSecretInSlice(params, conn, redact); err != nil {
 				logger.Errorf(`Error replacing secret in ParamsData of test "%q".`, testCase.Name)
 
 				return err
@@ -121,18 +182,49 @@ func replaceSecretInTestCases(testCases []loader.TestCases, conn *sqlite.Conn) e
 		}
 
 		if testCase.PostBodyData != "" {
-			testCase.PostBodyData, err = replaceSecretInString(testCase.PostBodyData, conn)
+			testCase.PostBodyData, err = replaceSecretInString(testCase.PostBodyData, conn, redact)
 			if err != nil {
 				logger.Errorf(`Error replacing secret in PostBodyData of test "%q".`, testCase.Name)
 
 				return err
 			}
 		}
+
+		if err = replaceSecretInOverride(testCase.Request, conn, redact); err != nil {
+			logger.Errorf(`Error replacing secret in request of test "%q".`, testCase.Name)
+
+			return err
+		}
+
+		for column, value := range testCase.Data {
+			testCase.Data[column], err = replaceSecretInString(value, conn, redact)
+			if err != nil {
+				logger.Errorf(`Error replacing secret in data column "%s" of test "%q".`, column, testCase.Name)
+
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// replaceSecretInOverride replaces secrets in the basic auth and headers
+// of the test case request override (if any) in-place.
+func replaceSecretInOverride(override *loader.RequestOverride, conn *sqlite.Conn, redact bool) error {
+	if override == nil {
+		return nil
+	}
+
+	var err error
+
+	if override.BasicAuth, err = replaceSecretInString(override.BasicAuth, conn, redact); err != nil {
+		return err
+	}
+
+	return replaceSecretInSlice(override.Headers, conn, redact)
+}
+
 // ExtractSecretHash uses a precompiled regex to extract the hash from
 // a '<secret-<hash>>' placeholder. Returns the hash without angle brackets
 // or prefix or an empty string if no match is found.
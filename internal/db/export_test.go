@@ -0,0 +1,8 @@
+package db
+
+// Exported for the tests of the schema migrations (package db_test).
+var (
+	Migrate       = migrate
+	Migrations    = migrations
+	SchemaVersion = schemaVersion
+)
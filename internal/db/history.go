@@ -0,0 +1,291 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Execution states stored in the 'request_executions' table.
+const (
+	StatusSuccess      = "success"
+	StatusChanged      = "changed"
+	StatusRequestError = "request-error"
+	StatusFormatError  = "format-error"
+)
+
+// RunCounts holds the aggregated result counters of a finished run.
+type RunCounts struct {
+	RequestErrors   int
+	FormatErrors    int
+	ChangedFiles    int
+	LatencyFailed   int
+	LatencyDegraded int
+	HookErrors      int
+}
+
+// Execution holds the data of a single executed request (or test case).
+type Execution struct {
+	RunID         int64
+	RequestID     string
+	TestCase      string
+	TestCaseIndex int
+	Tags          []string
+	Method        string
+	Endpoint      string
+	Status        string
+	StatusCode    string
+	Latency       string
+	DNSMs         float64
+	ConnectMs     float64
+	TLSMs         float64
+	TTFBMs        float64
+	TotalMs       float64
+	ResponseHash  string
+	ErrorText     string
+}
+
+// HistoryEntry is a single row of the run history query.
+type HistoryEntry struct {
+	RunID      int64
+	RunName    string
+	StartedAt  string
+	RequestID  string
+	TestCase   string
+	Status     string
+	StatusCode string
+	Latency    string
+	TotalMs    float64
+	ErrorText  string
+}
+
+// ExecutionState is the outcome of a single request execution,
+// used to evaluate the state over several runs (e.g. flakiness).
+type ExecutionState struct {
+	RunID         int64
+	RequestID     string
+	TestCase      string
+	TestCaseIndex int
+	Method        string
+	Endpoint      string
+	Tags          []string
+	Status        string
+	Latency       string
+}
+
+// IsFailure returns true if the execution failed (request error,
+// format error or failed latency threshold).
+func (state *ExecutionState) IsFailure() bool {
+	return state.Status == StatusRequestError || state.Status == StatusFormatError || state.Latency == "failed"
+}
+
+// InsertRun creates a new entry in the 'runs' table and
+// returns its id.
+func InsertRun(conn *sqlite.Conn, name string, hostname string, version string) (int64, error) {
+	insertSQL := "INSERT INTO runs(name, hostname, version, started_at) VALUES (?, ?, ?, ?)"
+
+	err := sqlitex.ExecuteTransient(conn, insertSQL, &sqlitex.ExecOptions{
+		Args:       []any{name, hostname, version, now()},
+		Named:      nil,
+		ResultFunc: nil,
+	})
+	if err != nil {
+		logger.Errorf("Failed to insert run. Error: %v", err)
+
+		return 0, err
+	}
+
+	return conn.LastInsertRowID(), nil
+}
+
+// FinishRun stores the finish time and the result counters of the run.
+func FinishRun(conn *sqlite.Conn, runID int64, counts RunCounts) error {
+	updateSQL := `
+		UPDATE runs SET
+			finished_at = ?, request_errors = ?, format_errors = ?,
+			changed_files = ?, latency_failed = ?, latency_degraded = ?,
+			hook_errors = ?
+		WHERE id = ?`
+
+	err := sqlitex.ExecuteTransient(conn, updateSQL, &sqlitex.ExecOptions{
+		Args: []any{
+			now(), counts.RequestErrors, counts.FormatErrors,
+			counts.ChangedFiles, counts.LatencyFailed, counts.LatencyDegraded,
+			counts.HookErrors, runID,
+		},
+		Named:      nil,
+		ResultFunc: nil,
+	})
+	if err != nil {
+		logger.Errorf("Failed to update run %d. Error: %v", runID, err)
+
+		return err
+	}
+
+	return nil
+}
+
+// InsertExecution stores a single request execution and returns its id.
+func InsertExecution(conn *sqlite.Conn, exe *Execution) (int64, error) {
+	insertSQL := `
+		INSERT INTO request_executions(
+			run_id, request_id, test_case, test_case_index, tags, method, endpoint,
+			status, status_code, latency, dns_ms, connect_ms, tls_ms, ttfb_ms, total_ms,
+			response_hash, error_text, executed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	err := sqlitex.ExecuteTransient(conn, insertSQL, &sqlitex.ExecOptions{
+		Args: []any{
+			exe.RunID, exe.RequestID, exe.TestCase, exe.TestCaseIndex, joinTags(exe.Tags), exe.Method, exe.Endpoint,
+			exe.Status, exe.StatusCode, exe.Latency, exe.DNSMs, exe.ConnectMs, exe.TLSMs, exe.TTFBMs, exe.TotalMs,
+			exe.ResponseHash, exe.ErrorText, now(),
+		},
+		Named:      nil,
+		ResultFunc: nil,
+	})
+	if err != nil {
+		logger.Errorf(`Failed to insert execution of request "%s". Error: %v`, exe.RequestID, err)
+
+		return 0, err
+	}
+
+	return conn.LastInsertRowID(), nil
+}
+
+// SelectHistory returns the request executions of the last runCount runs
+// which executed any request matching the selection condition (an SQL
+// condition over the columns 'e.request_id' and 'e.tags' with its arguments,
+// see selector.Selection.SQLCondition). Entries are ordered from the newest
+// to the oldest run.
+func SelectHistory(conn *sqlite.Conn, runCount int, condition string, args ...any) ([]HistoryEntry, error) {
+	querySQL := `
+		WITH selected AS (
+			SELECT e.* FROM request_executions e WHERE ` + condition + `
+		), recent AS (
+			SELECT DISTINCT run_id FROM selected ORDER BY run_id DESC LIMIT ?
+		)
+		SELECT r.id, r.name, r.started_at, e.request_id, e.test_case,
+			e.status, e.status_code, e.latency, e.total_ms, e.error_text
+		FROM selected e
+		JOIN recent ON recent.run_id = e.run_id
+		JOIN runs r ON r.id = e.run_id
+		ORDER BY r.id DESC, e.id ASC`
+
+	var entries []HistoryEntry
+
+	err := sqlitex.ExecuteTransient(conn, querySQL, &sqlitex.ExecOptions{
+		Args:  append(args, runCount),
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			entries = append(entries, HistoryEntry{
+				RunID:      stmt.ColumnInt64(0),
+				RunName:    stmt.ColumnText(1),
+				StartedAt:  stmt.ColumnText(2),
+				RequestID:  stmt.ColumnText(3),
+				TestCase:   stmt.ColumnText(4),
+				Status:     stmt.ColumnText(5),
+				StatusCode: stmt.ColumnText(6),
+				Latency:    stmt.ColumnText(7),
+				TotalMs:    stmt.ColumnFloat(8),
+				ErrorText:  stmt.ColumnText(9),
+			})
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf("Failed to query run history. Error: %v", err)
+
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SelectRunExecutions returns the execution states of all requests
+// executed in the given run.
+func SelectRunExecutions(conn *sqlite.Conn, runID int64) ([]ExecutionState, error) {
+	selectSQL := `
+		SELECT run_id, request_id, test_case, test_case_index, method, endpoint, status, latency, tags
+		FROM request_executions
+		WHERE run_id = ?
+		ORDER BY id ASC`
+
+	return selectExecutionStates(conn, selectSQL, runID)
+}
+
+// SelectExecutionStates returns the execution states of the last limit
+// executions of a request (and test case), ordered from newest to oldest.
+func SelectExecutionStates(conn *sqlite.Conn, requestID string, testCaseIndex int, limit int) ([]ExecutionState, error) {
+	selectSQL := `
+		SELECT run_id, request_id, test_case, test_case_index, method, endpoint, status, latency, tags
+		FROM request_executions
+		WHERE request_id = ? AND test_case_index = ?
+		ORDER BY id DESC
+		LIMIT ?`
+
+	return selectExecutionStates(conn, selectSQL, requestID, testCaseIndex, limit)
+}
+
+// selectExecutionStates runs the given query, which must select the
+// ExecutionState columns in order, and returns the resulting states.
+func selectExecutionStates(conn *sqlite.Conn, selectSQL string, args ...any) ([]ExecutionState, error) {
+	var states []ExecutionState
+
+	err := sqlitex.ExecuteTransient(conn, selectSQL, &sqlitex.ExecOptions{
+		Args:  args,
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			states = append(states, ExecutionState{
+				RunID:         stmt.ColumnInt64(0),
+				RequestID:     stmt.ColumnText(1),
+				TestCase:      stmt.ColumnText(2),
+				TestCaseIndex: stmt.ColumnInt(3),
+				Method:        stmt.ColumnText(4),
+				Endpoint:      stmt.ColumnText(5),
+				Tags:          splitTags(stmt.ColumnText(8)), //nolint:mnd
+				Status:        stmt.ColumnText(6),
+				Latency:       stmt.ColumnText(7),
+			})
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf("Failed to query execution states. Error: %v", err)
+
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// joinTags returns the tags as comma-separated list, which is also enclosed
+// by commas (",a,b,"), so a tag matches exactly by instr(tags, ',tag,') in
+// the selection condition of SelectHistory.
+func joinTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return "," + strings.Join(tags, ",") + ","
+}
+
+// splitTags returns the tags of a joined tag list (see joinTags).
+func splitTags(joined string) []string {
+	joined = strings.Trim(joined, ",")
+	if joined == "" {
+		return nil
+	}
+
+	return strings.Split(joined, ",")
+}
+
+// now returns the current UTC time in RFC3339 format.
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
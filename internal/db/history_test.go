@@ -0,0 +1,118 @@
+package db_test
+
+import (
+	"reflect"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/db"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	conn, err := db.OpenMemory()
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+
+	defer conn.Close()
+
+	executions := []db.Execution{ //nolint:exhaustruct
+		{
+			RequestID: "0f1e2d3c4b", TestCase: "", TestCaseIndex: -1, Tags: []string{"reqres", "env-prod"},
+			Method: "GET", Endpoint: "/users", Status: db.StatusSuccess, StatusCode: "200",
+			DNSMs: 1, ConnectMs: 2, TLSMs: 3, TTFBMs: 4, TotalMs: 10, ResponseHash: "abc",
+		},
+		{
+			RequestID: "0f1e2d3c4b", TestCase: "unknown user", TestCaseIndex: 0, Tags: []string{"reqres", "env-prod"},
+			Method: "GET", Endpoint: "/users/23", Status: db.StatusRequestError, StatusCode: "404",
+			Latency: "failed", TotalMs: 1200, ErrorText: "not found",
+		},
+		{
+			RequestID: "ab12cd34ef", TestCaseIndex: -1, Method: "POST", Endpoint: "/orders",
+			Status: db.StatusChanged, StatusCode: "201", TotalMs: 20,
+		},
+	}
+
+	var runIDs []int64
+
+	for run := range 2 {
+		runID, err := db.InsertRun(conn, "nightly", "host", "v1")
+		if err != nil {
+			t.Fatalf("insert run: %v", err)
+		}
+
+		runIDs = append(runIDs, runID)
+
+		for _, exe := range executions[run:] {
+			exe.RunID = runID
+
+			if _, err = db.InsertExecution(conn, &exe); err != nil {
+				t.Fatalf("insert execution: %v", err)
+			}
+		}
+
+		counts := db.RunCounts{RequestErrors: 1, FormatErrors: 0, ChangedFiles: 1, LatencyFailed: 1, LatencyDegraded: 0}
+		counts.HookErrors = run
+
+		if err = db.FinishRun(conn, runID, counts); err != nil {
+			t.Fatalf("finish run: %v", err)
+		}
+	}
+
+	// Execution states of the newest run keep the tags and test case.
+	states, err := db.SelectRunExecutions(conn, runIDs[1])
+	if err != nil || len(states) != 2 {
+		t.Fatalf("expected 2 executions of the run, got %d (%v)", len(states), err)
+	}
+
+	wantState := db.ExecutionState{
+		RunID: runIDs[1], RequestID: "0f1e2d3c4b", TestCase: "unknown user", TestCaseIndex: 0, Method: "GET",
+		Endpoint: "/users/23", Tags: []string{"reqres", "env-prod"}, Status: db.StatusRequestError, Latency: "failed",
+	}
+	if !reflect.DeepEqual(states[0], wantState) || !states[0].IsFailure() || states[1].Tags != nil {
+		t.Errorf("unexpected execution states %+v", states)
+	}
+
+	// Newest execution first, limited.
+	states, err = db.SelectExecutionStates(conn, "0f1e2d3c4b", 0, 1)
+	if err != nil || len(states) != 1 || states[0].RunID != runIDs[1] {
+		t.Errorf("expected the newest execution state, got %+v (%v)", states, err)
+	}
+
+	// All history entries of the last run, then of the first run.
+	entries, err := db.SelectHistory(conn, 2, "1")
+	if err != nil || len(entries) != 5 {
+		t.Fatalf("expected 5 history entries, got %d (%v)", len(entries), err)
+	}
+
+	wantEntry := db.HistoryEntry{
+		RunID: runIDs[1], RunName: "nightly", StartedAt: entries[0].StartedAt, RequestID: "0f1e2d3c4b",
+		TestCase: "unknown user", Status: db.StatusRequestError, StatusCode: "404", Latency: "failed",
+		TotalMs: 1200, ErrorText: "not found",
+	}
+	if entries[0] != wantEntry || entries[2].RunID != runIDs[0] || entries[2].TotalMs != 10 {
+		t.Errorf("unexpected history entries %+v", entries)
+	}
+
+	// The run count limits the runs with a matching execution.
+	entries, err = db.SelectHistory(conn, 1, "e.request_id = ?", "ab12cd34ef")
+	if err != nil || len(entries) != 1 || entries[0].RunID != runIDs[1] {
+		t.Errorf("expected the execution of the last run, got %+v (%v)", entries, err)
+	}
+
+	var hookErrors int
+
+	err = sqlitex.ExecuteTransient(conn, "SELECT hook_errors FROM runs WHERE id = ?", &sqlitex.ExecOptions{ //nolint:exhaustruct
+		Args: []any{runIDs[1]},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			hookErrors = stmt.ColumnInt(0)
+
+			return nil
+		},
+	})
+	if err != nil || hookErrors != 1 {
+		t.Errorf("expected 1 hook error of the run, got %d (%v)", hookErrors, err)
+	}
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// migrations returns the versioned schema changes. The position in the
+// list (index + 1) is the schema version which is stored in the database
+// by 'PRAGMA user_version'. Only append new entries, never modify
+// already released ones.
+func migrations() []string {
+	return []string{
+		// Version 1: secrets store.
+		`
+		CREATE TABLE IF NOT EXISTS secrets (
+			hash   TEXT PRIMARY KEY,
+			secret TEXT NOT NULL
+		);`,
+
+		// Version 2: run history (runs, request executions and snapshots).
+		`
+		CREATE TABLE IF NOT EXISTS runs (
+			id                INTEGER PRIMARY KEY AUTOINCREMENT,
+			name              TEXT    NOT NULL DEFAULT '',
+			hostname          TEXT    NOT NULL DEFAULT '',
+			version           TEXT    NOT NULL DEFAULT '',
+			started_at        TEXT    NOT NULL,
+			finished_at       TEXT    NOT NULL DEFAULT '',
+			request_errors    INTEGER NOT NULL DEFAULT 0,
+			format_errors     INTEGER NOT NULL DEFAULT 0,
+			changed_files     INTEGER NOT NULL DEFAULT 0,
+			latency_failed    INTEGER NOT NULL DEFAULT 0,
+			latency_degraded  INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS request_executions (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id          INTEGER NOT NULL REFERENCES runs(id),
+			request_id      TEXT    NOT NULL,
+			test_case       TEXT    NOT NULL DEFAULT '',
+			test_case_index INTEGER NOT NULL DEFAULT -1,
+			tags            TEXT    NOT NULL DEFAULT '',
+			method          TEXT    NOT NULL DEFAULT '',
+			endpoint        TEXT    NOT NULL DEFAULT '',
+			status          TEXT    NOT NULL,
+			status_code     TEXT    NOT NULL DEFAULT '',
+			latency         TEXT    NOT NULL DEFAULT '',
+			dns_ms          REAL    NOT NULL DEFAULT 0,
+			connect_ms      REAL    NOT NULL DEFAULT 0,
+			tls_ms          REAL    NOT NULL DEFAULT 0,
+			ttfb_ms         REAL    NOT NULL DEFAULT 0,
+			total_ms        REAL    NOT NULL DEFAULT 0,
+			response_hash   TEXT    NOT NULL DEFAULT '',
+			error_text      TEXT    NOT NULL DEFAULT '',
+			executed_at     TEXT    NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_request_executions_request_id
+			ON request_executions(request_id, run_id);
+
+		CREATE TABLE IF NOT EXISTS snapshots (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			execution_id INTEGER NOT NULL REFERENCES request_executions(id),
+			output_file  TEXT    NOT NULL,
+			hash         TEXT    NOT NULL,
+			content      BLOB    NOT NULL,
+			created_at   TEXT    NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_snapshots_output_file
+			ON snapshots(output_file, id);`,
+
+		// Version 3: snapshot review state (approve/reject workflow).
+		`
+		ALTER TABLE snapshots ADD COLUMN state TEXT NOT NULL DEFAULT 'approved';
+		ALTER TABLE snapshots ADD COLUMN reviewed_at TEXT NOT NULL DEFAULT '';`,
+
+		// Version 4: hook error counter of the runs.
+		`
+		ALTER TABLE runs ADD COLUMN hook_errors INTEGER NOT NULL DEFAULT 0;`,
+
+		// Version 5: run history query by run (newest runs first).
+		`
+		CREATE INDEX IF NOT EXISTS idx_request_executions_run_id
+			ON request_executions(run_id, id);`,
+	}
+}
+
+// migrate applies all pending schema migrations in order. Each migration
+// runs in its own savepoint together with the update of the schema version,
+// so a failing migration leaves the database at the previous version.
+func migrate(conn *sqlite.Conn) error {
+	currentVersion, err := schemaVersion(conn)
+	if err != nil {
+		return err
+	}
+
+	for idx, migration := range migrations() {
+		version := idx + 1
+		if version <= currentVersion {
+			continue
+		}
+
+		script := fmt.Sprintf("%s\nPRAGMA user_version = %d;", migration, version)
+
+		if err = sqlitex.ExecuteScript(conn, script, nil); err != nil {
+			logger.Errorf("Failed to migrate database to schema version %d. Error: %v", version, err)
+
+			return err
+		}
+
+		logger.Debugf("Database migrated to schema version %d.", version)
+	}
+
+	return nil
+}
+
+// schemaVersion returns the current schema version of the database.
+func schemaVersion(conn *sqlite.Conn) (int, error) {
+	var version int
+
+	err := sqlitex.ExecuteTransient(conn, "PRAGMA user_version;", &sqlitex.ExecOptions{
+		Args:  nil,
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			version = stmt.ColumnInt(0)
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf("Failed to query schema version. Error: %v", err)
+
+		return 0, err
+	}
+
+	return version, nil
+}
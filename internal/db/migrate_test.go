@@ -0,0 +1,82 @@
+package db_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/db"
+)
+
+func TestMigrateFromEmptyDatabaseToLatest(t *testing.T) {
+	// An empty database without migrations (schema version 0).
+	conn, err := sqlite.OpenConn(":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	defer conn.Close()
+
+	if version, err := db.SchemaVersion(conn); err != nil || version != 0 {
+		t.Fatalf("expected schema version 0, got %d (%v)", version, err)
+	}
+
+	// Migrating twice keeps the latest version (no migration is applied again).
+	for range 2 {
+		if err := db.Migrate(conn); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+
+		version, err := db.SchemaVersion(conn)
+		if err != nil || version != len(db.Migrations()) {
+			t.Fatalf("expected schema version %d, got %d (%v)", len(db.Migrations()), version, err)
+		}
+	}
+
+	var objects []string
+
+	err = sqlitex.ExecuteTransient(conn, "SELECT name FROM sqlite_master WHERE type IN ('table', 'index') ORDER BY name",
+		&sqlitex.ExecOptions{ //nolint:exhaustruct
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				objects = append(objects, stmt.ColumnText(0))
+
+				return nil
+			},
+		})
+	if err != nil {
+		t.Fatalf("query schema: %v", err)
+	}
+
+	for _, name := range []string{
+		"idx_request_executions_request_id", "idx_request_executions_run_id", "idx_snapshots_output_file",
+		"request_executions", "runs", "secrets", "snapshots",
+	} {
+		if !slices.Contains(objects, name) {
+			t.Errorf("expected table or index %s, got %v", name, objects)
+		}
+	}
+}
+
+func TestOpenReadOnlyWithoutDatabaseFile(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "db", "store.db")
+
+	conn, err := db.OpenReadOnly(dbFile)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	defer conn.Close()
+
+	if secret, err := db.SelectHash(conn, "0f1e2d3c4b"); err != nil || secret != "" {
+		t.Errorf("expected no secret, got %q (%v)", secret, err)
+	}
+
+	if _, err = os.Stat(dbFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no database file, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Snapshot review states stored in the 'snapshots' table.
+const (
+	SnapshotApproved = "approved"
+	SnapshotPending  = "pending"
+	SnapshotRejected = "rejected"
+	SnapshotObsolete = "obsolete"
+)
+
+// Snapshot is a stored version of an output file.
+type Snapshot struct {
+	ID        int64
+	RequestID string
+	TestCase  string
+
+	// OutputFile is the path relative to the output directory.
+	OutputFile string
+	Hash       string
+	State      string
+	CreatedAt  string
+	ReviewedAt string
+	Content    []byte
+}
+
+// InsertSnapshot stores the content of an output file together with its
+// hash, review state and the execution which produced it. Returns the id
+// of the snapshot.
+func InsertSnapshot(
+	conn *sqlite.Conn,
+	executionID int64,
+	outputFile string,
+	hash string,
+	content []byte,
+	state string,
+) (int64, error) {
+	insertSQL := `
+		INSERT INTO snapshots(execution_id, output_file, hash, content, state, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	err := sqlitex.ExecuteTransient(conn, insertSQL, &sqlitex.ExecOptions{
+		Args:       []any{executionID, outputFile, hash, content, state, now()},
+		Named:      nil,
+		ResultFunc: nil,
+	})
+	if err != nil {
+		logger.Errorf(`Failed to insert snapshot of "%s". Error: %v`, outputFile, err)
+
+		return 0, err
+	}
+
+	return conn.LastInsertRowID(), nil
+}
+
+// CountSnapshots returns the number of stored snapshots of the output file.
+func CountSnapshots(conn *sqlite.Conn, outputFile string) (int, error) {
+	var count int
+
+	err := sqlitex.ExecuteTransient(conn, "SELECT COUNT(*) FROM snapshots WHERE output_file = ?", &sqlitex.ExecOptions{
+		Args:  []any{outputFile},
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			count = stmt.ColumnInt(0)
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf(`Failed to count snapshots of "%s". Error: %v`, outputFile, err)
+
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindPendingSnapshot returns the id of the pending snapshot of the output
+// file with the given hash, or 0 if there is no such snapshot.
+func FindPendingSnapshot(conn *sqlite.Conn, outputFile string, hash string) (int64, error) {
+	var snapshotID int64
+
+	selectSQL := "SELECT id FROM snapshots WHERE output_file = ? AND hash = ? AND state = ? ORDER BY id DESC LIMIT 1"
+
+	err := sqlitex.ExecuteTransient(conn, selectSQL, &sqlitex.ExecOptions{
+		Args:  []any{outputFile, hash, SnapshotPending},
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			snapshotID = stmt.ColumnInt64(0)
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf(`Failed to query pending snapshot of "%s". Error: %v`, outputFile, err)
+
+		return 0, err
+	}
+
+	return snapshotID, nil
+}
+
+// SelectSnapshots returns the snapshots (without content) filtered by state
+// and/or output file. Empty filter values are ignored. Snapshots are ordered
+// from the newest to the oldest.
+func SelectSnapshots(conn *sqlite.Conn, state string, outputFile string) ([]Snapshot, error) {
+	selectSQL := `
+		SELECT s.id, e.request_id, e.test_case, s.output_file, s.hash, s.state, s.created_at, s.reviewed_at
+		FROM snapshots s
+		JOIN request_executions e ON e.id = s.execution_id
+		WHERE (? = '' OR s.state = ?) AND (? = '' OR s.output_file = ?)
+		ORDER BY s.id DESC`
+
+	var snapshots []Snapshot
+
+	err := sqlitex.ExecuteTransient(conn, selectSQL, &sqlitex.ExecOptions{
+		Args:  []any{state, state, outputFile, outputFile},
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			snapshots = append(snapshots, Snapshot{
+				ID:         stmt.ColumnInt64(0),
+				RequestID:  stmt.ColumnText(1),
+				TestCase:   stmt.ColumnText(2),
+				OutputFile: stmt.ColumnText(3),
+				Hash:       stmt.ColumnText(4),
+				State:      stmt.ColumnText(5),
+				CreatedAt:  stmt.ColumnText(6),
+				ReviewedAt: stmt.ColumnText(7),
+				Content:    nil,
+			})
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf("Failed to query snapshots. Error: %v", err)
+
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// SelectSnapshot returns the snapshot (including content) with the given id
+// or nil if there is no such snapshot.
+func SelectSnapshot(conn *sqlite.Conn, snapshotID int64) (*Snapshot, error) {
+	selectSQL := `
+		SELECT s.id, e.request_id, e.test_case, s.output_file, s.hash, s.state, s.created_at, s.reviewed_at, s.content
+		FROM snapshots s
+		JOIN request_executions e ON e.id = s.execution_id
+		WHERE s.id = ?`
+
+	var snapshot *Snapshot
+
+	err := sqlitex.ExecuteTransient(conn, selectSQL, &sqlitex.ExecOptions{
+		Args:  []any{snapshotID},
+		Named: nil,
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			content := make([]byte, stmt.ColumnLen(8)) //nolint:mnd
+			stmt.ColumnBytes(8, content)               //nolint:mnd
+
+			snapshot = &Snapshot{
+				ID:         stmt.ColumnInt64(0),
+				RequestID:  stmt.ColumnText(1),
+				TestCase:   stmt.ColumnText(2),
+				OutputFile: stmt.ColumnText(3),
+				Hash:       stmt.ColumnText(4),
+				State:      stmt.ColumnText(5),
+				CreatedAt:  stmt.ColumnText(6),
+				ReviewedAt: stmt.ColumnText(7),
+				Content:    content,
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		logger.Errorf("Failed to query snapshot %d. Error: %v", snapshotID, err)
+
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// UpdateSnapshotState sets the review state of the snapshot.
+func UpdateSnapshotState(conn *sqlite.Conn, snapshotID int64, state string) error {
+	err := sqlitex.ExecuteTransient(conn, "UPDATE snapshots SET state = ?, reviewed_at = ? WHERE id = ?", &sqlitex.ExecOptions{
+		Args:       []any{state, now(), snapshotID},
+		Named:      nil,
+		ResultFunc: nil,
+	})
+	if err != nil {
+		logger.Errorf("Failed to update state of snapshot %d. Error: %v", snapshotID, err)
+
+		return err
+	}
+
+	return nil
+}
+
+// UpdatePendingSnapshotsState sets the review state of all pending snapshots
+// of the output file (e.g. to obsolete, if the baseline is valid again).
+func UpdatePendingSnapshotsState(conn *sqlite.Conn, outputFile string, state string) error {
+	updateSQL := "UPDATE snapshots SET state = ?, reviewed_at = ? WHERE output_file = ? AND state = ?"
+
+	err := sqlitex.ExecuteTransient(conn, updateSQL, &sqlitex.ExecOptions{
+		Args:       []any{state, now(), outputFile, SnapshotPending},
+		Named:      nil,
+		ResultFunc: nil,
+	})
+	if err != nil {
+		logger.Errorf(`Failed to update pending snapshots of "%s". Error: %v`, outputFile, err)
+
+		return err
+	}
+
+	return nil
+}
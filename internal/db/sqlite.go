@@ -2,9 +2,11 @@ package db
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"zombiezen.com/go/sqlite"
@@ -13,28 +15,68 @@ import (
 	"github.com/sven-seyfert/apiprobe/internal/logger"
 )
 
-// Init opens or creates the SQLite database file at './db/store.db',
-// ensures that the 'secrets' table exists and returns the active
-// connection to the caller.
-func Init() (*sqlite.Conn, error) {
-	// Create database.
-	conn, err := sqlite.OpenConn("./db/store.db", sqlite.OpenReadWrite, sqlite.OpenCreate)
+// Init opens or creates the SQLite database file (like './db/store.db'),
+// applies all pending schema migrations (e.g. the 'secrets' table and
+// the run history tables) and returns the active connection to the caller.
+func Init(dbFile string) (*sqlite.Conn, error) {
+	// Create database (and its directory).
+	if err := os.MkdirAll(filepath.Dir(dbFile), os.ModePerm); err != nil { //nolint:gosec
+		logger.Errorf(`Failed to create database directory "%s". Error: %v`, filepath.Dir(dbFile), err)
+
+		return nil, err
+	}
+
+	conn, err := sqlite.OpenConn(dbFile, sqlite.OpenReadWrite, sqlite.OpenCreate)
 	if err != nil {
 		logger.Errorf("Failed to open database. Error: %v", err)
 
 		return nil, err
 	}
 
-	// Create table if it does not exist.
-	createTableSQL := `
-		CREATE TABLE IF NOT EXISTS secrets (
-			hash   TEXT PRIMARY KEY,
-			secret TEXT NOT NULL
-		);`
+	// Create or update tables.
+	if err = migrate(conn); err != nil {
+		logger.Errorf("Failed to create database. Error: %v", err)
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// OpenReadOnly opens the existing SQLite database file read-only (no file is
+// created and no migration is applied), e.g. to look up secrets on a dry run.
+// Without database file (like in a fresh workspace), an empty in-memory
+// database with the current schema is opened instead.
+func OpenReadOnly(dbFile string) (*sqlite.Conn, error) {
+	if _, err := os.Stat(dbFile); errors.Is(err, os.ErrNotExist) {
+		logger.Debugf(`Database "%s" does not exist yet. Using an empty in-memory database.`, dbFile)
+
+		return OpenMemory()
+	}
 
-	err = sqlitex.ExecuteTransient(conn, createTableSQL, nil)
+	conn, err := sqlite.OpenConn(dbFile, sqlite.OpenReadOnly)
 	if err != nil {
-		logger.Errorf("Failed to create database. Error: %v", err)
+		logger.Errorf(`Failed to open database "%s" read-only. Error: %v`, dbFile, err)
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// OpenMemory opens an empty in-memory database and applies all migrations
+// (like for a dry run in a fresh workspace).
+func OpenMemory() (*sqlite.Conn, error) {
+	conn, err := sqlite.OpenConn(":memory:", sqlite.OpenReadWrite, sqlite.OpenCreate)
+	if err != nil {
+		logger.Errorf("Failed to open in-memory database. Error: %v", err)
+
+		return nil, err
+	}
+
+	if err = migrate(conn); err != nil {
+		logger.Errorf("Failed to create in-memory database. Error: %v", err)
+		conn.Close()
 
 		return nil, err
 	}
@@ -43,9 +85,9 @@ func Init() (*sqlite.Conn, error) {
 }
 
 // InsertSeedData checks if the 'secrets' table is empty;
-// if so, reads './db/seed.csv', constructs a bulk-insert SQL statement
+// if so, reads the seed file (like './db/seed.csv'), constructs a bulk-insert SQL statement
 // and populates the table. Returns an error if any operation fails.
-func InsertSeedData(conn *sqlite.Conn) error {
+func InsertSeedData(conn *sqlite.Conn, seedFile string) error {
 	// Check if the table is empty.
 	count, err := GetTableEntryCount(conn)
 	if err != nil {
@@ -57,7 +99,7 @@ func InsertSeedData(conn *sqlite.Conn) error {
 	}
 
 	// Insert data (bulk insert).
-	SQLValues, err := readSeedData()
+	SQLValues, err := readSeedData(seedFile)
 	if err != nil {
 		return err
 	}
@@ -98,10 +140,10 @@ func GetTableEntryCount(conn *sqlite.Conn) (int, error) {
 	return count, nil
 }
 
-// readSeedData reads './db/seed.csv', each line containing 'hash,secret'
+// readSeedData reads the seed file, each line containing 'hash,secret'
 // and returns a string suitable for a SQL VALUES clause for a bulk insert.
-func readSeedData() (string, error) {
-	file, err := os.Open("./db/seed.csv")
+func readSeedData(seedFile string) (string, error) {
+	file, err := os.Open(seedFile)
 	if err != nil {
 		logger.Errorf("Failure opening file. Error: %v", err)
 
@@ -9,13 +9,14 @@ import (
 )
 
 // HasFileContentChanged compares the SHA256 checksum of the given output
-// bytes against the current contents of outputPath. If they differ,
-// writes the new content to file and returns true;
+// bytes against the current contents (baseline) of outputPath. If they differ
+// and updateBaseline is true, writes the new content to file. Returns whether
+// the content has changed and the previous content of the file;
 // otherwise logs 'No change' and returns false.
-func HasFileContentChanged(output []byte, outputPath string) (bool, error) {
+func HasFileContentChanged(output []byte, outputPath string, updateBaseline bool) (bool, []byte, error) {
 	err := fileutil.EnsureFileExists(outputPath)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
 	newHash := sha256.Sum256(output)
@@ -26,7 +27,7 @@ func HasFileContentChanged(output []byte, outputPath string) (bool, error) {
 	if err != nil {
 		logger.Errorf(`Failed to read file "%s"`, outputPath)
 
-		return false, err
+		return false, nil, err
 	}
 
 	prevHash = sha256.Sum256(existing)
@@ -34,14 +35,18 @@ func HasFileContentChanged(output []byte, outputPath string) (bool, error) {
 	if newHash == prevHash {
 		logger.Infof(`No change for "%s"`, outputPath)
 
-		return false, nil
+		return false, existing, nil
 	}
 
 	logger.Infof(`Detected change (diff) in "%s"`, outputPath)
 
+	if !updateBaseline {
+		return true, existing, nil
+	}
+
 	if err = fileutil.WriteOutputFile(outputPath, output); err != nil {
-		return true, err
+		return true, existing, err
 	}
 
-	return true, nil
+	return true, existing, nil
 }
@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type operation struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a line based diff of previous and current content in the
+// unified diff format (with three lines of context), or an empty string if
+// both contents are equal.
+func Unified(previous []byte, current []byte, previousName string, currentName string) string {
+	ops := computeOperations(splitLines(string(previous)), splitLines(string(current)))
+
+	hunks := buildHunks(ops)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", previousName, currentName)
+
+	for _, hunk := range hunks {
+		builder.WriteString(hunk)
+	}
+
+	return builder.String()
+}
+
+// splitLines splits text into lines without the line endings.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// computeOperations returns the edit operations which transform the previous
+// into the current lines. Common prefix and suffix are matched directly, the
+// remaining middle part by the longest common subsequence (LCS). For very large
+// middle parts the LCS is skipped and the whole part is replaced.
+func computeOperations(previous []string, current []string) []operation {
+	prefix := 0
+	for prefix < len(previous) && prefix < len(current) && previous[prefix] == current[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(previous)-prefix && suffix < len(current)-prefix &&
+		previous[len(previous)-1-suffix] == current[len(current)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]operation, 0, len(previous)+len(current))
+
+	for _, line := range previous[:prefix] {
+		ops = append(ops, operation{kind: opEqual, line: line})
+	}
+
+	ops = append(ops, lcsOperations(previous[prefix:len(previous)-suffix], current[prefix:len(current)-suffix])...)
+
+	for _, line := range previous[len(previous)-suffix:] {
+		ops = append(ops, operation{kind: opEqual, line: line})
+	}
+
+	return ops
+}
+
+// lcsOperations computes the edit operations of two line lists
+// based on the longest common subsequence table.
+func lcsOperations(previous []string, current []string) []operation {
+	const maxTableSize = 4_000_000
+
+	ops := make([]operation, 0, len(previous)+len(current))
+
+	if len(previous)*len(current) > maxTableSize {
+		for _, line := range previous {
+			ops = append(ops, operation{kind: opDelete, line: line})
+		}
+
+		for _, line := range current {
+			ops = append(ops, operation{kind: opInsert, line: line})
+		}
+
+		return ops
+	}
+
+	// table[i][j] holds the LCS length of previous[i:] and current[j:].
+	table := make([][]int32, len(previous)+1)
+	for idx := range table {
+		table[idx] = make([]int32, len(current)+1)
+	}
+
+	for i := len(previous) - 1; i >= 0; i-- {
+		for j := len(current) - 1; j >= 0; j-- {
+			if previous[i] == current[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < len(previous) && j < len(current) {
+		switch {
+		case previous[i] == current[j]:
+			ops = append(ops, operation{kind: opEqual, line: previous[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, operation{kind: opDelete, line: previous[i]})
+			i++
+		default:
+			ops = append(ops, operation{kind: opInsert, line: current[j]})
+			j++
+		}
+	}
+
+	for ; i < len(previous); i++ {
+		ops = append(ops, operation{kind: opDelete, line: previous[i]})
+	}
+
+	for ; j < len(current); j++ {
+		ops = append(ops, operation{kind: opInsert, line: current[j]})
+	}
+
+	return ops
+}
+
+// buildHunks groups the edit operations into unified diff hunks,
+// each with up to three lines of context around the changes.
+func buildHunks(ops []operation) []string {
+	const contextLines = 3
+
+	var hunks []string
+
+	idx := 0
+
+	for idx < len(ops) {
+		if ops[idx].kind == opEqual {
+			idx++
+
+			continue
+		}
+
+		start := max(0, idx-contextLines)
+		end := idx
+
+		// Extend the hunk as long as the next change is within the context range.
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+
+				continue
+			}
+
+			nextChange := end
+			for nextChange < len(ops) && ops[nextChange].kind == opEqual {
+				nextChange++
+			}
+
+			if nextChange == len(ops) || nextChange-end > 2*contextLines {
+				end = min(len(ops), end+contextLines)
+
+				break
+			}
+
+			end = nextChange
+		}
+
+		hunks = append(hunks, formatHunk(ops, start, end))
+		idx = end
+	}
+
+	return hunks
+}
+
+// formatHunk renders the operations ops[start:end] as a unified diff hunk.
+func formatHunk(ops []operation, start int, end int) string {
+	previousLine, currentLine := 1, 1
+
+	for _, op := range ops[:start] {
+		if op.kind != opInsert {
+			previousLine++
+		}
+
+		if op.kind != opDelete {
+			currentLine++
+		}
+	}
+
+	var body strings.Builder
+
+	previousCount, currentCount := 0, 0
+
+	for _, op := range ops[start:end] {
+		switch op.kind {
+		case opEqual:
+			body.WriteString(" " + op.line + "\n")
+			previousCount++
+			currentCount++
+		case opDelete:
+			body.WriteString("-" + op.line + "\n")
+			previousCount++
+		case opInsert:
+			body.WriteString("+" + op.line + "\n")
+			currentCount++
+		}
+	}
+
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n%s", previousLine, previousCount, currentLine, currentCount, body.String())
+}
@@ -0,0 +1,46 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/diff"
+)
+
+func TestUnified_equalContent(t *testing.T) {
+	content := []byte("{\n  \"id\": 1\n}\n")
+
+	received := diff.Unified(content, content, "a", "b")
+	if received != "" {
+		t.Fatalf("expected empty diff, received:\n%s", received)
+	}
+}
+
+func TestUnified_changedLine(t *testing.T) {
+	previous := []byte("{\n  \"id\": 1,\n  \"name\": \"Alice\"\n}\n")
+	current := []byte("{\n  \"id\": 1,\n  \"name\": \"Bob\"\n}\n")
+	expected := "--- baseline\n+++ snapshot\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		" {\n" +
+		"   \"id\": 1,\n" +
+		"-  \"name\": \"Alice\"\n" +
+		"+  \"name\": \"Bob\"\n" +
+		" }\n"
+
+	received := diff.Unified(previous, current, "baseline", "snapshot")
+	if received != expected {
+		t.Fatalf("unexpected diff:\nexpected:\n%s\nreceived:\n%s", expected, received)
+	}
+}
+
+func TestUnified_separateHunks(t *testing.T) {
+	previous := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+	current := []byte("A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\n")
+	expected := "--- a\n+++ b\n" +
+		"@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n" +
+		"@@ -9,4 +9,4 @@\n i\n j\n k\n-l\n+L\n"
+
+	received := diff.Unified(previous, current, "a", "b")
+	if received != expected {
+		t.Fatalf("unexpected diff:\nexpected:\n%s\nreceived:\n%s", expected, received)
+	}
+}
@@ -3,25 +3,35 @@ package exec
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/sven-seyfert/apiprobe/internal/loader"
 	"github.com/sven-seyfert/apiprobe/internal/logger"
+	"github.com/sven-seyfert/apiprobe/internal/report"
 )
 
-// runCurl executes an external 'curl' command with specified timeouts
+// response holds the outcome of a single executed request.
+type response struct {
+	body          []byte
+	statusCode    string
+	errorResponse string
+	timings       *report.Timings
+}
+
+// runCurl executes the external 'curl' command (curlPath) with specified timeouts
 // and write-out flags, captures its stdout, splits the HTTP status code
-// and returns the response body if the status code is 2xx;
+// and timings and returns the response body if the status code is 2xx;
 // otherwise, returns an error.
-func runCurl(ctx context.Context, req *loader.APIRequest, debugMode bool) ([]byte, string, string, error) {
+func runCurl(ctx context.Context, req *loader.APIRequest, curlPath string, debugMode bool) (*response, error) {
 	cmdArgs := req.CurlCmdArguments()
 
 	var stdout, stderr bytes.Buffer
 
-	cmd := exec.CommandContext(ctx, "./lib/curl.exe", cmdArgs...)
+	cmd := exec.CommandContext(ctx, curlPath, cmdArgs...)
 
 	if debugMode {
 		fmt.Printf("\n%s\n\n", buildCurlFormat(cmd.String())) //nolint:forbidigo
@@ -33,9 +43,7 @@ func runCurl(ctx context.Context, req *loader.APIRequest, debugMode bool) ([]byt
 	logger.Debugf(`Executing endpoint request "%s"`, req.Request.Endpoint)
 	logger.Infof(`Description: "%s"`, req.Request.Description)
 
-	start := time.Now()
 	err := cmd.Run()
-	duration := time.Since(start)
 	rawOutput := stdout.Bytes()
 
 	if err != nil {
@@ -43,15 +51,16 @@ func runCurl(ctx context.Context, req *loader.APIRequest, debugMode bool) ([]byt
 		logger.Errorf("StdOut response: %s", stdout.String())
 		logger.Errorf("StdErr response: %s", stderr.String())
 
-		return nil, "", stderr.String(), fmt.Errorf("curl error: %w", err)
+		return &response{errorResponse: stderr.String()}, fmt.Errorf("curl error: %w", err)
 	}
 
-	body, statusCode, err := extractStatusCode(rawOutput)
+	body, statusCode, timings, err := extractWriteOut(rawOutput)
 	if err != nil {
-		return nil, "", stdout.String(), err
+		return &response{errorResponse: stdout.String()}, err
 	}
 
-	logger.Debugf("Status: %s, Duration: %dms", statusCode, duration.Milliseconds())
+	logger.Debugf("Status: %s, Duration: %.0fms (DNS: %.0fms, Connect: %.0fms, TLS: %.0fms, TTFB: %.0fms)",
+		statusCode, timings.TotalMs, timings.DNSMs, timings.ConnectMs, timings.TLSMs, timings.TTFBMs)
 
 	if !strings.HasPrefix(statusCode, "2") {
 		logger.Warnf("Non-2xx status code received: status %s", statusCode)
@@ -59,26 +68,74 @@ func runCurl(ctx context.Context, req *loader.APIRequest, debugMode bool) ([]byt
 		logger.Warnf("StdOut response: %s", stdout.String())
 		logger.Warnf("StdErr response: %s", stderr.String())
 
-		return nil, statusCode, string(body), fmt.Errorf("status %s", statusCode)
+		return &response{statusCode: statusCode, errorResponse: string(body), timings: timings},
+			fmt.Errorf("status %s", statusCode)
 	}
 
-	return body, statusCode, "", nil
+	return &response{body: body, statusCode: statusCode, timings: timings}, nil
 }
 
-// extractStatusCode splits the raw output from curl (where the last
-// three bytes encode the HTTP status code) into the response body
-// and the status code string.
-func extractStatusCode(output []byte) ([]byte, string, error) {
-	const HTTPCodeLength = 3
+// extractWriteOut splits the raw output from curl (where the write-out data
+// is appended behind loader.CurlWriteOutMarker) into the response body,
+// the status code string and the request timings.
+func extractWriteOut(output []byte) ([]byte, string, *report.Timings, error) {
+	markerIndex := bytes.LastIndex(output, []byte(loader.CurlWriteOutMarker))
+	if markerIndex < 0 {
+		logger.Warnf("Output does not contain write-out data: only %d bytes", len(output))
+
+		return nil, "", nil, fmt.Errorf("no write-out data in %d bytes", len(output))
+	}
+
+	body := output[:markerIndex]
+	fields := strings.Fields(string(output[markerIndex+len(loader.CurlWriteOutMarker):]))
+
+	const writeOutFieldCount = 6
+
+	if len(fields) != writeOutFieldCount {
+		logger.Warnf(`Unexpected write-out data "%s"`, strings.Join(fields, " "))
+
+		return nil, "", nil, errors.New("invalid write-out data")
+	}
+
+	seconds := make([]float64, writeOutFieldCount-1)
+
+	for idx := range seconds {
+		value, err := strconv.ParseFloat(fields[idx], 64)
+		if err != nil {
+			logger.Warnf(`Invalid timing value "%s". Error: %v`, fields[idx], err)
+
+			return nil, "", nil, err
+		}
 
-	if len(output) < HTTPCodeLength {
-		logger.Warnf("Output too short to contain status code: only %d bytes", len(output))
+		seconds[idx] = value
+	}
+
+	return body, fields[writeOutFieldCount-1], buildTimings(seconds), nil
+}
 
-		return nil, "", fmt.Errorf("only %d bytes", len(output))
+// buildTimings converts the cumulative curl timings (in seconds) of
+// namelookup, connect, appconnect, starttransfer and total into
+// the duration of each phase in milliseconds. The TTFB is the wait for
+// the first response byte after the connection (and TLS handshake) is
+// established, the total is the duration of the whole request.
+func buildTimings(seconds []float64) *report.Timings {
+	const msPerSecond = 1000
+
+	nameLookup, connect, appConnect, startTransfer, total := seconds[0], seconds[1], seconds[2], seconds[3], seconds[4]
+
+	timings := &report.Timings{
+		DNSMs:     nameLookup * msPerSecond,
+		ConnectMs: (connect - nameLookup) * msPerSecond,
+		TLSMs:     0,
+		TTFBMs:    (startTransfer - connect) * msPerSecond,
+		TotalMs:   total * msPerSecond,
 	}
 
-	body := output[:len(output)-HTTPCodeLength]
-	statusCode := string(output[len(output)-HTTPCodeLength:])
+	// appconnect is zero for plain HTTP (no TLS handshake).
+	if appConnect > 0 {
+		timings.TLSMs = (appConnect - connect) * msPerSecond
+		timings.TTFBMs = (startTransfer - appConnect) * msPerSecond
+	}
 
-	return body, statusCode, nil
+	return timings
 }
@@ -0,0 +1,106 @@
+package exec_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+func TestExtractWriteOut(t *testing.T) {
+	const writeOut = loader.CurlWriteOutMarker + "0.001 0.002 0 0.010 0.012 "
+
+	tests := []struct {
+		name       string
+		output     string
+		wantBody   string
+		wantStatus string
+		wantErr    bool
+	}{
+		{
+			name:       "json body",
+			output:     `{"id":1}` + writeOut + "200",
+			wantBody:   `{"id":1}`,
+			wantStatus: "200",
+		},
+		{
+			name:       "empty body",
+			output:     writeOut + "204",
+			wantBody:   "",
+			wantStatus: "204",
+		},
+		{
+			name:       "body containing the marker",
+			output:     `{"text":"` + loader.CurlWriteOutMarker + ` 1 2 3"}` + writeOut + "200",
+			wantBody:   `{"text":"` + loader.CurlWriteOutMarker + ` 1 2 3"}`,
+			wantStatus: "200",
+		},
+		{name: "missing marker", output: `{"id":1}`, wantErr: true},
+		{name: "missing field", output: loader.CurlWriteOutMarker + "0.001 0.002 0 0.010 200", wantErr: true},
+		{name: "invalid timing", output: loader.CurlWriteOutMarker + "0.001 x 0 0.010 0.012 200", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, statusCode, timings, err := exec.ExtractWriteOut([]byte(tc.output))
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got body %q", body)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(body) != tc.wantBody || statusCode != tc.wantStatus || timings == nil {
+				t.Fatalf("expected %q %s, got %q %s %+v", tc.wantBody, tc.wantStatus, body, statusCode, timings)
+			}
+		})
+	}
+}
+
+func TestBuildTimings(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds []float64
+		want    report.Timings
+	}{
+		{
+			name:    "plain http",
+			seconds: []float64{0.010, 0.030, 0, 0.100, 0.150},
+			want:    report.Timings{DNSMs: 10, ConnectMs: 20, TLSMs: 0, TTFBMs: 70, TotalMs: 150},
+		},
+		{
+			name:    "https",
+			seconds: []float64{0.010, 0.030, 0.080, 0.100, 0.150},
+			want:    report.Timings{DNSMs: 10, ConnectMs: 20, TLSMs: 50, TTFBMs: 20, TotalMs: 150},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := exec.BuildTimings(tc.seconds)
+
+			for _, field := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"dns", got.DNSMs, tc.want.DNSMs},
+				{"connect", got.ConnectMs, tc.want.ConnectMs},
+				{"tls", got.TLSMs, tc.want.TLSMs},
+				{"ttfb", got.TTFBMs, tc.want.TTFBMs},
+				{"total", got.TotalMs, tc.want.TotalMs},
+			} {
+				if math.Abs(field.got-field.want) > 0.001 {
+					t.Errorf("expected %s %.3fms, got %.3fms", field.name, field.want, field.got)
+				}
+			}
+		})
+	}
+}
@@ -2,29 +2,27 @@ package exec
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/sven-seyfert/apiprobe/internal/auth"
+	"github.com/sven-seyfert/apiprobe/internal/db"
 	"github.com/sven-seyfert/apiprobe/internal/diff"
 	"github.com/sven-seyfert/apiprobe/internal/fileutil"
 	"github.com/sven-seyfert/apiprobe/internal/loader"
 	"github.com/sven-seyfert/apiprobe/internal/logger"
 	"github.com/sven-seyfert/apiprobe/internal/report"
-	"github.com/sven-seyfert/apiprobe/internal/util"
 )
 
 // ProcessFirstRequest executes the APIRequest (including optional test cases),
-// compares the response against existing output, and triggers the webhook
-// if differences are detected.
+// compares the response against existing output, records the execution in
+// the run history and triggers the webhook if differences are detected.
 func ProcessFirstRequest(
 	ctx context.Context,
 	idx int,
 	req *loader.APIRequest,
 	testCaseIndex *int,
-	res *report.Result,
-	rep *report.Report,
-	tokenStore *auth.TokenStore,
-	debugMode bool,
+	session *Session,
 ) {
 	if testCaseIndex != nil {
 		logger.NewLine()
@@ -33,61 +31,83 @@ func ProcessFirstRequest(
 
 	const noTestCaseIndicator = -1
 
-	outputFile := fileutil.BuildOutputFilePath(req, testCaseIndex)
+	reportIndex := noTestCaseIndicator
+	if testCaseIndex != nil {
+		reportIndex = *testCaseIndex
+	}
+
+	res, rep := session.Result, session.Report
+	outputFile := fileutil.BuildOutputFilePath(session.OutputDir, req, testCaseIndex)
+
+	resp, err := executeRequest(ctx, req, session.CurlPath, session.DebugMode)
+
+	if resp.timings != nil {
+		rep.AddTiming(req, *resp.timings, reportIndex)
+	}
 
-	response, statusCode, errorResponse, err := executeRequest(ctx, req, debugMode)
 	if err != nil {
 		logger.Errorf(`Failed endpoint request "%s": %v`, req.Request.Endpoint, err)
 		res.IncreaseRequestErrorCount()
-
-		if testCaseIndex != nil {
-			rep.AddReportData(req, statusCode, errorResponse, outputFile, *testCaseIndex)
-		} else {
-			rep.AddReportData(req, statusCode, errorResponse, outputFile, noTestCaseIndicator)
-		}
+		rep.AddReportData(req, report.IssueRequestError, resp.statusCode, resp.errorResponse, outputFile, reportIndex, resp.timings)
+		session.recordExecution(req, reportIndex, resp, db.StatusRequestError, "", buildErrorText(err, resp))
 
 		return
 	}
 
-	result, err := formatResponse(ctx, req, response)
+	latency := ""
+	if resp.timings != nil {
+		latency = report.EvaluateLatency(req, *resp.timings)
+	}
+
+	if latency != "" {
+		logger.Warnf(`Latency %s for "%s": %.0fms`, latency, req.Request.Endpoint, resp.timings.TotalMs)
+		res.IncreaseLatencyCount(latency)
+	}
+
+	result, err := formatResponse(ctx, req, resp.body)
 	if err != nil {
 		logger.Errorf("Failed processing JSON query by JQ. Error: %v", err)
 		res.IncreaseFormatErrorCount()
-
-		if testCaseIndex != nil {
-			rep.AddReportData(req, statusCode, errorResponse, outputFile, *testCaseIndex)
-		} else {
-			rep.AddReportData(req, statusCode, errorResponse, outputFile, noTestCaseIndicator)
-		}
+		rep.AddReportData(req, report.IssueFormatError, resp.statusCode, resp.errorResponse, outputFile, reportIndex, resp.timings)
+		session.recordExecution(req, reportIndex, resp, db.StatusFormatError, "", buildErrorText(err, resp))
 
 		return
 	}
 
 	if req.IsAuthRequest {
-		auth.AddAuthTokenToTokenStore(result, tokenStore, req)
+		auth.AddAuthTokenToTokenStore(result, session.TokenStore, req)
+		session.recordExecution(req, reportIndex, resp, db.StatusSuccess, "", "")
 
 		logger.Debugf("No output file will be written (unnecessary), because generic token result.")
 
 		return
 	}
 
-	hasChanged, err := diff.HasFileContentChanged(result, outputFile)
+	// With required approval, the baseline stays untouched until the change is approved.
+	hasChanged, previous, err := diff.HasFileContentChanged(result, outputFile, !session.RequireApproval)
 	if err != nil {
 		logger.Errorf("%v", err)
 
 		return
 	}
 
+	responseHash := hashContent(result)
+
 	if !hasChanged {
-		return
-	}
+		session.recordExecution(req, reportIndex, resp, db.StatusSuccess, responseHash, "")
+		session.discardPendingSnapshots(outputFile)
+	} else {
+		res.IncreaseChangedFilesCount()
 
-	res.IncreaseChangedFilesCount()
+		executionID := session.recordExecution(req, reportIndex, resp, db.StatusChanged, responseHash, "")
+		session.recordSnapshot(executionID, outputFile, responseHash, result, previous)
+	}
 
-	if testCaseIndex != nil {
-		rep.AddReportData(req, statusCode, errorResponse, outputFile, *testCaseIndex)
-	} else {
-		rep.AddReportData(req, statusCode, errorResponse, outputFile, noTestCaseIndicator)
+	switch {
+	case hasChanged:
+		rep.AddReportData(req, report.IssueChanged, resp.statusCode, resp.errorResponse, outputFile, reportIndex, resp.timings)
+	case latency != "":
+		rep.AddReportData(req, report.IssueLatency, resp.statusCode, resp.errorResponse, outputFile, reportIndex, resp.timings)
 	}
 }
 
@@ -97,45 +117,129 @@ func ProcessTestCasesRequests(
 	ctx context.Context,
 	req *loader.APIRequest,
 	idx int,
-	res *report.Result,
-	rep *report.Report,
-	tokenStore *auth.TokenStore,
-	debugMode bool,
+	session *Session,
 ) {
 	for testCaseIndex, testCase := range req.TestCases {
-		if testCase.ParamsData == "" && testCase.PostBodyData == "" {
+		modifiedReq, isExecuted := TestCaseRequest(req, testCase)
+		if !isExecuted {
 			continue
 		}
 
-		modifiedReq := *req
+		ProcessFirstRequest(ctx, idx+1, modifiedReq, &testCaseIndex, session)
+		logger.Infof("Test case: %s", testCase.Name)
+	}
+}
 
-		if testCase.ParamsData != "" {
-			modifiedReq.Request.Params = util.ReplaceQueryParam(req.Request.Params, testCase.ParamsData)
-		}
+// TestCaseRequest returns a copy of the request with the request overrides,
+// query params, POST body, data (placeholder values) and expectation of the
+// test case applied. Returns false if the test case has neither request
+// overrides, params, POST body data nor data (it is not executed).
+func TestCaseRequest(req *loader.APIRequest, testCase loader.TestCases) (*loader.APIRequest, bool) {
+	if testCase.Request == nil && testCase.ParamsData.IsEmpty() && testCase.PostBodyData == "" && testCase.Data == nil {
+		return nil, false
+	}
 
-		if testCase.PostBodyData != "" {
-			modifiedReq.Request.PostBody = testCase.PostBodyData
-		}
+	modifiedReq := *req
 
-		ProcessFirstRequest(ctx, idx+1, &modifiedReq, &testCaseIndex, res, rep, tokenStore, debugMode)
-		logger.Infof("Test case: %s", testCase.Name)
+	if testCase.Request != nil {
+		modifiedReq.Request = testCase.Request.Apply(req.Request)
+	}
+
+	if testCase.Expect != nil {
+		modifiedReq.Expect = testCase.Expect
 	}
+
+	if !testCase.ParamsData.IsEmpty() {
+		modifiedReq.Request.Params = testCase.ParamsData.Apply(modifiedReq.Request.Params)
+	}
+
+	if testCase.PostBodyData != "" {
+		modifiedReq.Request.PostBody = testCase.PostBodyData
+	}
+
+	if testCase.Data == nil {
+		return &modifiedReq, true
+	}
+
+	filledReq, err := modifiedReq.WithData(testCase.Data)
+	if err != nil {
+		logger.Errorf(`Failed to fill placeholders of test case "%s". Error: %v`, testCase.Name, err)
+
+		return nil, false
+	}
+
+	return filledReq, true
 }
 
-// executeRequest wraps runCurl to perform the HTTP request defined by APIRequest
-// and returns the raw response body, status code and potential error information.
-func executeRequest(ctx context.Context, req *loader.APIRequest, debugMode bool) ([]byte, string, string, error) {
-	curlOutput, statusCode, errorResponse, err := runCurl(ctx, req, debugMode)
+// checkExpectation evaluates the status code of the response against the
+// expected status of the request or test case. A matching non-2xx response
+// (like an expected 404) is a success, a mismatching 2xx response is a
+// request error. Failed curl executions stay request errors.
+func checkExpectation(resp *response, err error, expect *loader.Expectation) (*response, error) {
+	if resp.statusCode == "" {
+		return resp, err
+	}
+
+	if !expect.MatchStatus(resp.statusCode) {
+		if err == nil {
+			resp.errorResponse = string(resp.body)
+		}
+
+		return resp, fmt.Errorf("status %s, expected %s", resp.statusCode, expect.Status)
+	}
+
 	if err != nil {
-		return nil, statusCode, errorResponse, err
+		logger.Infof("Expected status %s received", resp.statusCode)
+
+		resp.body = []byte(resp.errorResponse)
+		resp.errorResponse = ""
 	}
 
-	return curlOutput, statusCode, errorResponse, nil
+	return resp, nil
 }
 
-// formatResponse formats the curl output using jq
-// and returns the filtered result.
+// executeRequest wraps runCurl (runGRPC or runStream for gRPC calls and streams) to perform the request
+// defined by APIRequest, checks the expectation and the errors of GraphQL responses
+// and returns the response (body, status code, timings and potential error information).
+func executeRequest(ctx context.Context, req *loader.APIRequest, curlPath string, debugMode bool) (*response, error) {
+	var (
+		resp *response
+		err  error
+	)
+
+	switch {
+	case req.Request.IsGRPC():
+		resp, err = runGRPC(ctx, req, debugMode)
+	case req.Request.IsStream():
+		resp, err = runStream(ctx, req, debugMode)
+	default:
+		resp, err = runCurl(ctx, req, curlPath, debugMode)
+	}
+
+	if req.Expect != nil {
+		resp, err = checkExpectation(resp, err, req.Expect)
+	}
+
+	if err == nil && req.Request.IsGraphQL() {
+		err = checkGraphQLErrors(resp)
+	}
+
+	return resp, err
+}
+
+// formatResponse formats the curl output using jq (XML by XPath, see
+// formatXML) and returns the filtered result.
 func formatResponse(ctx context.Context, req *loader.APIRequest, response []byte) ([]byte, error) {
+	// XML responses (like SOAP) are canonicalised, optionally as JSON for jq.
+	if isXML(response) {
+		formatted, isJSON, err := formatXML(req, response)
+		if err != nil || !isJSON {
+			return formatted, err
+		}
+
+		response = formatted
+	}
+
 	// If response is not JSON ("content-type: application/json"),
 	// it's plain text and therefore there is no need for jq formatting.
 	if !strings.HasPrefix(string(response), "{") && !strings.HasPrefix(string(response), "[") {
@@ -0,0 +1,38 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/db"
+)
+
+// Exported for the tests of the curl output parsing (package exec_test).
+var (
+	ExtractWriteOut = extractWriteOut
+	BuildTimings    = buildTimings
+)
+
+// NewTestSession returns a session with an in-memory database, temporary
+// output directories and the curl executable of the PATH.
+func NewTestSession(t *testing.T) *Session {
+	t.Helper()
+
+	conn, err := db.OpenMemory()
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	cfg := &config.Config{} //nolint:exhaustruct
+	cfg.Locations = config.DefaultPaths(t.TempDir())
+	cfg.Locations.Curl = "curl"
+
+	session, err := NewSession(conn, cfg, "test")
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+
+	return session
+}
@@ -0,0 +1,38 @@
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// graphQLResponse holds the errors of a GraphQL response.
+type graphQLResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// checkGraphQLErrors returns an error if the GraphQL response has errors,
+// also with a 2xx status code (GraphQL reports errors within the body).
+// Responses which are no JSON objects are not checked.
+func checkGraphQLErrors(resp *response) error {
+	var gqlResponse graphQLResponse
+
+	if err := json.Unmarshal(resp.body, &gqlResponse); err != nil || len(gqlResponse.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(gqlResponse.Errors))
+	for _, gqlError := range gqlResponse.Errors {
+		messages = append(messages, gqlError.Message)
+	}
+
+	logger.Warnf("GraphQL errors received: %s", resp.body)
+
+	resp.errorResponse = string(resp.body)
+
+	return fmt.Errorf("graphql errors: %s", strings.Join(messages, "; "))
+}
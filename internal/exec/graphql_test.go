@@ -0,0 +1,37 @@
+package exec_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+func TestGraphQLErrorsAreRequestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte(`{"data": null, "errors": [{"message": "user not found"}]}`))
+	}))
+	defer server.Close()
+
+	req := &loader.APIRequest{ID: "f6a7b8c9d0", IsActive: true, JqCommand: "."} //nolint:exhaustruct
+	req.Request.Method = "POST"
+	req.Request.BaseURL = server.URL
+	req.Request.Endpoint = "/graphql"
+	req.Request.PostBody = `{"query":"{ user(id: 1) { name } }"}`
+	req.Request.GraphQL = &loader.GraphQL{Query: "{ user(id: 1) { name } }"} //nolint:exhaustruct
+
+	session := exec.NewTestSession(t)
+	exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+	if session.Result.RequestErrorCount != 1 || len(session.Report.Requests) != 1 {
+		t.Fatalf("expected a request error, got %+v", session.Report.Requests)
+	}
+
+	if issue := session.Report.Requests[0]; issue.Issue != report.IssueRequestError || issue.StatusCode != "200" {
+		t.Errorf("unexpected report entry %+v", issue)
+	}
+}
@@ -0,0 +1,282 @@
+package exec
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// grpcTimeout is the maximum duration of a gRPC call (like --max-time of curl).
+const grpcTimeout = 24 * time.Second
+
+// runGRPC executes the unary gRPC call of the request with the JSON request
+// message (POST body) and the headers as metadata. Returns the response
+// message as JSON and the gRPC status (like "OK" or "NotFound") as status
+// code; a status other than OK is an error. The timings are set on every
+// response, also when the call fails before it is invoked.
+func runGRPC(ctx context.Context, req *loader.APIRequest, debugMode bool) (*response, error) {
+	target, isTLS := req.Request.GRPCTarget()
+	serviceName, methodName := req.Request.GRPCMethod()
+	fullMethod := "/" + serviceName + "/" + methodName
+
+	if debugMode {
+		fmt.Printf("\ngrpc %s %s %s\n\n", target, fullMethod, req.Request.PostBody) //nolint:forbidigo
+	}
+
+	logger.Debugf(`Executing gRPC call "%s"`, fullMethod)
+	logger.Infof(`Description: "%s"`, req.Request.Description)
+
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, grpcTimeout)
+	defer cancel()
+
+	creds := insecure.NewCredentials()
+	if isTLS {
+		// Like curl (--insecure), the server certificate is not verified.
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true}) //nolint:exhaustruct,gosec
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return &response{errorResponse: err.Error(), timings: grpcTimings(start)}, fmt.Errorf("grpc error: %w", err)
+	}
+
+	defer conn.Close()
+
+	files, err := resolveDescriptors(ctx, conn, req.Request.GRPC, serviceName)
+	if err != nil {
+		return grpcErrorResponse(err, grpcTimings(start)), fmt.Errorf("grpc descriptors: %w", err)
+	}
+
+	method, err := findMethod(files, serviceName, methodName)
+	if err != nil {
+		return &response{errorResponse: err.Error(), timings: grpcTimings(start)}, err
+	}
+
+	input := dynamicpb.NewMessage(method.Input())
+
+	if req.Request.PostBody != "" {
+		if err = protojson.Unmarshal([]byte(req.Request.PostBody), input); err != nil {
+			resp := &response{errorResponse: err.Error(), timings: grpcTimings(start)}
+
+			return resp, fmt.Errorf("invalid request message: %w", err)
+		}
+	}
+
+	output := dynamicpb.NewMessage(method.Output())
+	ctx = metadata.NewOutgoingContext(ctx, buildMetadata(req.Request.Headers))
+
+	invokeStart := time.Now()
+	err = conn.Invoke(ctx, fullMethod, input, output)
+	timings := grpcTimings(invokeStart)
+
+	if err != nil {
+		resp := grpcErrorResponse(err, timings)
+		logger.Warnf("Non-OK gRPC status received: status %s, message: %s", resp.statusCode, resp.errorResponse)
+
+		return resp, fmt.Errorf("status %s", resp.statusCode)
+	}
+
+	body, err := protojson.MarshalOptions{Resolver: dynamicpb.NewTypes(files)}.Marshal(output) //nolint:exhaustruct
+	if err != nil {
+		return &response{statusCode: loader.GRPCStatusOK, errorResponse: err.Error(), timings: timings}, err
+	}
+
+	logger.Debugf("Status: %s, Duration: %.0fms", loader.GRPCStatusOK, timings.TotalMs)
+
+	return &response{body: body, statusCode: loader.GRPCStatusOK, timings: timings}, nil
+}
+
+// grpcTimings returns the duration since start as timings of a gRPC call.
+func grpcTimings(start time.Time) *report.Timings {
+	return &report.Timings{TotalMs: float64(time.Since(start).Microseconds()) / 1000} //nolint:exhaustruct,mnd
+}
+
+// grpcErrorResponse returns the response of a failed gRPC call with the gRPC
+// status as status code and the status message as error response.
+func grpcErrorResponse(err error, timings *report.Timings) *response {
+	grpcStatus := status.Convert(err)
+
+	return &response{
+		body:          nil,
+		statusCode:    grpcStatus.Code().String(),
+		errorResponse: grpcStatus.Message(),
+		timings:       timings,
+	}
+}
+
+// resolveDescriptors returns the descriptors of the service: compiled from
+// the proto files or, without proto files, by server reflection.
+func resolveDescriptors(
+	ctx context.Context,
+	conn *grpc.ClientConn,
+	grpcDef *loader.GRPC,
+	serviceName string,
+) (*protoregistry.Files, error) {
+	if len(grpcDef.ProtoFiles) == 0 {
+		return reflectDescriptors(ctx, conn, serviceName)
+	}
+
+	compiler := protocompile.Compiler{ //nolint:exhaustruct
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: grpcDef.ImportPaths}), //nolint:exhaustruct
+	}
+
+	compiled, err := compiler.Compile(ctx, grpcDef.ProtoFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := new(protoregistry.Files)
+
+	for _, file := range compiled {
+		if err = files.RegisterFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// reflectDescriptors requests the file descriptors of the service (including
+// the dependencies) by the server reflection service (v1).
+func reflectDescriptors(ctx context.Context, conn *grpc.ClientConn, serviceName string) (*protoregistry.Files, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = stream.CloseSend() }()
+
+	protos := make(map[string]*descriptorpb.FileDescriptorProto)
+
+	request := &reflectionpb.ServerReflectionRequest{ //nolint:exhaustruct
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	}
+
+	for request != nil {
+		if err = receiveDescriptors(stream, request, protos); err != nil {
+			return nil, err
+		}
+
+		request = nil
+
+		// Request the missing dependencies by file name.
+		for _, fileProto := range protos {
+			for _, dependency := range fileProto.GetDependency() {
+				if _, found := protos[dependency]; !found {
+					request = &reflectionpb.ServerReflectionRequest{ //nolint:exhaustruct
+						MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dependency},
+					}
+				}
+			}
+		}
+	}
+
+	fileSet := &descriptorpb.FileDescriptorSet{File: make([]*descriptorpb.FileDescriptorProto, 0, len(protos))}
+	for _, fileProto := range protos {
+		fileSet.File = append(fileSet.File, fileProto)
+	}
+
+	return protodesc.NewFiles(fileSet)
+}
+
+// receiveDescriptors sends the reflection request and adds the received file
+// descriptors to the protos (by file name).
+func receiveDescriptors(
+	stream reflectionpb.ServerReflection_ServerReflectionInfoClient,
+	request *reflectionpb.ServerReflectionRequest,
+	protos map[string]*descriptorpb.FileDescriptorProto,
+) error {
+	if err := stream.Send(request); err != nil {
+		return err
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	if errResp := resp.GetErrorResponse(); errResp != nil {
+		return fmt.Errorf("reflection: %s", errResp.GetErrorMessage())
+	}
+
+	received := resp.GetFileDescriptorResponse().GetFileDescriptorProto()
+	if len(received) == 0 {
+		return errors.New("reflection: no file descriptors received")
+	}
+
+	for _, encoded := range received {
+		fileProto := new(descriptorpb.FileDescriptorProto)
+
+		if err = proto.Unmarshal(encoded, fileProto); err != nil {
+			return err
+		}
+
+		protos[fileProto.GetName()] = fileProto
+	}
+
+	return nil
+}
+
+// findMethod returns the descriptor of the unary method of the service.
+func findMethod(files *protoregistry.Files, serviceName string, methodName string) (protoreflect.MethodDescriptor, error) {
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf(`service "%s" not found`, serviceName)
+	}
+
+	service, isService := descriptor.(protoreflect.ServiceDescriptor)
+	if !isService {
+		return nil, fmt.Errorf(`"%s" is no service`, serviceName)
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf(`method "%s" not found in service "%s"`, methodName, serviceName)
+	}
+
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf(`method "%s" is no unary method`, methodName)
+	}
+
+	return method, nil
+}
+
+// buildMetadata returns the request headers ('Name: value') as gRPC metadata.
+func buildMetadata(headers []string) metadata.MD {
+	md := metadata.MD{}
+
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found {
+			continue
+		}
+
+		md.Append(strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value))
+	}
+
+	return md
+}
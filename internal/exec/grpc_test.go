@@ -0,0 +1,158 @@
+package exec_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+// healthProto is the health service (like grpc_health_v1) as proto file.
+const healthProto = `syntax = "proto3";
+package grpc.health.v1;
+message HealthCheckRequest { string service = 1; }
+message HealthCheckResponse {
+  enum ServingStatus { UNKNOWN = 0; SERVING = 1; NOT_SERVING = 2; SERVICE_UNKNOWN = 3; }
+  ServingStatus status = 1;
+}
+service Health { rpc Check(HealthCheckRequest) returns (HealthCheckResponse); }
+`
+
+// startGRPCServer starts an in-process gRPC server with the health service
+// and server reflection and returns its address.
+func startGRPCServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	go func() { _ = server.Serve(listener) }()
+
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestGRPCCallByReflectionAndProtoFile(t *testing.T) {
+	address := startGRPCServer(t)
+
+	protoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(protoDir, "health.proto"), []byte(healthProto), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, grpcDef := range []*loader.GRPC{
+		{ProtoFiles: nil, ImportPaths: nil},
+		{ProtoFiles: []string{"health.proto"}, ImportPaths: []string{protoDir}},
+	} {
+		req := &loader.APIRequest{ //nolint:exhaustruct
+			ID:        "a7b8c9d0e1",
+			IsActive:  true,
+			JqCommand: ".status",
+			Request: loader.Request{ //nolint:exhaustruct
+				Method:   loader.MethodGRPC,
+				BaseURL:  "grpc://" + address,
+				Endpoint: "/grpc.health.v1.Health/Check",
+				PostBody: `{"service": "orders"}`,
+				GRPC:     grpcDef,
+			},
+		}
+
+		session := exec.NewTestSession(t)
+		exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+		if len(session.Report.Requests) != 1 {
+			t.Fatalf("expected a new output file, got %+v", session.Report.Requests)
+		}
+
+		output, err := os.ReadFile(session.Report.Requests[0].OutputFile)
+		if err != nil || strings.TrimSpace(string(output)) != `"NOT_SERVING"` {
+			t.Errorf("got output %s (%v), want \"NOT_SERVING\"", output, err)
+		}
+	}
+}
+
+func TestGRPCStatusIsReported(t *testing.T) {
+	address := startGRPCServer(t)
+
+	req := &loader.APIRequest{ //nolint:exhaustruct
+		ID:        "a7b8c9d0e1",
+		IsActive:  true,
+		JqCommand: ".status",
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   loader.MethodGRPC,
+			BaseURL:  "grpc://" + address,
+			Endpoint: "/grpc.health.v1.Health/Check",
+			PostBody: `{"service": "unknown"}`,
+			GRPC:     &loader.GRPC{},
+		},
+	}
+
+	session := exec.NewTestSession(t)
+	exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+	if session.Result.RequestErrorCount != 1 || session.Report.Requests[0].StatusCode != "NotFound" {
+		t.Fatalf("expected request error with status NotFound, got %+v", session.Report.Requests)
+	}
+
+	// An expected status is no request error.
+	req.Expect = &loader.Expectation{Status: "NOT_FOUND"}
+
+	session = exec.NewTestSession(t)
+	exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+	if session.Result.RequestErrorCount != 0 {
+		t.Errorf("expected status reported as error: %+v", session.Report.Requests)
+	}
+}
+
+func TestGRPCExpectedStatusOfUnreachableServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := listener.Addr().String()
+	listener.Close()
+
+	// Server reflection fails before the call, the expected status is a success.
+	req := &loader.APIRequest{ //nolint:exhaustruct
+		ID:        "a7b8c9d0e1",
+		IsActive:  true,
+		JqCommand: ".status",
+		Expect:    &loader.Expectation{Status: "Unavailable"},
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   loader.MethodGRPC,
+			BaseURL:  "grpc://" + address,
+			Endpoint: "/grpc.health.v1.Health/Check",
+			PostBody: `{"service": "orders"}`,
+			GRPC:     &loader.GRPC{},
+		},
+	}
+
+	session := exec.NewTestSession(t)
+	exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+	if session.Result.RequestErrorCount != 0 || len(session.Report.Timings) != 1 {
+		t.Errorf("expected an expected status with timings, got %+v", session.Report)
+	}
+}
@@ -0,0 +1,140 @@
+package exec
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// HookRunner runs the setup and teardown hooks around the requests: the
+// suite hooks around all requests, the setup hooks of a definition file
+// before its first request and the teardown hooks after its last request.
+type HookRunner struct {
+	hooks   *loader.Hooks
+	session *Session
+
+	// Index of the last active request per definition file.
+	lastIndex map[string]int
+
+	// Definition files with executed setup and pending or executed teardown.
+	started  map[string]bool
+	finished map[string]bool
+
+	// Definition files with failed setup (requests are skipped).
+	failed map[string]bool
+
+	isSuiteFailed bool
+}
+
+// NewHookRunner returns the HookRunner for the hooks around the requests.
+func NewHookRunner(hooks *loader.Hooks, requests []*loader.APIRequest, session *Session) *HookRunner {
+	lastIndex := make(map[string]int)
+
+	for idx, req := range requests {
+		if req.IsActive {
+			lastIndex[req.JSONFilePath] = idx
+		}
+	}
+
+	return &HookRunner{
+		hooks:         hooks,
+		session:       session,
+		lastIndex:     lastIndex,
+		started:       make(map[string]bool),
+		finished:      make(map[string]bool),
+		failed:        make(map[string]bool),
+		isSuiteFailed: false,
+	}
+}
+
+// Start runs the suite setup hooks. Returns false if a hook failed, then
+// no request is executed.
+func (r *HookRunner) Start(ctx context.Context) bool {
+	if !r.runHooks(ctx, r.hooks.SuiteSetup) {
+		r.isSuiteFailed = true
+	}
+
+	return !r.isSuiteFailed
+}
+
+// BeforeRequest runs the setup hooks of the definition file of the request
+// (once, before its first request). Returns false if the request has to be
+// skipped, because a (suite or file) setup hook failed.
+func (r *HookRunner) BeforeRequest(ctx context.Context, req *loader.APIRequest) bool {
+	if r.isSuiteFailed {
+		return false
+	}
+
+	file := req.JSONFilePath
+
+	if !r.started[file] {
+		r.started[file] = true
+
+		if !r.runHooks(ctx, r.hooks.Setup[file]) {
+			logger.Warnf(`Skip requests of "%s" due to failed setup.`, file)
+
+			r.failed[file] = true
+		}
+	}
+
+	return !r.failed[file]
+}
+
+// AfterRequest runs the teardown hooks of the definition file of the
+// request, if it was the last request of the file.
+func (r *HookRunner) AfterRequest(ctx context.Context, idx int, req *loader.APIRequest) {
+	if r.lastIndex[req.JSONFilePath] == idx {
+		r.teardown(ctx, req.JSONFilePath)
+	}
+}
+
+// Finish runs the pending teardown hooks of the definition files (like after
+// a cancellation) and the suite teardown hooks. The teardown hooks run
+// regardless of failed requests, failed setup hooks or a cancelled context.
+func (r *HookRunner) Finish(ctx context.Context) {
+	files := make([]string, 0, len(r.started))
+
+	for file := range r.started {
+		files = append(files, file)
+	}
+
+	slices.Sort(files)
+
+	for _, file := range files {
+		r.teardown(ctx, file)
+	}
+
+	r.runHooks(context.WithoutCancel(ctx), r.hooks.SuiteTeardown)
+}
+
+// teardown runs the teardown hooks of the definition file (once).
+func (r *HookRunner) teardown(ctx context.Context, file string) {
+	if r.finished[file] {
+		return
+	}
+
+	r.finished[file] = true
+
+	r.runHooks(context.WithoutCancel(ctx), r.hooks.Teardown[file])
+}
+
+// runHooks executes the hooks in order and stops at the first failed hook
+// (which is reported as hook error). Returns false if a hook failed.
+func (r *HookRunner) runHooks(ctx context.Context, hooks []*loader.APIRequest) bool {
+	for _, hook := range hooks {
+		logger.Infof(`Hook: %s "%s", File: "%s"`, hook.Hook, hook.ID, hook.JSONFilePath)
+
+		resp, err := executeRequest(ctx, hook, r.session.CurlPath, r.session.DebugMode)
+		if err != nil {
+			logger.Errorf(`Failed %s hook "%s": %v`, hook.Hook, hook.ID, err)
+			r.session.Result.IncreaseHookErrorCount()
+			r.session.Report.AddHookData(hook, resp.statusCode, buildErrorText(err, resp))
+
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package exec_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+func TestHookRunnerRunsTeardownAfterFailedSetup(t *testing.T) {
+	var mutex sync.Mutex
+
+	var calls []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		calls = append(calls, req.URL.Path)
+		mutex.Unlock()
+
+		if req.URL.Path == "/seed" {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	// The hooks (and a probe without hook) of the definition files.
+	requests := make([]*loader.APIRequest, 0, 5) //nolint:mnd
+
+	for _, def := range []struct{ hook, file, endpoint string }{
+		{loader.HookSuiteSetup, "suite.json", "/login"},
+		{loader.HookSetup, "orders.json", "/seed"},
+		{loader.HookTeardown, "orders.json", "/cleanup"},
+		{loader.HookSuiteTeardown, "suite.json", "/logout"},
+		{"", "orders.json", "/orders"},
+	} {
+		requests = append(requests, &loader.APIRequest{ //nolint:exhaustruct
+			ID:           "b4d2c1e0f9",
+			IsActive:     true,
+			Hook:         def.hook,
+			JSONFilePath: def.file,
+			Request:      loader.Request{Method: "POST", BaseURL: server.URL, Endpoint: def.endpoint}, //nolint:exhaustruct
+		})
+	}
+
+	probes, hooks, err := loader.SplitHooks(requests)
+	if err != nil || len(probes) != 1 {
+		t.Fatalf("split: %v, %d probes", err, len(probes))
+	}
+
+	session := exec.NewTestSession(t)
+	runner := exec.NewHookRunner(hooks, probes, session)
+
+	// Cancelled after the setup (like an interrupt): the teardown hooks still run.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if !runner.Start(ctx) {
+		t.Fatal("suite setup failed")
+	}
+
+	if runner.BeforeRequest(ctx, probes[0]) {
+		t.Error("request not skipped after failed setup")
+	}
+
+	cancel()
+	runner.Finish(ctx)
+
+	want := []string{"/login", "/seed", "/cleanup", "/logout"}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] || calls[2] != want[2] || calls[3] != want[3] {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	if session.Result.HookErrorCount != 1 || session.Result.RequestErrorCount != 0 {
+		t.Errorf("got %d hook errors and %d request errors", session.Result.HookErrorCount, session.Result.RequestErrorCount)
+	}
+
+	if hook := session.Report.Requests[0]; hook.Issue != report.IssueHookError || hook.Hook != loader.HookSetup {
+		t.Errorf("unexpected report entry %+v", hook)
+	}
+}
+
+func TestSplitHooksRejectsUnknownHook(t *testing.T) {
+	hook := &loader.APIRequest{ID: "b4d2c1e0f9", IsActive: true, Hook: "before", JSONFilePath: "a.json"} //nolint:exhaustruct
+
+	if _, _, err := loader.SplitHooks([]*loader.APIRequest{hook}); err == nil {
+		t.Error("expected error for unknown hook")
+	}
+}
@@ -0,0 +1,190 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// ProcessScenario executes the steps of the scenario in order with shared
+// (extracted) variables. A failed step stops the scenario, unless the step
+// continues on failure; the following steps are skipped. The cleanup steps
+// run in any case (also after cancellation). A failed scenario is reported
+// as a single request error with the outcome of all steps.
+func ProcessScenario(ctx context.Context, idx int, req *loader.APIRequest, session *Session) {
+	variables := make(map[string]string)
+	steps := make([]report.ScenarioStep, 0, len(req.Scenario.Steps)+len(req.Scenario.Cleanup))
+	isFailed, isStopped := false, false
+
+	for stepIndex := range req.Scenario.Steps {
+		step := &req.Scenario.Steps[stepIndex]
+
+		if isStopped || ctx.Err() != nil {
+			steps = append(steps, skippedStep(step))
+
+			continue
+		}
+
+		logger.Infof(`Run: %d, Scenario step: %d "%s"`, idx, stepIndex+1, step.Name)
+
+		result := runScenarioStep(ctx, step, variables, session)
+		steps = append(steps, result)
+
+		if result.Status == report.StepFailed {
+			isFailed = true
+			isStopped = !step.ContinueOnFailure
+		}
+	}
+
+	// Cleanup steps also run after a cancellation (like test data deletion).
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	for stepIndex := range req.Scenario.Cleanup {
+		step := &req.Scenario.Cleanup[stepIndex]
+
+		logger.Infof(`Run: %d, Scenario cleanup step: %d "%s"`, idx, stepIndex+1, step.Name)
+
+		result := runScenarioStep(cleanupCtx, step, variables, session)
+		result.Cleanup = true
+		steps = append(steps, result)
+
+		if result.Status == report.StepFailed {
+			isFailed = true
+		}
+	}
+
+	const noTestCaseIndicator = -1
+
+	status := db.StatusSuccess
+	resp := &response{body: nil, statusCode: "", errorResponse: "", timings: nil}
+
+	if isFailed {
+		logger.Errorf(`Failed scenario "%s"`, req.ID)
+		session.Result.IncreaseRequestErrorCount()
+		failed := session.Report.AddScenarioData(req, steps)
+
+		status = db.StatusRequestError
+		resp.statusCode = failed.StatusCode
+		resp.errorResponse = failed.ErrorResponse
+	}
+
+	session.recordExecution(req, noTestCaseIndicator, resp, status, "", resp.errorResponse)
+}
+
+// runScenarioStep executes a single step with the placeholders filled by the
+// variables, checks the expectation and extracts the variables of the step.
+func runScenarioStep(
+	ctx context.Context,
+	step *loader.ScenarioStep,
+	variables map[string]string,
+	session *Session,
+) report.ScenarioStep {
+	result := report.ScenarioStep{
+		Name:       step.Name,
+		Method:     step.Target.Request.Method,
+		Endpoint:   step.Target.Request.Endpoint,
+		Status:     report.StepPassed,
+		StatusCode: "",
+		Error:      "",
+		DurationMs: 0,
+		Cleanup:    false,
+	}
+
+	fail := func(err error) report.ScenarioStep {
+		logger.Errorf(`Failed scenario step "%s": %v`, step.Name, err)
+
+		result.Status = report.StepFailed
+		result.Error = err.Error()
+
+		return result
+	}
+
+	stepReq, err := step.Target.WithVariables(variables)
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Endpoint = stepReq.Request.Endpoint
+
+	if step.Expect != nil {
+		stepReq.Expect = step.Expect
+	}
+
+	resp, err := executeRequest(ctx, stepReq, session.CurlPath, session.DebugMode)
+
+	result.StatusCode = resp.statusCode
+
+	if resp.timings != nil {
+		result.DurationMs = resp.timings.TotalMs
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	names := make([]string, 0, len(step.Extract))
+	for name := range step.Extract {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		value, extractErr := extractVariable(ctx, step.Extract[name], resp.body)
+		if extractErr != nil {
+			return fail(fmt.Errorf(`extract "%s": %w`, name, extractErr))
+		}
+
+		variables[name] = value
+	}
+
+	return result
+}
+
+// extractVariable returns the result of the jq filter on the response body
+// as variable value: strings without quotes, other values as JSON.
+func extractVariable(ctx context.Context, filter string, body []byte) (string, error) {
+	output, err := GoJQ(ctx, filter, body)
+	if err != nil {
+		return "", err
+	}
+
+	var value any
+
+	if err = json.Unmarshal(output, &value); err != nil {
+		return "", err
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return "", errors.New("no value (null)")
+	case string:
+		return typed, nil
+	default:
+		compacted, _ := json.Marshal(typed)
+
+		return strings.TrimSpace(string(compacted)), nil
+	}
+}
+
+// skippedStep returns the outcome of a step which is not executed.
+func skippedStep(step *loader.ScenarioStep) report.ScenarioStep {
+	return report.ScenarioStep{
+		Name:       step.Name,
+		Method:     step.Target.Request.Method,
+		Endpoint:   step.Target.Request.Endpoint,
+		Status:     report.StepSkipped,
+		StatusCode: "",
+		Error:      "",
+		DurationMs: 0,
+		Cleanup:    false,
+	}
+}
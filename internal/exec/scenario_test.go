@@ -0,0 +1,128 @@
+package exec_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/auth"
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// inlineStep returns a scenario step with an inline request.
+func inlineStep(name string, method string, baseURL string, endpoint string) loader.ScenarioStep {
+	return loader.ScenarioStep{ //nolint:exhaustruct
+		Name:    name,
+		Request: &loader.Request{Method: method, BaseURL: baseURL, Endpoint: endpoint}, //nolint:exhaustruct
+	}
+}
+
+func TestScenarioSharesVariablesAndRunsCleanup(t *testing.T) {
+	var mutex sync.Mutex
+
+	var calls []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		_, _ = io.ReadAll(req.Body)
+
+		mutex.Lock()
+		calls = append(calls, req.Method+" "+req.URL.Path)
+		mutex.Unlock()
+
+		switch req.URL.Path {
+		case "/carts":
+			_, _ = writer.Write([]byte(`{"cart": {"id": "c-42"}}`))
+		case "/carts/c-42/checkout":
+			writer.WriteHeader(http.StatusInternalServerError)
+		default:
+			writer.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	create := inlineStep("create cart", "POST", server.URL, "/carts")
+	create.Extract = map[string]string{"cartId": ".cart.id"}
+
+	scenario := &loader.APIRequest{ID: "5ce7a1f0b2", IsActive: true} //nolint:exhaustruct
+	scenario.Scenario = &loader.Scenario{
+		Steps: []loader.ScenarioStep{
+			create,
+			inlineStep("checkout", "POST", server.URL, "/carts/{{.cartId}}/checkout"),
+			inlineStep("verify order", "GET", server.URL, "/orders"),
+		},
+		Cleanup: []loader.ScenarioStep{inlineStep("delete cart", "DELETE", server.URL, "/carts/{{.cartId}}")},
+	}
+
+	if err := loader.ResolveScenarios(nil, []*loader.APIRequest{scenario}); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	session := exec.NewTestSession(t)
+	exec.ProcessScenario(context.Background(), 1, scenario, session)
+
+	want := []string{"POST /carts", "POST /carts/c-42/checkout", "DELETE /carts/c-42"}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] || calls[2] != want[2] {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	if session.Result.RequestErrorCount != 1 || len(session.Report.Requests) != 1 {
+		t.Fatalf("expected a single failed scenario, got %+v", session.Report.Requests)
+	}
+
+	statuses := []string{report.StepPassed, report.StepFailed, report.StepSkipped, report.StepPassed}
+	for idx, step := range session.Report.Requests[0].Steps {
+		if step.Status != statuses[idx] {
+			t.Errorf("step %d: got status %s, want %s", idx+1, step.Status, statuses[idx])
+		}
+	}
+}
+
+func TestScenarioStepUsesTokenOfReferencedPreRequest(t *testing.T) {
+	var mutex sync.Mutex
+
+	authHeaders := make(map[string]string)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		authHeaders[req.URL.Path] = req.Header.Get("Authorization")
+		mutex.Unlock()
+
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	orders := &loader.APIRequest{ID: "0f1e2d3c4b", IsActive: true, PreRequestID: "a1b2c3d4e5"} //nolint:exhaustruct
+	orders.Request = loader.Request{                                                           //nolint:exhaustruct
+		Method: "GET", BaseURL: server.URL, Endpoint: "/orders", Headers: []string{"Authorization: Bearer <auth-token>"},
+	}
+
+	profile := inlineStep("profile", "GET", server.URL, "/profile")
+	profile.Request.Headers = []string{"Authorization: Bearer <auth-token>"}
+
+	scenario := &loader.APIRequest{ID: "5ce7a1f0b2", IsActive: true, PreRequestID: "f6e5d4c3b2"} //nolint:exhaustruct
+	scenario.Scenario = &loader.Scenario{                                                        //nolint:exhaustruct
+		Steps: []loader.ScenarioStep{profile, {RequestID: orders.ID}}, //nolint:exhaustruct
+	}
+
+	loaded := []*loader.APIRequest{orders, scenario}
+
+	if err := loader.ResolveScenarios(loaded, []*loader.APIRequest{scenario}); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	session := exec.NewTestSession(t)
+	session.TokenStore.Add("f6e5d4c3b2", "scenario-token")
+	session.TokenStore.Add("a1b2c3d4e5", "orders-token")
+
+	auth.RepaceAuthTokenPlaceholderInRequestHeader(scenario, session.TokenStore)
+	exec.ProcessScenario(context.Background(), 1, scenario, session)
+
+	if authHeaders["/profile"] != "Bearer scenario-token" || authHeaders["/orders"] != "Bearer orders-token" {
+		t.Fatalf("unexpected authorization headers %v", authHeaders)
+	}
+}
@@ -0,0 +1,210 @@
+package exec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	"zombiezen.com/go/sqlite"
+
+	"github.com/sven-seyfert/apiprobe/internal/auth"
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/fileutil"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// Session bundles the state which is shared by all requests of one run:
+// result counters, report, token store and the run history (database
+// connection and run id).
+type Session struct {
+	Result     *report.Result
+	Report     *report.Report
+	TokenStore *auth.TokenStore
+	Conn       *sqlite.Conn
+	RunID      int64
+	DebugMode  bool
+
+	// Paths of the output directory and the curl executable.
+	OutputDir string
+	CurlPath  string
+
+	// Number of executed requests (including test cases).
+	ExecutedCount int
+
+	// Keep detected changes pending (baseline untouched) until approved.
+	RequireApproval bool
+}
+
+// NewSession creates a new run entry in the run history and returns
+// the Session for processing the requests of this run.
+func NewSession(conn *sqlite.Conn, cfg *config.Config, runName string) (*Session, error) {
+	hostname, _ := os.Hostname()
+
+	runID, err := db.InsertRun(conn, runName, hostname, config.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		Result:     &report.Result{},
+		Report:     &report.Report{RunID: runID},
+		TokenStore: auth.NewTokenStore(),
+		Conn:       conn,
+		RunID:      runID,
+		DebugMode:  cfg.DebugMode,
+
+		OutputDir: cfg.Locations.Output,
+		CurlPath:  cfg.Locations.Curl,
+
+		ExecutedCount: 0,
+
+		RequireApproval: cfg.Snapshots.RequireApproval,
+	}, nil
+}
+
+// Finish stores the result counters of the session in the run history.
+func (s *Session) Finish() {
+	res := s.Result
+
+	_ = db.FinishRun(s.Conn, s.RunID, db.RunCounts{
+		RequestErrors:   res.RequestErrorCount,
+		FormatErrors:    res.FormatResponseErrorCount,
+		ChangedFiles:    res.ChangedFilesCount,
+		LatencyFailed:   res.LatencyFailedCount,
+		LatencyDegraded: res.LatencyDegradedCount,
+		HookErrors:      res.HookErrorCount,
+	})
+}
+
+// recordExecution stores the execution of a request (or test case) in the
+// run history. Returns the execution id or 0 if storing failed.
+func (s *Session) recordExecution(
+	req *loader.APIRequest,
+	testCaseIndex int,
+	resp *response,
+	status string,
+	responseHash string,
+	errorText string,
+) int64 {
+	s.ExecutedCount++
+
+	exe := &db.Execution{
+		RunID:         s.RunID,
+		RequestID:     req.ID,
+		TestCase:      report.TestCaseName(req, testCaseIndex),
+		TestCaseIndex: testCaseIndex,
+		Tags:          req.Tags,
+		Method:        req.Request.Method,
+		Endpoint:      req.Request.Endpoint,
+		Status:        status,
+		StatusCode:    resp.statusCode,
+		Latency:       "",
+		DNSMs:         0,
+		ConnectMs:     0,
+		TLSMs:         0,
+		TTFBMs:        0,
+		TotalMs:       0,
+		ResponseHash:  responseHash,
+		ErrorText:     errorText,
+	}
+
+	if resp.timings != nil {
+		exe.Latency = report.EvaluateLatency(req, *resp.timings)
+		exe.DNSMs = resp.timings.DNSMs
+		exe.ConnectMs = resp.timings.ConnectMs
+		exe.TLSMs = resp.timings.TLSMs
+		exe.TTFBMs = resp.timings.TTFBMs
+		exe.TotalMs = resp.timings.TotalMs
+	}
+
+	executionID, err := db.InsertExecution(s.Conn, exe)
+	if err != nil {
+		return 0
+	}
+
+	return executionID
+}
+
+// recordSnapshot stores the new content of the output file as snapshot in
+// the run history (by the path relative to the output directory). The
+// previous content is stored beforehand, in case it is the first snapshot
+// of the file, to not lose the initial baseline. With RequireApproval the
+// snapshot is pending (only once per distinct content), otherwise it is
+// approved right away (baseline already updated).
+func (s *Session) recordSnapshot(
+	executionID int64,
+	outputFile string,
+	responseHash string,
+	content []byte,
+	previous []byte,
+) {
+	if executionID == 0 {
+		logger.Warnf(`No execution recorded, skip snapshot of "%s".`, outputFile)
+
+		return
+	}
+
+	snapshotFile := fileutil.RelativeOutputPath(s.OutputDir, outputFile)
+
+	count, err := db.CountSnapshots(s.Conn, snapshotFile)
+	if err != nil {
+		return
+	}
+
+	if count == 0 && len(previous) > 0 {
+		_, _ = db.InsertSnapshot(s.Conn, executionID, snapshotFile, hashContent(previous), previous, db.SnapshotApproved)
+	}
+
+	if !s.RequireApproval {
+		_, _ = db.InsertSnapshot(s.Conn, executionID, snapshotFile, responseHash, content, db.SnapshotApproved)
+
+		return
+	}
+
+	snapshotID, err := db.FindPendingSnapshot(s.Conn, snapshotFile, responseHash)
+	if err != nil {
+		return
+	}
+
+	if snapshotID == 0 {
+		snapshotID, err = db.InsertSnapshot(s.Conn, executionID, snapshotFile, responseHash, content, db.SnapshotPending)
+		if err != nil {
+			return
+		}
+	}
+
+	logger.Warnf(`Change of "%s" is pending approval. Review by --show-diff %d, then --approve %d or --reject %d.`,
+		outputFile, snapshotID, snapshotID, snapshotID)
+}
+
+// discardPendingSnapshots marks all pending snapshots of the output file as
+// obsolete, because the response matches the baseline again.
+func (s *Session) discardPendingSnapshots(outputFile string) {
+	if !s.RequireApproval {
+		return
+	}
+
+	_ = db.UpdatePendingSnapshotsState(s.Conn, fileutil.RelativeOutputPath(s.OutputDir, outputFile), db.SnapshotObsolete)
+}
+
+// hashContent returns the hex encoded SHA256 checksum of the content.
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// buildErrorText combines the error and the error response
+// (StdOut and/or StdErr) into a single text.
+func buildErrorText(err error, resp *response) string {
+	errorResponse := strings.TrimSpace(resp.errorResponse)
+	if errorResponse == "" {
+		return err.Error()
+	}
+
+	return err.Error() + ": " + errorResponse
+}
@@ -0,0 +1,254 @@
+package exec
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// streamTimeout is the maximum duration of a stream without duration (like
+// --max-time of curl).
+const streamTimeout = 24 * time.Second
+
+// maxEventSize is the maximum size of a single Server-Sent Events line.
+const maxEventSize = 1024 * 1024
+
+// collector collects the received messages of a stream until the count is reached.
+type collector struct {
+	messages []json.RawMessage
+	count    int
+	start    time.Time
+	first    time.Duration
+}
+
+// add adds the message (JSON or text, which becomes a JSON string) and returns
+// whether the count of messages is reached.
+func (c *collector) add(message []byte) bool {
+	if len(c.messages) == 0 {
+		c.first = time.Since(c.start)
+	}
+
+	if !json.Valid(message) {
+		message, _ = json.Marshal(string(message))
+	}
+
+	c.messages = append(c.messages, message)
+
+	return c.count > 0 && len(c.messages) >= c.count
+}
+
+// runStream connects to the WebSocket or Server-Sent Events stream of the
+// request, sends the messages (WebSocket) and collects the received messages
+// for the duration or until the count is reached. Returns the collected
+// messages as JSON array. Connection failures and less messages than the
+// count within the duration are errors.
+func runStream(ctx context.Context, req *loader.APIRequest, debugMode bool) (*response, error) {
+	stream := req.Request.Stream
+	url := req.BuildRequestURL()
+
+	if debugMode {
+		fmt.Printf("\nstream %s %s %s\n\n", req.Request.Method, url, stream.StreamMessages()) //nolint:forbidigo
+	}
+
+	logger.Debugf(`Executing stream request "%s"`, req.Request.Endpoint)
+	logger.Infof(`Description: "%s"`, req.Request.Description)
+
+	duration := streamTimeout
+	if stream.DurationMs > 0 {
+		duration = time.Duration(stream.DurationMs) * time.Millisecond
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	messages := &collector{messages: []json.RawMessage{}, count: stream.Count, start: time.Now(), first: 0}
+
+	var (
+		statusCode string
+		err        error
+	)
+
+	if stream.IsWebSocket {
+		statusCode, err = collectWebSocket(ctx, req, url, messages)
+	} else {
+		statusCode, err = collectEvents(ctx, req, url, messages)
+	}
+
+	timings := &report.Timings{ //nolint:exhaustruct
+		TTFBMs:  float64(messages.first.Microseconds()) / 1000,             //nolint:mnd
+		TotalMs: float64(time.Since(messages.start).Microseconds()) / 1000, //nolint:mnd
+	}
+
+	if err != nil {
+		logger.Warnf("Stream failed: status %s, error: %v", statusCode, err)
+
+		return &response{statusCode: statusCode, errorResponse: err.Error(), timings: timings},
+			fmt.Errorf("stream error: %w", err)
+	}
+
+	body, _ := json.Marshal(messages.messages)
+
+	if stream.Count > 0 && len(messages.messages) < stream.Count {
+		err = fmt.Errorf("timeout: received %d of %d messages", len(messages.messages), stream.Count)
+
+		return &response{statusCode: statusCode, errorResponse: string(body), timings: timings}, err
+	}
+
+	logger.Debugf("Status: %s, Messages: %d, Duration: %.0fms", statusCode, len(messages.messages), timings.TotalMs)
+
+	return &response{body: body, statusCode: statusCode, timings: timings}, nil
+}
+
+// collectWebSocket connects to the WebSocket, sends the messages and collects
+// the received messages until the count is reached, the context is done or the
+// server closes the connection. Returns the status code of the handshake.
+func collectWebSocket(ctx context.Context, req *loader.APIRequest, url string, messages *collector) (string, error) {
+	conn, resp, err := websocket.Dial(ctx, url, &websocket.DialOptions{ //nolint:exhaustruct
+		HTTPClient: streamHTTPClient(),
+		HTTPHeader: buildHeader(req),
+	})
+	if err != nil {
+		return responseStatus(resp), err
+	}
+
+	defer func() { _ = conn.Close(websocket.StatusNormalClosure, "") }()
+
+	statusCode := responseStatus(resp)
+
+	for _, message := range req.Request.Stream.StreamMessages() {
+		if err = conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+			return statusCode, err
+		}
+	}
+
+	for {
+		_, message, readErr := conn.Read(ctx)
+
+		switch {
+		case ctx.Err() != nil:
+			return statusCode, nil
+		case websocket.CloseStatus(readErr) == websocket.StatusNormalClosure:
+			return statusCode, nil
+		case readErr != nil:
+			return statusCode, readErr
+		}
+
+		if messages.add(message) {
+			return statusCode, nil
+		}
+	}
+}
+
+// collectEvents requests the Server-Sent Events stream and collects the data
+// of the events until the count is reached, the context is done or the server
+// closes the stream. Returns the status code of the response.
+func collectEvents(ctx context.Context, req *loader.APIRequest, url string, messages *collector) (string, error) {
+	var body io.Reader
+	if req.Request.PostBody != "" {
+		body = strings.NewReader(req.Request.PostBody)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Request.Method, url, body)
+	if err != nil {
+		return "", err
+	}
+
+	httpReq.Header = buildHeader(req)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := streamHTTPClient().Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	statusCode := responseStatus(resp)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		errorResponse, _ := io.ReadAll(resp.Body)
+
+		return statusCode, fmt.Errorf("status %s: %s", statusCode, errorResponse)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxEventSize)
+
+	var data []string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event (with data).
+		if line == "" {
+			if len(data) > 0 && messages.add([]byte(strings.Join(data, "\n"))) {
+				return statusCode, nil
+			}
+
+			data = nil
+
+			continue
+		}
+
+		if value, found := strings.CutPrefix(line, "data:"); found {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+
+	if err = scanner.Err(); err != nil && ctx.Err() == nil {
+		return statusCode, err
+	}
+
+	return statusCode, nil
+}
+
+// streamHTTPClient returns the HTTP client of the streams. Like curl
+// (--insecure), the server certificate is not verified.
+func streamHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()      //nolint:forcetypeassert
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:exhaustruct,gosec
+
+	return &http.Client{Transport: transport} //nolint:exhaustruct
+}
+
+// buildHeader returns the request headers ('Name: value') and the basic auth
+// of the request as HTTP header.
+func buildHeader(req *loader.APIRequest) http.Header {
+	header := http.Header{}
+
+	for _, line := range req.Request.Headers {
+		name, value, found := strings.Cut(line, ":")
+		if found {
+			header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+	}
+
+	if req.Request.BasicAuth != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(req.Request.BasicAuth)))
+	}
+
+	return header
+}
+
+// responseStatus returns the status code of the response, empty without response.
+func responseStatus(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+
+	return strconv.Itoa(resp.StatusCode)
+}
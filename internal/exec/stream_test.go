@@ -0,0 +1,105 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coder/websocket"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestWebSocketStreamCollectsMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Accept(writer, req, nil)
+		if err != nil {
+			return
+		}
+
+		defer func() { _ = conn.CloseNow() }()
+
+		// Echo the subscription, then push prices.
+		_, subscription, err := conn.Read(req.Context())
+		if err != nil {
+			return
+		}
+
+		_ = conn.Write(req.Context(), websocket.MessageText, subscription)
+
+		for price := 1; price <= 5; price++ {
+			_ = conn.Write(req.Context(), websocket.MessageText, []byte(fmt.Sprintf(`{"price": %d}`, price)))
+		}
+
+		_, _, _ = conn.Read(req.Context())
+	}))
+	defer server.Close()
+
+	stream := &loader.Stream{Messages: []json.RawMessage{[]byte(`"subscribe"`)}, Count: 3, IsWebSocket: true} //nolint:exhaustruct
+
+	req := &loader.APIRequest{ //nolint:exhaustruct
+		ID:        "b8c9d0e1f2",
+		IsActive:  true,
+		JqCommand: ".",
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   loader.MethodWebSocket,
+			BaseURL:  "ws" + strings.TrimPrefix(server.URL, "http"),
+			Endpoint: "/prices",
+			Stream:   stream,
+		},
+	}
+
+	session := exec.NewTestSession(t)
+	exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+	if len(session.Report.Requests) != 1 || session.Result.RequestErrorCount != 0 {
+		t.Fatalf("expected a new output file, got %+v", session.Report.Requests)
+	}
+
+	output, _ := os.ReadFile(session.Report.Requests[0].OutputFile)
+
+	want := "[\n  \"subscribe\",\n  {\n    \"price\": 1\n  },\n  {\n    \"price\": 2\n  }\n]"
+	if strings.TrimSpace(string(output)) != want {
+		t.Errorf("got output %s, want %s", output, want)
+	}
+}
+
+func TestServerSentEventsTimeoutIsRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		writer.Header().Set("Content-Type", "text/event-stream")
+		_, _ = writer.Write([]byte(": comment\nevent: price\ndata: {\"price\": 1}\n\n"))
+		writer.(http.Flusher).Flush()
+
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	req := &loader.APIRequest{ //nolint:exhaustruct
+		ID:        "b8c9d0e1f2",
+		IsActive:  true,
+		JqCommand: ".",
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   "GET",
+			BaseURL:  server.URL,
+			Endpoint: "/prices",
+			Stream:   &loader.Stream{Count: 2, DurationMs: 300}, //nolint:exhaustruct
+		},
+	}
+
+	session := exec.NewTestSession(t)
+	exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+	if session.Result.RequestErrorCount != 1 {
+		t.Fatalf("expected request error, got %+v", session.Report.Requests)
+	}
+
+	if got := session.Report.Requests[0].ErrorResponse; got != `[{"price":1}]` {
+		t.Errorf("got error response %s", got)
+	}
+}
@@ -0,0 +1,306 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"golang.org/x/net/html/charset"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+// xmlElement is an element of a parsed XML document. Names keep their
+// namespace prefix (like 'soap:Envelope'), the text is the trimmed
+// character data of the element.
+type xmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*xmlElement
+}
+
+// xpathResult is a node selected by XPath: an element or the text of an
+// attribute or text node.
+type xpathResult struct {
+	element *xmlElement
+	text    string
+}
+
+// isXML returns whether the response looks like an XML document (like a
+// SOAP envelope).
+func isXML(response []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(response), []byte("<"))
+}
+
+// formatXML selects the nodes of the XPath of the request and returns them
+// (or the whole document) canonicalised: either pretty-printed as XML or
+// converted to JSON (for jq), which is returned as second value. Responses
+// which are no XML documents (like HTML pages) are returned unchanged,
+// unless XPath or conversion are defined.
+func formatXML(req *loader.APIRequest, response []byte) ([]byte, bool, error) {
+	var (
+		results []xpathResult
+		err     error
+	)
+
+	if req.XPath != "" {
+		results, err = selectXPath(response, req.XPath)
+	} else {
+		var root *xmlElement
+
+		root, err = parseXML(response)
+		results = []xpathResult{{element: root, text: ""}}
+	}
+
+	if err != nil {
+		if req.XPath == "" && !req.XMLToJSON {
+			return response, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	if req.XMLToJSON {
+		output, err := xmlResultsToJSON(results)
+
+		return output, true, err
+	}
+
+	var buf bytes.Buffer
+
+	for _, result := range results {
+		if result.element == nil {
+			buf.WriteString(result.text + "\n")
+
+			continue
+		}
+
+		result.element.write(&buf, 0)
+	}
+
+	return buf.Bytes(), false, nil
+}
+
+// parseXML parses the XML document into its root element. Comments,
+// processing instructions (like the XML declaration) and whitespace between
+// elements are dropped.
+func parseXML(data []byte) (*xmlElement, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var (
+		root  *xmlElement
+		stack []*xmlElement
+	)
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch typed := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{name: qualifiedName(typed.Name), attrs: typed.Copy().Attr, text: "", children: nil}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, element)
+			} else if root == nil {
+				root = element
+			}
+
+			stack = append(stack, element)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected end element %s", qualifiedName(typed.Name))
+			}
+
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			text := strings.TrimSpace(string(typed))
+			if text == "" || len(stack) == 0 {
+				continue
+			}
+
+			element := stack[len(stack)-1]
+			element.text = strings.TrimSpace(element.text + " " + text)
+		}
+	}
+
+	if root == nil || len(stack) > 0 {
+		return nil, errors.New("incomplete XML document")
+	}
+
+	return root, nil
+}
+
+// selectXPath returns the nodes of the XML document selected by the XPath
+// expression. Returns an error if no node matches.
+func selectXPath(data []byte, expr string) ([]xpathResult, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := xmlquery.QueryAll(doc, expr)
+	if err != nil {
+		return nil, fmt.Errorf("xpath %s: %w", expr, err)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("xpath %s: no match", expr)
+	}
+
+	results := make([]xpathResult, 0, len(nodes))
+
+	for _, node := range nodes {
+		if node.Type != xmlquery.ElementNode {
+			results = append(results, xpathResult{element: nil, text: strings.TrimSpace(node.InnerText())})
+
+			continue
+		}
+
+		element, err := parseXML([]byte(node.OutputXML(true)))
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, xpathResult{element: element, text: ""})
+	}
+
+	return results, nil
+}
+
+// xmlResultsToJSON converts the XPath results to JSON: a single result as
+// value, several results as array. An element becomes an object with its
+// name as key (see xmlElement.toJSON).
+func xmlResultsToJSON(results []xpathResult) ([]byte, error) {
+	values := make([]any, 0, len(results))
+
+	for _, result := range results {
+		if result.element == nil {
+			values = append(values, result.text)
+
+			continue
+		}
+
+		values = append(values, map[string]any{result.element.name: result.element.toJSON()})
+	}
+
+	if len(values) == 1 {
+		return json.Marshal(values[0])
+	}
+
+	return json.Marshal(values)
+}
+
+// toJSON returns the JSON value of the element: the text for elements
+// without attributes and children, otherwise an object with the attributes
+// ('@name'), the children (by name, repeated names as array) and the text
+// ('#text').
+func (e *xmlElement) toJSON() any {
+	if len(e.attrs) == 0 && len(e.children) == 0 {
+		return e.text
+	}
+
+	object := make(map[string]any, len(e.attrs)+len(e.children)+1)
+
+	for _, attr := range e.attrs {
+		object["@"+qualifiedName(attr.Name)] = attr.Value
+	}
+
+	for _, child := range e.children {
+		value := child.toJSON()
+
+		switch existing := object[child.name].(type) {
+		case nil:
+			object[child.name] = value
+		case []any:
+			object[child.name] = append(existing, value)
+		default:
+			object[child.name] = []any{existing, value}
+		}
+	}
+
+	if e.text != "" {
+		object["#text"] = e.text
+	}
+
+	return object
+}
+
+// write writes the element pretty-printed (indented by two spaces per level)
+// with sorted attributes (namespace declarations first).
+func (e *xmlElement) write(buf *bytes.Buffer, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	buf.WriteString(indent + "<" + e.name)
+
+	attrs := slices.Clone(e.attrs)
+	slices.SortStableFunc(attrs, func(a, b xml.Attr) int {
+		if isNamespaceDeclaration(a) != isNamespaceDeclaration(b) {
+			if isNamespaceDeclaration(a) {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(qualifiedName(a.Name), qualifiedName(b.Name))
+	})
+
+	for _, attr := range attrs {
+		buf.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		_ = xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteString(`"`)
+	}
+
+	switch {
+	case len(e.children) == 0 && e.text == "":
+		buf.WriteString("/>\n")
+	case len(e.children) == 0:
+		buf.WriteString(">")
+		_ = xml.EscapeText(buf, []byte(e.text))
+		buf.WriteString("</" + e.name + ">\n")
+	default:
+		buf.WriteString(">\n")
+
+		if e.text != "" {
+			buf.WriteString(indent + "  ")
+			_ = xml.EscapeText(buf, []byte(e.text))
+			buf.WriteString("\n")
+		}
+
+		for _, child := range e.children {
+			child.write(buf, depth+1)
+		}
+
+		buf.WriteString(indent + "</" + e.name + ">\n")
+	}
+}
+
+// qualifiedName returns the name with its namespace prefix (raw token names).
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// isNamespaceDeclaration returns whether the attribute declares a namespace.
+func isNamespaceDeclaration(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns"
+}
@@ -0,0 +1,96 @@
+package exec_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+// soapResponse is a SOAP envelope with insignificant whitespace.
+const soapResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:m="https://www.example.org/stock">
+  <soap:Body>   <m:GetPriceResponse currency="EUR" m:market="XETRA">
+      <m:Price>34.5</m:Price>
+      <m:Price>34.7</m:Price><m:Note/>
+    </m:GetPriceResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestFormatXMLResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		// The POST body (JSON string) is sent as XML.
+		if body, _ := io.ReadAll(req.Body); !strings.HasPrefix(string(body), "<soap:Envelope") {
+			writer.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/soap+xml")
+		_, _ = writer.Write([]byte(soapResponse))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		xpath     string
+		xmlToJSON bool
+		jq        string
+		want      string
+	}{
+		{
+			name: "canonical",
+			want: `<soap:Envelope xmlns:m="https://www.example.org/stock" xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body>
+    <m:GetPriceResponse currency="EUR" m:market="XETRA">
+      <m:Price>34.5</m:Price>
+      <m:Price>34.7</m:Price>
+      <m:Note/>
+    </m:GetPriceResponse>
+  </soap:Body>
+</soap:Envelope>
+`,
+		},
+		{name: "xpath", xpath: "//m:Price", want: "<m:Price>34.5</m:Price>\n<m:Price>34.7</m:Price>\n"},
+		{name: "xpath text", xpath: "//m:GetPriceResponse/@currency", want: "EUR\n"},
+		{
+			name:      "json",
+			xpath:     "//m:GetPriceResponse",
+			xmlToJSON: true,
+			jq:        `.["m:GetPriceResponse"] | {currency: .["@currency"], prices: .["m:Price"]}`,
+			want:      "{\n  \"currency\": \"EUR\",\n  \"prices\": [\n    \"34.5\",\n    \"34.7\"\n  ]\n}",
+		},
+	}
+
+	for _, test := range tests {
+		req := &loader.APIRequest{ID: "c9d0e1f2a3", IsActive: true, XPath: test.xpath, XMLToJSON: test.xmlToJSON} //nolint:exhaustruct
+		req.JqCommand = test.jq
+		req.Request.Method = "POST"
+		req.Request.BaseURL = server.URL
+		req.Request.Endpoint = "/stock"
+		req.Request.Headers = []string{"Content-Type: application/soap+xml"}
+		req.Request.PostBodyRaw = []byte(`"<soap:Envelope><soap:Body><m:GetPrice/></soap:Body></soap:Envelope>"`)
+
+		if err := req.PreparePostBody(); err != nil {
+			t.Fatalf("prepare: %v", err)
+		}
+
+		session := exec.NewTestSession(t)
+		exec.ProcessFirstRequest(context.Background(), 1, req, nil, session)
+
+		if len(session.Report.Requests) != 1 {
+			t.Fatalf("%s: expected a new output file, got %+v", test.name, session.Report.Requests)
+		}
+
+		output, _ := os.ReadFile(session.Report.Requests[0].OutputFile)
+		if string(output) != test.want {
+			t.Errorf("%s: got output\n%s\nwant\n%s", test.name, output, test.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package exitcode
+
+// Process exit codes of APIProbe. If several apply, the precedence is
+// Interrupted, ConfigError, RuntimeError, Failures, DeliveryFailed, Changes,
+// Success (e.g. an interrupted run is reported as Interrupted, even if
+// failures occurred).
+const (
+	// Success means all requests succeeded without changes.
+	Success = 0
+	// Changes means at least one output file changed (or a change is pending).
+	Changes = 1
+	// Failures means at least one request, format or latency failure occurred.
+	Failures = 2
+	// ConfigError means an invalid configuration, request definition or a
+	// failed command (like --add-secret).
+	ConfigError = 3
+	// DeliveryFailed means at least one notification could not be delivered.
+	DeliveryFailed = 4
+	// RuntimeError means the run was aborted by a failure of the environment
+	// (like the database or the log files), not by the configuration.
+	RuntimeError = 5
+	// Interrupted means the run was cancelled (SIGINT, SIGTERM).
+	Interrupted = 130
+)
+
+// Name returns the status name of the exit code, used in the summary line.
+func Name(code int) string {
+	switch code {
+	case Success:
+		return "success"
+	case Changes:
+		return "changes"
+	case Failures:
+		return "failures"
+	case ConfigError:
+		return "config-error"
+	case DeliveryFailed:
+		return "delivery-failed"
+	case RuntimeError:
+		return "runtime-error"
+	case Interrupted:
+		return "interrupted"
+	default:
+		return "unknown"
+	}
+}
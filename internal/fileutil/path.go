@@ -12,18 +12,26 @@ import (
 
 // BuildOutputFilePath computes the output file path for a
 // given APIRequest and optional test case index, by inserting
-// '-test-case-XX' into the JSON file name and nesting under
-// './data/output'.
-func BuildOutputFilePath(req *loader.APIRequest, testCaseIndex *int) string {
-	outputDir := "./data/output"
-
+// '-test-case-XX' (or '-<key>' for test cases with key, like data file
+// rows) into the definition file name and nesting under the output
+// directory (like './data/output'). The output file of a YAML definition
+// file is a JSON file too.
+func BuildOutputFilePath(outputDir string, req *loader.APIRequest, testCaseIndex *int) string {
 	fileExt := filepath.Ext(req.JSONFilePath)
 	file := req.JSONFilePath
 
-	if testCaseIndex != nil {
-		file = strings.Replace(file, fileExt, fmt.Sprintf("-test-case-%02d%s", *testCaseIndex+1, fileExt), 1)
-	} else {
-		file = strings.Replace(file, fileExt, fmt.Sprintf("-test-case-%02d%s", 0, fileExt), 1)
+	outputExt := fileExt
+	if loader.IsYAMLFile(file) {
+		outputExt = loader.ExtJSON
+	}
+
+	switch {
+	case testCaseIndex != nil && req.TestCases[*testCaseIndex].Key != "":
+		file = strings.Replace(file, fileExt, "-"+loader.FileKey(req.TestCases[*testCaseIndex].Key)+outputExt, 1)
+	case testCaseIndex != nil:
+		file = strings.Replace(file, fileExt, fmt.Sprintf("-test-case-%02d%s", *testCaseIndex+1, outputExt), 1)
+	default:
+		file = strings.Replace(file, fileExt, fmt.Sprintf("-test-case-%02d%s", 0, outputExt), 1)
 	}
 
 	return filepath.Join(outputDir, file)
@@ -42,3 +50,27 @@ func createOutputDir(outputPath string) error {
 
 	return nil
 }
+
+// RelativeOutputPath returns the output file path relative to the output
+// directory (like 'users-test-case-00.json'), which is independent of the
+// working directory (e.g. for the snapshots). Paths outside of the output
+// directory are returned unchanged.
+func RelativeOutputPath(outputDir string, outputFile string) string {
+	relative, err := filepath.Rel(outputDir, outputFile)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return outputFile
+	}
+
+	return relative
+}
+
+// ResolveOutputPath returns the output file path of a path relative to the
+// output directory (see RelativeOutputPath). Absolute paths are returned
+// unchanged.
+func ResolveOutputPath(outputDir string, relative string) string {
+	if filepath.IsAbs(relative) {
+		return relative
+	}
+
+	return filepath.Join(outputDir, relative)
+}
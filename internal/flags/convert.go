@@ -0,0 +1,127 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Formats of definition files.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// FileFormat is the definition file format of the --new-file flag. The flag
+// can be used without value (JSON) or with value, like --new-file=yaml.
+type FileFormat string
+
+// String returns the format.
+func (f *FileFormat) String() string {
+	return string(*f)
+}
+
+// Set sets the format of the flag value ("true" by the flag without value).
+func (f *FileFormat) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "true", FormatJSON:
+		*f = FormatJSON
+	case FormatYAML, "yml":
+		*f = FormatYAML
+	case "false":
+		*f = ""
+	default:
+		return fmt.Errorf(`unknown file format "%s" (json or yaml)`, value)
+	}
+
+	return nil
+}
+
+// IsBoolFlag allows the flag without value.
+func (f *FileFormat) IsBoolFlag() bool {
+	return true
+}
+
+// IsConvert checks whether a definition file should be converted. If so, it
+// converts the JSON file to YAML or the YAML file to JSON, writes it next to
+// the given file and returns an instruction to exit the program or not.
+func IsConvert(file string) (bool, error) {
+	if file == "" {
+		return false, nil
+	}
+
+	return true, convertDefinitionFile(file)
+}
+
+// convertDefinitionFile converts the definition file into the other format.
+// An existing target file is not overwritten.
+func convertDefinitionFile(file string) error {
+	const permissions = 0o644
+
+	if !loader.IsDefinitionFile(file) {
+		err := fmt.Errorf(`"%s" is no JSON or YAML definition file`, file)
+		logger.Errorf("Failed to convert file. Error: %v", err)
+
+		return err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		logger.Errorf(`Failed to read file "%s". Error: %v`, file, err)
+
+		return err
+	}
+
+	targetExt := loader.ExtYAML
+	convert := jsonDefinitionsToYAML
+
+	if loader.IsYAMLFile(file) {
+		targetExt = loader.ExtJSON
+		convert = loader.YAMLToJSON
+	}
+
+	target := strings.TrimSuffix(file, filepath.Ext(file)) + targetExt
+
+	if _, err = os.Stat(target); err == nil {
+		err = fmt.Errorf(`target file "%s" already exists`, target)
+		logger.Errorf("Failed to convert file. Error: %v", err)
+
+		return err
+	}
+
+	converted, err := convert(data)
+	if err != nil {
+		logger.Errorf(`Failed to convert file "%s". Error: %v`, file, err)
+
+		return err
+	}
+
+	if err = os.WriteFile(target, converted, permissions); err != nil {
+		logger.Errorf(`Failed to write file "%s". Error: %v`, target, err)
+
+		return err
+	}
+
+	fmt.Printf("Converted \"%s\" to \"%s\".\n", file, target)                          //nolint:forbidigo
+	fmt.Printf("Remove one of both files, otherwise the requests are loaded twice.\n") //nolint:forbidigo
+
+	return nil
+}
+
+// jsonDefinitionsToYAML validates the JSON definitions (an array of
+// definitions) and converts them to YAML.
+func jsonDefinitionsToYAML(data []byte) ([]byte, error) {
+	var definitions []json.RawMessage
+
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return nil, errors.Join(errors.New("JSON definition file must be an array of definitions"), err)
+	}
+
+	return loader.JSONToYAML(data)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/sven-seyfert/apiprobe/internal/config"
 	"github.com/sven-seyfert/apiprobe/internal/crypto"
 	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
 	"github.com/sven-seyfert/apiprobe/internal/logger"
 )
 
@@ -19,12 +20,34 @@ type CLIFlags struct {
 	Name          *string
 	ID            *string
 	Tags          *string
+	Select        *string
+	List          *bool
+	DryRun        *bool
 	ExcludeIDs    *string
 	ExcludeTags   *string
 	NewID         *bool
-	NewFile       *bool
+	NewFile       *FileFormat
+	Convert       *string
 	AddSecret     *string
 	NotifyChannel *string
+	History       *int
+	Pending       *bool
+	Snapshots     *string
+	ShowDiff      *int
+	Approve       *int
+	Reject        *int
+	Rollback      *int
+	Workspace     *string
+	ConfigFile    *string
+	InputDir      *string
+	OutputDir     *string
+	Database      *string
+	SeedFile      *string
+	ReportsDir    *string
+	LogsDir       *string
+	Curl          *string
+	SpoolDir      *string
+	TemplatesDir  *string
 }
 
 // Init defines and parses the CLI flags and returning their values.
@@ -38,16 +61,28 @@ func Init() *CLIFlags {
 	nameUsage := "Custom name for this test run (for this execution). Shown in the final notification to help identify the run.\n" +
 		"Example: --name \"Environment: PROD\"\n"
 
-	idUsage := "Specify the ten-character hex hash (id) of the request to run.\n" +
-		"The hash must match the JSON \"id\" value, in the JSON definition (input) files.\n" +
+	idUsage := "Specify a comma-separated list of ten-character hex hashes (ids) or id prefixes of the requests to run.\n" +
+		"The hashes must match (or prefix) the JSON \"id\" value, in the JSON definition (input) files.\n" +
 		"In combination with the --exclude-ids flag, exclude will be prioritized.\n" +
-		"Example: --id \"ff00fceb61\"\n"
+		"Example: --id \"ff00fceb61, bb55\"\n"
 
 	tagUsage := "Specify a comma-separated list of tags to select which requests to run.\n" +
 		"Tags must match the JSON \"tags\" value, in the JSON definition (input) files.\n" +
 		"In combination with the --exclude-ids flag, exclude will be prioritized.\n" +
 		"Example: --tags \"reqres, booker\"\n"
 
+	selectUsage := "Specify a boolean expression of tags to select which requests to run.\n" +
+		"Operators are 'and', 'or', 'not' and parentheses; 'id:<prefix>' matches request ids by prefix.\n" +
+		"Combined with --id and --tags, a request must match all of them.\n" +
+		"Example: --select \"(reqres or booker) and not slow and env-prod\"\n"
+
+	dryRunUsage := "Print the execution plan (requests, test cases, resolved URLs, headers and output files)\n" +
+		"with redacted secrets and exit. No request is sent and no file is written.\n" +
+		"Example: --dry-run --tags \"env-prod\"\n"
+
+	listUsage := "List the selected requests (including pre-requests) which would be executed and exit.\n" +
+		"Example: --list --select \"env-prod and not slow\"\n"
+
 	excludeIDsUsage := "Specify a comma-separated list of IDs to exclude from the execution.\n" +
 		"Requests containing ANY of these IDs will be excluded.\n" +
 		"The IDs must match the JSON \"id\" value, in the JSON definition (input) files.\n" +
@@ -61,9 +96,13 @@ func Init() *CLIFlags {
 		"JSON \"id\" value, in the JSON definition (input) file.\n" +
 		"Example: --new-id\n"
 
-	newFileUsage := "Generate a new JSON definition template file.\n" +
+	newFileUsage := "Generate a new JSON definition template file (or YAML by --new-file=yaml).\n" +
 		"Then enter the request values/data and done.\n" +
-		"Example: --new-file\n"
+		"Example: --new-file or --new-file=yaml\n"
+
+	convertUsage := "Convert a JSON definition file to YAML or a YAML definition file to JSON and exit.\n" +
+		"The converted file is written next to the given file (same name, other extension).\n" +
+		"Example: --convert \"data/input/reqres-api/users.json\"\n"
 
 	addSecretUsage := "Stores a secret (e.g., API request token, api-key, a bearer token or\n" +
 		"other request secrets) in the database and return a placeholder such as \"<secret-b29ff12b50>\".\n" +
@@ -74,23 +113,99 @@ func Init() *CLIFlags {
 		"The name must match a key in the 'webEx.webhooks' or 'msTeams.webhooks' map, in config file apiprobe.json.\n" +
 		"Example: --notify-channel \"prod\" or \"test\"\n"
 
+	historyUsage := "Show the run history (request executions) of the last N runs and exit.\n" +
+		"Combine with --id, --tags or --select to only show the history of the matching requests.\n" +
+		"Example: --history 10 --id \"ff00fceb61\"\n"
+
+	pendingUsage := "List all pending (not yet approved) snapshots of detected changes and exit.\n" +
+		"Pending snapshots exist if 'snapshots.requireApproval' is true, in config file apiprobe.json.\n" +
+		"Example: --pending\n"
+
+	snapshotsUsage := "List all snapshots (versions) of the given output file and exit.\n" +
+		"Example: --snapshots \"data/output/reqres-api/users-test-case-00.json\"\n"
+
+	showDiffUsage := "Show the diff between the current baseline (output file) and the snapshot with the given id.\n" +
+		"Example: --show-diff 12\n"
+
+	approveUsage := "Approve the pending snapshot with the given id. The snapshot becomes the new baseline.\n" +
+		"Example: --approve 12\n"
+
+	rejectUsage := "Reject the pending snapshot with the given id. The baseline stays untouched.\n" +
+		"Example: --reject 12\n"
+
+	rollbackUsage := "Roll back the baseline (output file) to the approved snapshot (version) with the given id.\n" +
+		"Example: --rollback 7\n"
+
+	workspaceUsage := "Root directory of the probe suite (config, data, db, reports, logs, lib, spool).\n" +
+		"Defaults to the environment variable " + config.EnvWorkspace + " or the working directory.\n" +
+		"Example: --workspace \"/var/lib/apiprobe/shop\"\n"
+
+	pathUsage := func(location string, envVar string, configKey string) string {
+		usage := fmt.Sprintf("Path of the %s. Overrides the environment variable %s", location, envVar)
+		if configKey != "" {
+			usage += fmt.Sprintf(" and the config key 'paths.%s'", configKey)
+		}
+
+		return usage + " (default relative to the workspace).\n"
+	}
+
 	cliFlags := &CLIFlags{
 		Name:          flag.String("name", "", nameUsage),
 		ID:            flag.String("id", "", idUsage),
 		Tags:          flag.String("tags", "", tagUsage),
+		Select:        flag.String("select", "", selectUsage),
+		List:          flag.Bool("list", false, listUsage),
+		DryRun:        flag.Bool("dry-run", false, dryRunUsage),
 		ExcludeIDs:    flag.String("exclude-ids", "", excludeIDsUsage),
 		ExcludeTags:   flag.String("exclude-tags", "", excludeTagsUsage),
 		NewID:         flag.Bool("new-id", false, newIDUsage),
-		NewFile:       flag.Bool("new-file", false, newFileUsage),
+		NewFile:       new(FileFormat),
+		Convert:       flag.String("convert", "", convertUsage),
 		AddSecret:     flag.String("add-secret", "", addSecretUsage),
 		NotifyChannel: flag.String("notify-channel", "", notifyChannelUsage),
+		History:       flag.Int("history", 0, historyUsage),
+		Pending:       flag.Bool("pending", false, pendingUsage),
+		Snapshots:     flag.String("snapshots", "", snapshotsUsage),
+		ShowDiff:      flag.Int("show-diff", 0, showDiffUsage),
+		Approve:       flag.Int("approve", 0, approveUsage),
+		Reject:        flag.Int("reject", 0, rejectUsage),
+		Rollback:      flag.Int("rollback", 0, rollbackUsage),
+		Workspace:     flag.String("workspace", "", workspaceUsage),
+		ConfigFile:    flag.String("config", "", pathUsage("config file apiprobe.json", config.EnvConfig, "")),
+		InputDir:      flag.String("input-dir", "", pathUsage("JSON definitions directory", config.EnvInput, "input")),
+		OutputDir:     flag.String("output-dir", "", pathUsage("response output directory", config.EnvOutput, "output")),
+		Database:      flag.String("db", "", pathUsage("SQLite database file", config.EnvDatabase, "database")),
+		SeedFile:      flag.String("seed", "", pathUsage("secrets seed CSV file", config.EnvSeed, "seed")),
+		ReportsDir:    flag.String("reports-dir", "", pathUsage("reports directory", config.EnvReports, "reports")),
+		LogsDir:       flag.String("logs-dir", "", pathUsage("logs directory", config.EnvLogs, "logs")),
+		Curl:          flag.String("curl", "", pathUsage("curl executable", config.EnvCurl, "curl")),
+		SpoolDir:      flag.String("spool-dir", "", pathUsage("notification spool directory", config.EnvSpool, "spool")),
+		TemplatesDir:  flag.String("templates-dir", "", pathUsage("payload templates directory", config.EnvTemplates, "templates")),
 	}
 
+	flag.Var(cliFlags.NewFile, "new-file", newFileUsage)
+
 	flag.Parse()
 
 	return cliFlags
 }
 
+// Paths returns the paths set by flags (empty if unset).
+func (f *CLIFlags) Paths() config.Paths {
+	return config.Paths{
+		Config:    *f.ConfigFile,
+		Input:     *f.InputDir,
+		Output:    *f.OutputDir,
+		Database:  *f.Database,
+		Seed:      *f.SeedFile,
+		Reports:   *f.ReportsDir,
+		Logs:      *f.LogsDir,
+		Curl:      *f.Curl,
+		Spool:     *f.SpoolDir,
+		Templates: *f.TemplatesDir,
+	}
+}
+
 // IsNewID checks whether a new ID should be generated, and if so,
 // produces a cryptographically secure hex hash and prints it and
 // returns an instruction to exit the program or not.
@@ -108,20 +223,20 @@ func IsNewID(isNewID bool) (bool, error) {
 		return complete, err
 	}
 
-	fmt.Printf(`Use this ID "%s" in your JSON file, key "id".`, hash) //nolint:forbidigo
+	fmt.Printf(`Use this ID "%s" in your JSON file, key "id".`+"\n", hash) //nolint:forbidigo
 
 	complete = true
 
 	return complete, nil
 }
 
-// IsNewFile checks if a new file should be created. If true, it generates an ID,
-// writes a new template JSON file, and returns true on success. Returns false
-// and an error if any step fails.
-func IsNewFile(isNewFile bool) (bool, error) {
+// IsNewFile checks if a new file should be created (format is set). If true, it
+// generates an ID, writes a new template JSON or YAML file, and returns true on
+// success. Returns false and an error if any step fails.
+func IsNewFile(format FileFormat, inputDir string) (bool, error) {
 	complete := false
 
-	if !isNewFile {
+	if format == "" {
 		return complete, nil
 	}
 
@@ -132,7 +247,7 @@ func IsNewFile(isNewFile bool) (bool, error) {
 		return complete, err
 	}
 
-	if err = writeNewTemplateJSONFile(hash); err != nil {
+	if err = writeNewTemplateFile(hash, format, inputDir); err != nil {
 		return complete, err
 	}
 
@@ -141,10 +256,10 @@ func IsNewFile(isNewFile bool) (bool, error) {
 	return complete, nil
 }
 
-// writeNewTemplateJSONFile creates a new JSON definition file (a template)
-// with a given ID as content. Returns an error if directory creation
-// or file writing fails.
-func writeNewTemplateJSONFile(hash string) error {
+// writeNewTemplateFile creates a new JSON or YAML definition file (a template)
+// with a given ID as content in the input directory. Returns an error if directory
+// creation or file writing fails.
+func writeNewTemplateFile(hash string, format FileFormat, inputDir string) error {
 	content := `[
     {
         "id": "${ID}",
@@ -165,35 +280,48 @@ func writeNewTemplateJSONFile(hash string) error {
         "testCases": [
             {
                 "name": "",
-                "paramsData": "",
+                "paramsData": {},
                 "postBodyData": {}
             }
         ],
         "tags": [
             "env-prod"
         ],
-        "jq": ""
+        "jq": "",
+        "maxDurationMs": {
+            "degraded": 0,
+            "failed": 0
+        }
     }
 ]`
 
 	const (
-		path              = "./data/input/"
-		file              = "new-template.json"
+		file              = "new-template"
 		createPermissions = 0o755
 		writePermissions  = 0o644
 	)
 
-	err := os.MkdirAll(filepath.Dir(path), createPermissions)
+	err := os.MkdirAll(inputDir, createPermissions)
 	if err != nil {
-		logger.Errorf(`Failed to create data/input directory "%s". Error: %v`, file, err)
+		logger.Errorf(`Failed to create input directory "%s". Error: %v`, inputDir, err)
 
 		return err
 	}
 
-	filePath := filepath.Join(path, file)
-	content = strings.Replace(content, "${ID}", hash, 1)
+	filePath := filepath.Join(inputDir, file+loader.ExtJSON)
+	data := []byte(strings.Replace(content, "${ID}", hash, 1))
+
+	if format == FormatYAML {
+		filePath = filepath.Join(inputDir, file+loader.ExtYAML)
+
+		if data, err = loader.JSONToYAML(data); err != nil {
+			logger.Errorf("Failed to convert template to YAML. Error: %v", err)
+
+			return err
+		}
+	}
 
-	err = os.WriteFile(filePath, []byte(content), writePermissions)
+	err = os.WriteFile(filePath, data, writePermissions)
 	if err != nil {
 		logger.Errorf(`Failed to write file "%s". Error: %v`, filePath, err)
 
@@ -238,7 +366,7 @@ func IsAddSecret(givenSecret string, conn *sqlite.Conn) (bool, error) {
 
 	fmt.Printf("%d ==> %d\n"+ //nolint:forbidigo
 		"Use this placeholder \"<secret-%s>\" in your JSON file "+
-		"instead of the actual secret value.", countBefore, countAfter, hash)
+		"instead of the actual secret value.\n", countBefore, countAfter, hash)
 
 	complete = true
 
@@ -0,0 +1,138 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"zombiezen.com/go/sqlite"
+
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/selector"
+)
+
+// IsHistory checks whether the run history should be shown. If so, it queries
+// the request executions of the last runCount runs (filtered by the selection),
+// prints them as table including a "failing since" summary per request and
+// returns an instruction to exit the program or not.
+func IsHistory(runCount int, selection *selector.Selection, conn *sqlite.Conn) (bool, error) {
+	complete := false
+
+	if runCount <= 0 {
+		return complete, nil
+	}
+
+	condition, args := selection.SQLCondition("e.request_id", "e.tags")
+
+	entries, err := db.SelectHistory(conn, runCount, condition, args...)
+	if err != nil {
+		return complete, err
+	}
+
+	complete = true
+
+	if len(entries) == 0 {
+		fmt.Println("No run history found.") //nolint:forbidigo
+
+		return complete, nil
+	}
+
+	printHistory(entries)
+	printFailingSince(entries)
+
+	return complete, nil
+}
+
+// printHistory prints the history entries as aligned table to stdout.
+func printHistory(entries []db.HistoryEntry) {
+	const padding = 2
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, padding, ' ', 0)
+
+	fmt.Fprintln(writer, "RUN\tSTARTED\tNAME\tID\tTEST CASE\tSTATUS\tCODE\tDURATION\tERROR")
+
+	for _, entry := range entries {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%.0fms\t%s\n",
+			entry.RunID,
+			entry.StartedAt,
+			entry.RunName,
+			entry.RequestID,
+			entry.TestCase,
+			statusWithLatency(entry),
+			entry.StatusCode,
+			entry.TotalMs,
+			truncate(entry.ErrorText),
+		)
+	}
+
+	_ = writer.Flush()
+}
+
+// printFailingSince prints, for each request (and test case) which failed
+// in the newest run, since when (oldest run of the current failure streak)
+// it is failing.
+func printFailingSince(entries []db.HistoryEntry) {
+	type streak struct {
+		since   string
+		count   int
+		stopped bool
+	}
+
+	streaks := make(map[string]*streak)
+	order := make([]string, 0)
+
+	// Entries are ordered from the newest to the oldest run.
+	for _, entry := range entries {
+		key := entry.RequestID + " " + entry.TestCase
+
+		current, exists := streaks[key]
+		if !exists {
+			current = &streak{since: "", count: 0, stopped: false}
+			streaks[key] = current
+			order = append(order, key)
+		}
+
+		if current.stopped {
+			continue
+		}
+
+		if entry.Status != db.StatusRequestError && entry.Status != db.StatusFormatError {
+			current.stopped = true
+
+			continue
+		}
+
+		current.since = entry.StartedAt
+		current.count++
+	}
+
+	fmt.Println() //nolint:forbidigo
+
+	for _, key := range order {
+		if current := streaks[key]; current.count > 0 {
+			fmt.Printf("%s is failing since %s (%d consecutive runs)\n", key, current.since, current.count) //nolint:forbidigo
+		}
+	}
+}
+
+// statusWithLatency returns the status extended by a possible latency state.
+func statusWithLatency(entry db.HistoryEntry) string {
+	if entry.Latency == "" {
+		return entry.Status
+	}
+
+	return entry.Status + " (latency " + entry.Latency + ")"
+}
+
+// truncate shortens a text to a single line of limited length.
+func truncate(text string) string {
+	const maxLength = 60
+
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxLength {
+		return text[:maxLength] + "..."
+	}
+
+	return text
+}
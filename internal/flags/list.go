@@ -0,0 +1,77 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+// PrintRequests prints the requests which would be executed (in execution
+// order) as aligned table to stdout. Requests which are not selected are
+// pre-requests of selected ones, inactive requests are skipped on execution.
+// Test cases without params, POST body data and data are not executed (not
+// counted), the request of a data file is a template (not executed).
+func PrintRequests(requests []*loader.APIRequest, selected []*loader.APIRequest) {
+	const padding = 2
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, padding, ' ', 0)
+
+	fmt.Fprintln(writer, "ID\tMETHOD\tENDPOINT\tTEST CASES\tTAGS\tFILE\tNOTE")
+
+	executed := 0
+
+	for _, req := range requests {
+		note := ""
+
+		switch {
+		case !req.IsActive:
+			note = "inactive (skipped)"
+		case !slices.Contains(selected, req):
+			note = "pre-request"
+		case req.IsScenario():
+			note = fmt.Sprintf("scenario (%d steps)", len(req.ScenarioSteps()))
+		}
+
+		testCases := countTestCases(req)
+
+		if req.IsActive {
+			executed += testCases
+		}
+
+		if req.IsActive && !req.IsDataDriven() {
+			executed++
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			req.ID,
+			req.Request.Method,
+			req.Request.Endpoint,
+			testCases,
+			strings.Join(req.Tags, ", "),
+			req.JSONFilePath,
+			note,
+		)
+	}
+
+	_ = writer.Flush()
+
+	fmt.Printf("\n%d requests, %d executions (including test cases).\n", len(requests), executed) //nolint:forbidigo
+}
+
+// countTestCases returns the number of executed test cases of the request.
+func countTestCases(req *loader.APIRequest) int {
+	count := 0
+
+	for _, testCase := range req.TestCases {
+		if _, isExecuted := exec.TestCaseRequest(req, testCase); isExecuted {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,194 @@
+package flags
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sven-seyfert/apiprobe/internal/crypto"
+	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/fileutil"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+// PrintPlan prints the ordered execution plan of the requests (with resolved
+// or redacted secrets) to stdout: per request and test case the method, the
+// resolved URL, headers, POST body and output file path. Requests which are
+// not selected are pre-requests of selected ones. The hooks are listed where
+// they run: the suite hooks around all requests, the hooks of a definition
+// file around its active requests.
+func PrintPlan(requests []*loader.APIRequest, selected []*loader.APIRequest, hooks *loader.Hooks, outputDir string) {
+	fmt.Println("Execution plan (dry run, no request is sent and no file is written):") //nolint:forbidigo
+
+	firstIndex, lastIndex := make(map[string]int), make(map[string]int)
+
+	for idx, req := range requests {
+		if !req.IsActive {
+			continue
+		}
+
+		if _, found := firstIndex[req.JSONFilePath]; !found {
+			firstIndex[req.JSONFilePath] = idx
+		}
+
+		lastIndex[req.JSONFilePath] = idx
+	}
+
+	printHooks("Suite setup", hooks.SuiteSetup)
+
+	executed := 0
+
+	for idx, req := range requests {
+		notes := make([]string, 0, 3) //nolint:mnd
+
+		if !slices.Contains(selected, req) {
+			notes = append(notes, "pre-request")
+		}
+
+		if req.IsAuthRequest {
+			notes = append(notes, "auth request")
+		}
+
+		if req.IsDataDriven() {
+			notes = append(notes, "data file "+req.TestData.File)
+		}
+
+		if !req.IsActive {
+			notes = append(notes, "inactive, skipped")
+		}
+
+		note := ""
+		if len(notes) > 0 {
+			note = " [" + strings.Join(notes, ", ") + "]"
+		}
+
+		if first, found := firstIndex[req.JSONFilePath]; found && idx == first {
+			printHooks("Setup", hooks.Setup[req.JSONFilePath])
+		}
+
+		fmt.Printf("\n%d. %s (%s)%s\n", idx+1, req.ID, req.JSONFilePath, note) //nolint:forbidigo
+
+		if !req.IsActive {
+			continue
+		}
+
+		executed += printRequestPlan(req, outputDir)
+
+		if idx == lastIndex[req.JSONFilePath] {
+			printHooks("Teardown", hooks.Teardown[req.JSONFilePath])
+		}
+	}
+
+	printHooks("Suite teardown", hooks.SuiteTeardown)
+
+	fmt.Printf("\n%d requests, %d executions (including test cases).\n", len(requests), executed) //nolint:forbidigo
+}
+
+// printRequestPlan prints the executions (request, test cases or scenario
+// steps) of an active request and returns their count.
+func printRequestPlan(req *loader.APIRequest, outputDir string) int {
+	if req.IsScenario() {
+		printScenarioSteps(req)
+
+		return 1
+	}
+
+	executed := 0
+
+	if !req.IsDataDriven() {
+		printPlanStep(req, 0, req.Request.Name, fileutil.BuildOutputFilePath(outputDir, req, nil))
+
+		executed++
+	}
+
+	for testCaseIndex, testCase := range req.TestCases {
+		modifiedReq, isExecuted := exec.TestCaseRequest(req, testCase)
+		if !isExecuted {
+			continue
+		}
+
+		outputFile := fileutil.BuildOutputFilePath(outputDir, req, &testCaseIndex)
+		printPlanStep(modifiedReq, testCaseIndex+1, testCase.Name, outputFile)
+
+		executed++
+	}
+
+	return executed
+}
+
+// printHooks prints the hooks of a kind (like "Setup") with their request.
+func printHooks(kind string, hooks []*loader.APIRequest) {
+	for _, hook := range hooks {
+		fmt.Printf("\n%s hook %s (%s)\n", kind, hook.ID, hook.JSONFilePath)            //nolint:forbidigo
+		fmt.Printf("   Request: %s %s\n", hook.Request.Method, hook.BuildRequestURL()) //nolint:forbidigo
+
+		printRequestDetails(hook)
+	}
+}
+
+// printPlanStep prints a single execution (request or test case) of the plan.
+func printPlanStep(req *loader.APIRequest, testCaseNumber int, name string, outputFile string) {
+	if name != "" {
+		name = fmt.Sprintf(` "%s"`, name)
+	}
+
+	fmt.Printf("   Test case %d%s: %s %s\n", testCaseNumber, name, req.Request.Method, req.BuildRequestURL()) //nolint:forbidigo
+
+	printRequestDetails(req)
+
+	fmt.Printf("     Output:     %s\n", outputFile) //nolint:forbidigo
+}
+
+// printRequestDetails prints the basic auth (redacted), headers, POST body
+// and stream messages of a request.
+func printRequestDetails(req *loader.APIRequest) {
+	if req.Request.BasicAuth != "" {
+		user, _, _ := strings.Cut(req.Request.BasicAuth, ":")
+		fmt.Printf("     Basic auth: %s:%s\n", user, crypto.RedactedSecret) //nolint:forbidigo
+	}
+
+	for _, header := range req.Request.Headers {
+		fmt.Printf("     Header:     %s\n", header) //nolint:forbidigo
+	}
+
+	// The POST body is sent on POST and PUT requests only (see CurlCmdArguments)
+	// and is the request message of gRPC calls.
+	isBodyMethod := req.Request.Method == http.MethodPost || req.Request.Method == http.MethodPut || req.Request.IsGRPC()
+	if req.Request.PostBody != "" && isBodyMethod {
+		fmt.Printf("     Body:       %s\n", req.Request.PostBody) //nolint:forbidigo
+	}
+
+	if stream := req.Request.Stream; stream != nil {
+		fmt.Printf("     Stream:     count %d, duration %dms\n", stream.Count, stream.DurationMs) //nolint:forbidigo
+
+		for _, message := range stream.StreamMessages() {
+			fmt.Printf("     Message:    %s\n", message) //nolint:forbidigo
+		}
+	}
+}
+
+// printScenarioSteps prints the steps of a scenario (placeholders of
+// extracted variables are filled on execution).
+func printScenarioSteps(req *loader.APIRequest) {
+	for idx, step := range req.ScenarioSteps() {
+		kind, number := "Step", idx+1
+		if idx >= len(req.Scenario.Steps) {
+			kind, number = "Cleanup step", idx-len(req.Scenario.Steps)+1
+		}
+
+		fmt.Printf("   %s %d \"%s\": %s %s\n", //nolint:forbidigo
+			kind, number, step.Name, step.Target.Request.Method, step.Target.BuildRequestURL())
+
+		names := make([]string, 0, len(step.Extract))
+		for name := range step.Extract {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		for _, name := range names {
+			fmt.Printf("     Extract %s: %s\n", name, step.Extract[name]) //nolint:forbidigo
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package flags_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/flags"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+// captureStdout returns what the function prints to stdout.
+func captureStdout(t *testing.T, printPlan func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	defer func() { os.Stdout = stdout }()
+
+	printPlan()
+
+	writer.Close()
+
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read stdout: %v", err)
+	}
+
+	return string(output)
+}
+
+func TestPrintPlan(t *testing.T) {
+	users := &loader.APIRequest{ //nolint:exhaustruct
+		ID:           "0f1e2d3c4b",
+		JSONFilePath: "users.json",
+		IsActive:     true,
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   "GET",
+			BaseURL:  "https://api.example.com",
+			Endpoint: "/users",
+			Headers:  []string{"Accept: application/json"},
+		},
+	}
+
+	orders := &loader.APIRequest{ //nolint:exhaustruct
+		ID:           "ab12cd34ef",
+		JSONFilePath: "orders.json",
+		IsActive:     true,
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   "POST",
+			BaseURL:  "https://api.example.com",
+			Endpoint: "/orders",
+			PostBody: `{"item":"book"}`,
+		},
+	}
+
+	requests := []*loader.APIRequest{users, orders}
+
+	output := captureStdout(t, func() {
+		flags.PrintPlan(requests, requests, &loader.Hooks{}, "./data/output") //nolint:exhaustruct
+	})
+
+	expected := `Execution plan (dry run, no request is sent and no file is written):
+
+1. 0f1e2d3c4b (users.json)
+   Test case 0: GET https://api.example.com/users
+     Header:     Accept: application/json
+     Output:     data/output/users-test-case-00.json
+
+2. ab12cd34ef (orders.json)
+   Test case 0: POST https://api.example.com/orders
+     Body:       {"item":"book"}
+     Output:     data/output/orders-test-case-00.json
+
+2 requests, 2 executions (including test cases).
+`
+
+	if output != expected {
+		t.Errorf("unexpected plan:\n%s\nexpected:\n%s", output, expected)
+	}
+}
+
+func TestPrintPlanWithPreRequest(t *testing.T) {
+	login := &loader.APIRequest{ //nolint:exhaustruct
+		ID:            "a1b2c3d4e5",
+		JSONFilePath:  "auth.json",
+		IsActive:      true,
+		IsAuthRequest: true,
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   "POST",
+			BaseURL:  "https://api.example.com",
+			Endpoint: "/login",
+			PostBody: `{"user":"probe"}`,
+		},
+	}
+
+	orders := &loader.APIRequest{ //nolint:exhaustruct
+		ID:           "ab12cd34ef",
+		JSONFilePath: "orders.json",
+		IsActive:     true,
+		PreRequestID: login.ID,
+		Request: loader.Request{ //nolint:exhaustruct
+			Method:   "GET",
+			BaseURL:  "https://api.example.com",
+			Endpoint: "/orders",
+			Headers:  []string{"Authorization: Bearer <auth-token>"},
+		},
+	}
+
+	requests := []*loader.APIRequest{login, orders}
+	selected := []*loader.APIRequest{orders}
+
+	output := captureStdout(t, func() {
+		flags.PrintPlan(requests, selected, &loader.Hooks{}, "./data/output") //nolint:exhaustruct
+	})
+
+	expected := `Execution plan (dry run, no request is sent and no file is written):
+
+1. a1b2c3d4e5 (auth.json) [pre-request, auth request]
+   Test case 0: POST https://api.example.com/login
+     Body:       {"user":"probe"}
+     Output:     data/output/auth-test-case-00.json
+
+2. ab12cd34ef (orders.json)
+   Test case 0: GET https://api.example.com/orders
+     Header:     Authorization: Bearer <auth-token>
+     Output:     data/output/orders-test-case-00.json
+
+2 requests, 2 executions (including test cases).
+`
+
+	if output != expected {
+		t.Errorf("unexpected plan:\n%s\nexpected:\n%s", output, expected)
+	}
+}
@@ -0,0 +1,242 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/diff"
+	"github.com/sven-seyfert/apiprobe/internal/fileutil"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// IsSnapshotCommand checks whether one of the snapshot review flags
+// (--pending, --snapshots, --show-diff, --approve, --reject, --rollback)
+// is set, executes it and returns an instruction to exit the program or not.
+// The output files of the snapshots are relative to the output directory.
+func IsSnapshotCommand(cliFlags *CLIFlags, conn *sqlite.Conn, outputDir string) (bool, error) {
+	switch {
+	case *cliFlags.Pending:
+		return true, listSnapshots(conn, outputDir, db.SnapshotPending, "")
+	case *cliFlags.Snapshots != "":
+		return true, listSnapshots(conn, outputDir, "", fileutil.RelativeOutputPath(outputDir, *cliFlags.Snapshots))
+	case *cliFlags.ShowDiff > 0:
+		return true, showSnapshotDiff(conn, outputDir, int64(*cliFlags.ShowDiff))
+	case *cliFlags.Approve > 0:
+		return true, applySnapshot(conn, outputDir, int64(*cliFlags.Approve), db.SnapshotPending)
+	case *cliFlags.Reject > 0:
+		return true, rejectSnapshot(conn, int64(*cliFlags.Reject))
+	case *cliFlags.Rollback > 0:
+		return true, applySnapshot(conn, outputDir, int64(*cliFlags.Rollback), db.SnapshotApproved)
+	default:
+		return false, nil
+	}
+}
+
+// listSnapshots prints the snapshots filtered by state and/or output file.
+// The snapshot matching the current baseline file is marked.
+func listSnapshots(conn *sqlite.Conn, outputDir string, state string, outputFile string) error {
+	snapshots, err := db.SelectSnapshots(conn, state, outputFile)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots found.") //nolint:forbidigo
+
+		return nil
+	}
+
+	const padding = 2
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, padding, ' ', 0)
+
+	fmt.Fprintln(writer, "SNAPSHOT\tCREATED\tSTATE\tID\tTEST CASE\tOUTPUT FILE")
+
+	for _, snapshot := range snapshots {
+		snapshotState := snapshot.State
+		if isBaseline(outputDir, snapshot) {
+			snapshotState += " (baseline)"
+		}
+
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			snapshot.ID, snapshot.CreatedAt, snapshotState, snapshot.RequestID, snapshot.TestCase, snapshot.OutputFile)
+	}
+
+	return writer.Flush()
+}
+
+// showSnapshotDiff prints the unified diff between the current baseline
+// (output file) and the snapshot.
+func showSnapshotDiff(conn *sqlite.Conn, outputDir string, snapshotID int64) error {
+	snapshot, err := selectSnapshot(conn, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	outputFile := fileutil.ResolveOutputPath(outputDir, snapshot.OutputFile)
+
+	baseline, err := os.ReadFile(outputFile)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Errorf(`Failed to read file "%s". Error: %v`, outputFile, err)
+
+		return err
+	}
+
+	unified := diff.Unified(baseline, snapshot.Content, outputFile+" (baseline)",
+		fmt.Sprintf("snapshot %d (%s)", snapshot.ID, snapshot.State))
+
+	if unified == "" {
+		fmt.Println("Snapshot is equal to the baseline.") //nolint:forbidigo
+
+		return nil
+	}
+
+	fmt.Print(unified) //nolint:forbidigo
+
+	return nil
+}
+
+// applySnapshot writes the snapshot content as new baseline (output file)
+// and marks the snapshot as approved. The snapshot must have the expected
+// state: pending for an approval, approved (older version) for a rollback.
+// Other pending snapshots of the same output file are rejected. The states
+// are updated in a savepoint, which is rolled back if writing the baseline
+// fails; the previous baseline is restored if the commit fails.
+func applySnapshot(conn *sqlite.Conn, outputDir string, snapshotID int64, expectedState string) error {
+	snapshot, err := selectSnapshot(conn, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	if snapshot.State != expectedState {
+		logger.Errorf(`Snapshot %d has state "%s", but "%s" is expected.`, snapshotID, snapshot.State, expectedState)
+
+		return errors.New("unexpected snapshot state")
+	}
+
+	outputFile := fileutil.ResolveOutputPath(outputDir, snapshot.OutputFile)
+
+	previous, err := os.ReadFile(outputFile)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Errorf(`Failed to read file "%s". Error: %v`, outputFile, err)
+
+		return err
+	}
+
+	release := sqlitex.Save(conn)
+
+	err = approveSnapshot(conn, snapshot)
+	if err == nil {
+		err = writeBaseline(outputFile, snapshot.Content)
+	}
+
+	isWritten := err == nil
+
+	release(&err)
+
+	if err != nil {
+		if isWritten {
+			restoreBaseline(outputFile, previous)
+		}
+
+		return err
+	}
+
+	fmt.Printf("Snapshot %d is the new baseline of \"%s\".\n", snapshotID, outputFile) //nolint:forbidigo
+
+	return nil
+}
+
+// approveSnapshot rejects the other pending snapshots of the output file and
+// marks the snapshot as approved.
+func approveSnapshot(conn *sqlite.Conn, snapshot *db.Snapshot) error {
+	if err := db.UpdatePendingSnapshotsState(conn, snapshot.OutputFile, db.SnapshotRejected); err != nil {
+		return err
+	}
+
+	return db.UpdateSnapshotState(conn, snapshot.ID, db.SnapshotApproved)
+}
+
+// writeBaseline writes the content to the output file (created if missing).
+func writeBaseline(outputFile string, content []byte) error {
+	if err := fileutil.EnsureFileExists(outputFile); err != nil {
+		return err
+	}
+
+	return fileutil.WriteOutputFile(outputFile, content)
+}
+
+// restoreBaseline restores the previous content of the output file (removes
+// the file if there was no previous content).
+func restoreBaseline(outputFile string, previous []byte) {
+	var err error
+
+	if previous == nil {
+		err = os.Remove(outputFile)
+	} else {
+		err = fileutil.WriteOutputFile(outputFile, previous)
+	}
+
+	if err != nil {
+		logger.Errorf(`Failed to restore the baseline "%s". Error: %v`, outputFile, err)
+	}
+}
+
+// rejectSnapshot marks a pending snapshot as rejected (baseline stays untouched).
+func rejectSnapshot(conn *sqlite.Conn, snapshotID int64) error {
+	snapshot, err := selectSnapshot(conn, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	if snapshot.State != db.SnapshotPending {
+		logger.Errorf(`Snapshot %d has state "%s", only pending snapshots can be rejected.`, snapshotID, snapshot.State)
+
+		return errors.New("unexpected snapshot state")
+	}
+
+	if err = db.UpdateSnapshotState(conn, snapshotID, db.SnapshotRejected); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot %d rejected.\n", snapshotID) //nolint:forbidigo
+
+	return nil
+}
+
+// selectSnapshot loads the snapshot by id and returns an error if it does not exist.
+func selectSnapshot(conn *sqlite.Conn, snapshotID int64) (*db.Snapshot, error) {
+	snapshot, err := db.SelectSnapshot(conn, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot == nil {
+		logger.Errorf("Snapshot %d not found.", snapshotID)
+
+		return nil, errors.New("snapshot not found")
+	}
+
+	return snapshot, nil
+}
+
+// isBaseline checks whether the snapshot hash matches the current
+// content of its output file.
+func isBaseline(outputDir string, snapshot db.Snapshot) bool {
+	content, err := os.ReadFile(fileutil.ResolveOutputPath(outputDir, snapshot.OutputFile))
+	if err != nil {
+		return false
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]) == snapshot.Hash
+}
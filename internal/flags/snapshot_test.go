@@ -0,0 +1,99 @@
+package flags_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/flags"
+)
+
+// insertPendingSnapshot stores a pending snapshot of the output file
+// (relative to the output directory) and returns its id.
+func insertPendingSnapshot(t *testing.T, conn *sqlite.Conn, outputFile string, content string) int64 {
+	t.Helper()
+
+	runID, err := db.InsertRun(conn, "test", "", "")
+	if err != nil {
+		t.Fatalf("insert run: %v", err)
+	}
+
+	execution := &db.Execution{RunID: runID, RequestID: "0f1e2d3c4b", Status: db.StatusChanged} //nolint:exhaustruct
+
+	executionID, err := db.InsertExecution(conn, execution)
+	if err != nil {
+		t.Fatalf("insert execution: %v", err)
+	}
+
+	snapshotID, err := db.InsertSnapshot(conn, executionID, outputFile, "hash", []byte(content), db.SnapshotPending)
+	if err != nil {
+		t.Fatalf("insert snapshot: %v", err)
+	}
+
+	return snapshotID
+}
+
+// approveFlags returns the CLI flags of --approve with the snapshot id.
+func approveFlags(snapshotID int64) *flags.CLIFlags {
+	isPending, snapshots, showDiff, approve := false, "", 0, int(snapshotID)
+
+	return &flags.CLIFlags{Pending: &isPending, Snapshots: &snapshots, ShowDiff: &showDiff, Approve: &approve} //nolint:exhaustruct
+}
+
+func TestApproveSnapshotWritesBaselineInOutputDir(t *testing.T) {
+	conn, err := db.Init(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+
+	defer conn.Close()
+
+	outputDir := t.TempDir()
+	snapshotID := insertPendingSnapshot(t, conn, "users-test-case-00.json", `{"id":1}`)
+
+	if _, err = flags.IsSnapshotCommand(approveFlags(snapshotID), conn, outputDir); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "users-test-case-00.json"))
+	if err != nil || string(content) != `{"id":1}` {
+		t.Fatalf("expected the baseline in the output directory, got %q (%v)", content, err)
+	}
+
+	snapshot, _ := db.SelectSnapshot(conn, snapshotID)
+	if snapshot.State != db.SnapshotApproved {
+		t.Errorf("expected approved snapshot, got %s", snapshot.State)
+	}
+}
+
+func TestApproveSnapshotKeepsStateIfBaselineFails(t *testing.T) {
+	conn, err := db.Init(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+
+	defer conn.Close()
+
+	// The output directory is a file, so the baseline can not be written.
+	outputDir := filepath.Join(t.TempDir(), "output")
+	if err = os.WriteFile(outputDir, nil, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	otherID := insertPendingSnapshot(t, conn, "users-test-case-00.json", `{"id":2}`)
+	snapshotID := insertPendingSnapshot(t, conn, "users-test-case-00.json", `{"id":1}`)
+
+	if _, err = flags.IsSnapshotCommand(approveFlags(snapshotID), conn, outputDir); err == nil {
+		t.Fatal("expected error on writing the baseline")
+	}
+
+	for _, id := range []int64{snapshotID, otherID} {
+		snapshot, _ := db.SelectSnapshot(conn, id)
+		if snapshot.State != db.SnapshotPending {
+			t.Errorf("snapshot %d: expected pending state after rollback, got %s", id, snapshot.State)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Expectation defines the expected outcome of a request or test case.
+type Expectation struct {
+	// Status is the expected HTTP status code, like "404" or 404,
+	// a status class, like "4xx", or a gRPC status, like "NotFound".
+	Status StatusCode `json:"status"`
+}
+
+// StatusCode is an HTTP status code or status class, written as JSON
+// string or number.
+type StatusCode string
+
+// UnmarshalJSON accepts the status code as JSON string or number.
+func (s *StatusCode) UnmarshalJSON(data []byte) error {
+	var number json.Number
+
+	if err := json.Unmarshal(data, &number); err == nil {
+		*s = StatusCode(number.String())
+
+		return nil
+	}
+
+	var value string
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid status %s", data)
+	}
+
+	*s = StatusCode(value)
+
+	return nil
+}
+
+// MatchStatus returns whether the status code matches the expected
+// status (code, class or gRPC status name, like "NotFound" or "NOT_FOUND").
+// No expected status matches all 2xx codes and the gRPC status OK.
+func (e *Expectation) MatchStatus(statusCode string) bool {
+	expected := strings.ToLower(string(e.Status))
+	if expected == "" {
+		if statusCode == GRPCStatusOK {
+			return true
+		}
+
+		expected = "2xx"
+	}
+
+	if strings.EqualFold(strings.ReplaceAll(expected, "_", ""), statusCode) {
+		return true
+	}
+
+	if len(expected) != len(statusCode) {
+		return false
+	}
+
+	for idx := range expected {
+		if expected[idx] != 'x' && expected[idx] != statusCode[idx] {
+			return false
+		}
+	}
+
+	return true
+}
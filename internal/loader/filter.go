@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/sven-seyfert/apiprobe/internal/logger"
+	"github.com/sven-seyfert/apiprobe/internal/selector"
 )
 
 // ExcludeRequestsByID returns a filtered slice of APIRequest, excluding any
@@ -73,94 +74,44 @@ func ExcludeRequestsByTags(requests []*APIRequest, excludeTags string) []*APIReq
 	return filteredRequests
 }
 
-// FilterRequests filters the given slice of APIRequest by the '--id'
-// and '--tags' flags. It returns a slice of matching requests and a
-// boolean flag that is true if no requests matched the filters.
-func FilterRequests(requests []*APIRequest, id string, tags string) ([]*APIRequest, bool) { //nolint:varnamelen
+// FilterRequests filters the given slice of APIRequest by the selection
+// (flags '--id', '--tags' and '--select'). It returns a slice of matching
+// requests and a boolean flag that is true if no requests matched the
+// selection.
+func FilterRequests(requests []*APIRequest, selection *selector.Selection) ([]*APIRequest, bool) {
 	if len(requests) == 0 {
 		logger.Warnf(`No requests found.`)
 
 		return requests, true
 	}
 
-	// Filter requests by ID.
-	if id != "" {
-		if req := filterByID(requests, id); req != nil {
-			return []*APIRequest{req}, false
-		}
-
-		logger.Warnf(`No request with id (hex hash) "%s" found.`, id)
-
-		return requests, true
+	// Fallback (no selection): return all requests.
+	if selection.IsEmpty() {
+		return requests, false
 	}
 
-	// Or filter requests by tags.
-	if tags != "" {
-		tagsList := strings.Split(tags, ",")
-		wantedTags := make([]string, 0, len(tagsList))
-
-		for _, tag := range tagsList {
-			tag = strings.TrimSpace(tag)
-			if tag != "" {
-				wantedTags = append(wantedTags, tag)
-			}
-		}
-
-		filteredRequests := filterByTags(requests, wantedTags)
-		if len(filteredRequests) > 0 {
-			return filteredRequests, false
-		}
-
-		logger.Warnf(`No requests found for tags "%s".`, tags)
-
-		return requests, true
-	}
-
-	// Or use the fallback (return all requests).
-	return requests, false
-}
+	var filteredRequests []*APIRequest
 
-// filterByID searches a slice of APIRequest for the given ID
-// and returns the first matching object.
-func filterByID(requests []*APIRequest, id string) *APIRequest {
 	for _, req := range requests {
-		if req.ID == id {
-			return req
+		if selection.Match(req.ID, req.Tags) {
+			filteredRequests = append(filteredRequests, req)
 		}
 	}
 
-	return nil
-}
-
-// filterByTags returns all APIRequest objects whose tags intersect
-// with the desired tag list.
-func filterByTags(requests []*APIRequest, wantedTags []string) []*APIRequest {
-	// Build a set (map) for O|1 lookup of desired tags.
-	wantedSet := make(map[string]struct{}, len(wantedTags))
-	for _, w := range wantedTags {
-		wantedSet[w] = struct{}{}
-	}
-
-	var filteredRequests []*APIRequest
-
-	// Check each request only once.
-	for _, req := range requests {
-		for _, tag := range req.Tags {
-			if _, ok := wantedSet[tag]; ok {
-				filteredRequests = append(filteredRequests, req)
+	if len(filteredRequests) == 0 {
+		logger.Warnf(`No requests found for %s.`, selection)
 
-				break
-			}
-		}
+		return requests, true
 	}
 
-	return filteredRequests
+	return filteredRequests, false
 }
 
 // MergePreRequests constructs a merged requests list in which, for each
 // filtered request having a PreRequestID, the corresponding loaded request
-// is prepended before the filtered requests. It returns the gathered/merged
-// APIRequest list without duplicates.
+// is prepended before the filtered requests. The pre-requests of requests
+// referenced by scenario steps are prepended as well. It returns the
+// gathered/merged APIRequest list without duplicates.
 func MergePreRequests(loadedRequests []*APIRequest, filteredRequests []*APIRequest) ([]*APIRequest, error) {
 	lookupMap := make(map[string]*APIRequest, len(loadedRequests))
 
@@ -174,12 +125,7 @@ func MergePreRequests(loadedRequests []*APIRequest, filteredRequests []*APIReque
 	requestsList := make([]*APIRequest, 0, len(loadedRequests)+len(filteredRequests))
 
 	// Handle possible pre-requests by PreRequestID.
-	for _, filteredReq := range filteredRequests {
-		preID := filteredReq.PreRequestID
-		if preID == "" {
-			continue
-		}
-
+	for _, preID := range collectPreRequestIDs(filteredRequests, lookupMap) {
 		if !hexPattern.MatchString(preID) {
 			logger.Errorf(`PreRequestID "%s" has invalid format (not the expected ten character hex hash format).`, preID)
 
@@ -202,6 +148,30 @@ func MergePreRequests(loadedRequests []*APIRequest, filteredRequests []*APIReque
 	return removeDuplicates(requestsList), nil
 }
 
+// collectPreRequestIDs returns the PreRequestIDs of the requests and of the
+// requests referenced by their scenario steps (in order).
+func collectPreRequestIDs(requests []*APIRequest, lookupMap map[string]*APIRequest) []string {
+	preIDs := make([]string, 0, len(requests))
+
+	for _, req := range requests {
+		if req.PreRequestID != "" {
+			preIDs = append(preIDs, req.PreRequestID)
+		}
+
+		if !req.IsScenario() {
+			continue
+		}
+
+		for _, step := range req.ScenarioSteps() {
+			if referenced, found := lookupMap[step.RequestID]; found && referenced.PreRequestID != "" {
+				preIDs = append(preIDs, referenced.PreRequestID)
+			}
+		}
+	}
+
+	return preIDs
+}
+
 // removeDuplicates returns a new slice of APIRequest pointers with
 // duplicates removed, keeping only the first occurrence of each
 // request based on its ID.
@@ -0,0 +1,26 @@
+package loader_test
+
+import (
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestMergePreRequestsOfScenarioSteps(t *testing.T) {
+	login := &loader.APIRequest{ID: "a1b2c3d4e5", IsActive: true, IsAuthRequest: true}     //nolint:exhaustruct
+	orders := &loader.APIRequest{ID: "0f1e2d3c4b", IsActive: true, PreRequestID: login.ID} //nolint:exhaustruct
+
+	scenario := &loader.APIRequest{ID: "5ce7a1f0b2", IsActive: true} //nolint:exhaustruct
+	scenario.Scenario = &loader.Scenario{                            //nolint:exhaustruct
+		Steps: []loader.ScenarioStep{{RequestID: orders.ID}}, //nolint:exhaustruct
+	}
+
+	merged, err := loader.MergePreRequests([]*loader.APIRequest{login, orders, scenario}, []*loader.APIRequest{scenario})
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	if len(merged) != 2 || merged[0] != login || merged[1] != scenario {
+		t.Fatalf("expected the pre-request of the step before the scenario, got %v", merged)
+	}
+}
@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GraphQL defines a GraphQL operation, which is sent as JSON POST body
+// (query, variables and operation name) to the endpoint.
+type GraphQL struct {
+	Query string `json:"query"`
+
+	// QueryFile is a .graphql file (relative to the definition file) with the query.
+	QueryFile string `json:"queryFile"`
+
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// graphQLBody is the POST body of a GraphQL operation.
+type graphQLBody struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// IsGraphQL returns whether the request is a GraphQL operation.
+func (r *Request) IsGraphQL() bool {
+	return r.GraphQL != nil
+}
+
+// prepareGraphQL turns the GraphQL operation into the raw POST body of the
+// request (a POST request with JSON content type, if not defined). The
+// query file is read relative to the definition directory.
+func (r *Request) prepareGraphQL(definitionDir string) error {
+	gql := r.GraphQL
+
+	switch {
+	case gql.Query != "" && gql.QueryFile != "":
+		return errors.New(`both "query" and "queryFile" defined`)
+	case hasPostBody(r.PostBodyRaw):
+		return errors.New(`both "graphql" and "postBody" defined`)
+	case gql.QueryFile != "":
+		query, err := os.ReadFile(filepath.Join(definitionDir, gql.QueryFile))
+		if err != nil {
+			return err
+		}
+
+		gql.Query = string(query)
+		gql.QueryFile = ""
+	}
+
+	if strings.TrimSpace(gql.Query) == "" {
+		return errors.New("no GraphQL query")
+	}
+
+	// Keep characters like '<' or '&' in the query readable.
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	body := graphQLBody{Query: gql.Query, Variables: gql.Variables, OperationName: gql.OperationName}
+	if err := encoder.Encode(body); err != nil {
+		return err
+	}
+
+	r.PostBodyRaw = bytes.TrimSpace(buf.Bytes())
+
+	if r.Method == "" {
+		r.Method = http.MethodPost
+	}
+
+	if !hasHeader(r.Headers, "Content-Type") {
+		r.Headers = append(r.Headers, "Content-Type: application/json")
+	}
+
+	return nil
+}
+
+// hasPostBody returns whether the raw POST body is defined (not omitted,
+// null or an empty JSON object).
+func hasPostBody(postBodyRaw json.RawMessage) bool {
+	switch string(bytes.TrimSpace(postBodyRaw)) {
+	case "", "null", "{}":
+		return false
+	default:
+		return true
+	}
+}
+
+// hasHeader returns whether the header (by name, case-insensitive) is set.
+func hasHeader(headers []string, name string) bool {
+	for _, header := range headers {
+		if strings.EqualFold(headerName(header), name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package loader_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestLoadGraphQLRequest(t *testing.T) {
+	dir := t.TempDir()
+
+	definition := `[{"id": "e5f6a7b8c9", "isActive": true, "request": {"url": "https://api.example.com", "endpoint": "/graphql",
+		"graphql": {"queryFile": "user.graphql", "variables": {"id": "{{.id}}"}, "operationName": "User"}}}]`
+	query := "query User($id: ID!) { user(id: $id) { name } }"
+
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte(definition), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "user.graphql"), []byte(query), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := loader.LoadAllRequests(dir)
+	if err != nil || len(requests) != 1 {
+		t.Fatalf("load: %v", err)
+	}
+
+	req := requests[0]
+	if err = req.PreparePostBody(); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	want := `{"query":"query User($id: ID!) { user(id: $id) { name } }","variables":{"id":"{{.id}}"},"operationName":"User"}`
+	if req.Request.PostBody != want {
+		t.Errorf("got POST body %s, want %s", req.Request.PostBody, want)
+	}
+
+	if req.Request.Method != "POST" || len(req.Request.Headers) != 1 || req.Request.Headers[0] != "Content-Type: application/json" {
+		t.Errorf("unexpected method %s or headers %v", req.Request.Method, req.Request.Headers)
+	}
+}
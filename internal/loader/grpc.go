@@ -0,0 +1,90 @@
+package loader
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// MethodGRPC is the method of gRPC requests (e.g. in the report).
+const MethodGRPC = "GRPC"
+
+// GRPCStatusOK is the status of successful gRPC calls.
+const GRPCStatusOK = "OK"
+
+// URL schemes of gRPC requests: plaintext (HTTP/2 without TLS) or TLS.
+const (
+	schemeGRPC  = "grpc://"
+	schemeGRPCS = "grpcs://"
+)
+
+// GRPC defines a unary gRPC call. The request url is the target (like
+// 'grpc://localhost:50051'), the endpoint the full method name (like
+// '/helloworld.Greeter/SayHello') and the POST body the JSON request message.
+// Without proto files, the descriptors are resolved by server reflection.
+type GRPC struct {
+	// ProtoFiles with the service (relative to the import paths).
+	ProtoFiles []string `json:"protoFiles"`
+
+	// ImportPaths of the proto files (absolute or relative to the definition
+	// file, default is the directory of the definition file).
+	ImportPaths []string `json:"importPaths"`
+}
+
+// IsGRPC returns whether the request is a gRPC call.
+func (r *Request) IsGRPC() bool {
+	return r.GRPC != nil
+}
+
+// GRPCTarget returns the address of the gRPC server (host and port) and
+// whether the connection uses TLS.
+func (r *Request) GRPCTarget() (string, bool) {
+	if target, found := strings.CutPrefix(r.BaseURL, schemeGRPCS); found {
+		return strings.TrimSuffix(target, "/"), true
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(r.BaseURL, schemeGRPC), "/"), false
+}
+
+// GRPCMethod returns the full service name and the method name of the
+// gRPC call (endpoint '/<package>.<Service>/<Method>').
+func (r *Request) GRPCMethod() (string, string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(r.Endpoint, "/"), "/")
+
+	return service, method
+}
+
+// prepareGRPC checks the target and method of the gRPC call and resolves the
+// relative import paths against the definition directory (absolute import
+// paths are kept).
+func (r *Request) prepareGRPC(definitionDir string) error {
+	if !strings.HasPrefix(r.BaseURL, schemeGRPC) && !strings.HasPrefix(r.BaseURL, schemeGRPCS) {
+		return errors.New(`url must start with "grpc://" (plaintext) or "grpcs://" (TLS)`)
+	}
+
+	if service, method := r.GRPCMethod(); service == "" || method == "" || strings.Contains(method, "/") {
+		return errors.New(`endpoint must be the full method name, like "/helloworld.Greeter/SayHello"`)
+	}
+
+	if len(r.GRPC.ImportPaths) == 0 {
+		r.GRPC.ImportPaths = []string{definitionDir}
+	} else {
+		importPaths := make([]string, 0, len(r.GRPC.ImportPaths))
+
+		for _, importPath := range r.GRPC.ImportPaths {
+			if !filepath.IsAbs(importPath) {
+				importPath = filepath.Join(definitionDir, importPath)
+			}
+
+			importPaths = append(importPaths, importPath)
+		}
+
+		r.GRPC.ImportPaths = importPaths
+	}
+
+	if r.Method == "" {
+		r.Method = MethodGRPC
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package loader_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestGRPCImportPathsKeepAbsolutePaths(t *testing.T) {
+	dir, protoDir := t.TempDir(), t.TempDir()
+
+	definition := `[{"id": "a7b8c9d0e1", "isActive": true, "request": {"url": "grpc://localhost:50051",
+		"endpoint": "/grpc.health.v1.Health/Check", "grpc": {"importPaths": ["protos", "` + filepath.ToSlash(protoDir) + `"]}}}]`
+
+	if err := os.WriteFile(filepath.Join(dir, "health.json"), []byte(definition), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := loader.LoadAllRequests(dir)
+	if err != nil || len(requests) != 1 {
+		t.Fatalf("load: %v", err)
+	}
+
+	expected := []string{filepath.Join(dir, "protos"), protoDir}
+	if importPaths := requests[0].Request.GRPC.ImportPaths; !slices.Equal(importPaths, expected) {
+		t.Errorf("got import paths %v, want %v", importPaths, expected)
+	}
+}
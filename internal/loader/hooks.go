@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"fmt"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Hook kinds of definitions. Setup and teardown hooks run before and after
+// the requests of their definition file, suite hooks before and after all
+// requests of the run.
+const (
+	HookSetup         = "setup"
+	HookTeardown      = "teardown"
+	HookSuiteSetup    = "suiteSetup"
+	HookSuiteTeardown = "suiteTeardown"
+)
+
+// Hooks are the (active) setup and teardown requests of the definition files
+// (by relative definition file path) and of the suite, in definition order.
+type Hooks struct {
+	Setup         map[string][]*APIRequest
+	Teardown      map[string][]*APIRequest
+	SuiteSetup    []*APIRequest
+	SuiteTeardown []*APIRequest
+}
+
+// IsHook returns whether the definition is a setup or teardown hook.
+func (req *APIRequest) IsHook() bool {
+	return req.Hook != ""
+}
+
+// SplitHooks separates the hooks from the requests. Hooks are no probes:
+// they are neither selected nor executed as requests. Returns an error for
+// unknown hook kinds and hooks which are scenarios, have a pre-request or
+// test cases.
+func SplitHooks(requests []*APIRequest) ([]*APIRequest, *Hooks, error) {
+	hooks := &Hooks{
+		Setup:         make(map[string][]*APIRequest),
+		Teardown:      make(map[string][]*APIRequest),
+		SuiteSetup:    nil,
+		SuiteTeardown: nil,
+	}
+
+	probes := make([]*APIRequest, 0, len(requests))
+
+	for _, req := range requests {
+		if !req.IsHook() {
+			probes = append(probes, req)
+
+			continue
+		}
+
+		if err := validateHook(req); err != nil {
+			logger.Errorf(`Invalid hook "%s" in "%s". Error: %v`, req.ID, req.JSONFilePath, err)
+
+			return nil, nil, err
+		}
+
+		if !req.IsActive {
+			continue
+		}
+
+		switch req.Hook {
+		case HookSetup:
+			hooks.Setup[req.JSONFilePath] = append(hooks.Setup[req.JSONFilePath], req)
+		case HookTeardown:
+			hooks.Teardown[req.JSONFilePath] = append(hooks.Teardown[req.JSONFilePath], req)
+		case HookSuiteSetup:
+			hooks.SuiteSetup = append(hooks.SuiteSetup, req)
+		case HookSuiteTeardown:
+			hooks.SuiteTeardown = append(hooks.SuiteTeardown, req)
+		}
+	}
+
+	return probes, hooks, nil
+}
+
+// validateHook checks the hook kind and that the hook is a plain request.
+func validateHook(req *APIRequest) error {
+	switch req.Hook {
+	case HookSetup, HookTeardown, HookSuiteSetup, HookSuiteTeardown:
+	default:
+		return fmt.Errorf(`unknown hook "%s" (%s, %s, %s or %s)`,
+			req.Hook, HookSetup, HookTeardown, HookSuiteSetup, HookSuiteTeardown)
+	}
+
+	if req.IsScenario() || req.PreRequestID != "" || len(req.TestCases) > 0 || req.IsDataDriven() {
+		return fmt.Errorf(`hook "%s" must be a request without scenario, pre-request and test cases`, req.Hook)
+	}
+
+	return nil
+}
+
+// All returns all hooks (e.g. to prepare them like the requests).
+func (h *Hooks) All() []*APIRequest {
+	all := make([]*APIRequest, 0, len(h.SuiteSetup)+len(h.SuiteTeardown))
+	all = append(all, h.SuiteSetup...)
+	all = append(all, h.SuiteTeardown...)
+
+	for _, setup := range h.Setup {
+		all = append(all, setup...)
+	}
+
+	for _, teardown := range h.Teardown {
+		all = append(all, teardown...)
+	}
+
+	return all
+}
@@ -3,6 +3,7 @@ package loader
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,17 +17,28 @@ import (
 // APIRequest represents the structure of each API request definition
 // as specified in the input JSON configuration.
 type APIRequest struct {
-	ID            string      `json:"id"`
-	IsActive      bool        `json:"isActive"`
-	IsAuthRequest bool        `json:"isAuthRequest"`
-	PreRequestID  string      `json:"preRequestId"`
-	Request       Request     `json:"request"`
-	TestCases     []TestCases `json:"testCases"`
-	Tags          []string    `json:"tags"`
-	JqCommand     string      `json:"jq"`
-
-	// Relative JSON file path.
+	ID            string             `json:"id"`
+	IsActive      bool               `json:"isActive"`
+	IsAuthRequest bool               `json:"isAuthRequest"`
+	PreRequestID  string             `json:"preRequestId"`
+	Request       Request            `json:"request"`
+	TestCases     []TestCases        `json:"testCases"`
+	TestData      *TestData          `json:"testData"`
+	Tags          []string           `json:"tags"`
+	JqCommand     string             `json:"jq"`
+	XPath         string             `json:"xpath"`
+	XMLToJSON     bool               `json:"xmlToJson"`
+	MaxDurationMs DurationThresholds `json:"maxDurationMs"`
+	Expect        *Expectation       `json:"expect"`
+	Scenario      *Scenario          `json:"scenario"`
+	Hook          string             `json:"hook"`
+
+	// Relative definition (JSON or YAML) file path.
 	JSONFilePath string `json:"-"`
+
+	// secret looks up the secrets of the template function 'secret' (set by
+	// PrepareTemplates, also used by the templates executed per test case).
+	secret SecretFunc
 }
 
 // Request holds the HTTP-specific details for an API request.
@@ -41,26 +53,58 @@ type Request struct {
 	PostBodyRaw json.RawMessage `json:"postBody"`
 	Name        string          `json:"name"`
 
+	// GraphQL operation (instead of the POST body).
+	GraphQL *GraphQL `json:"graphql"`
+
+	// GRPC marks the request as gRPC call (the POST body is the request message).
+	GRPC *GRPC `json:"grpc"`
+
+	// Stream marks the request as WebSocket or Server-Sent Events stream.
+	Stream *Stream `json:"stream"`
+
 	// Target data type for the POST body format is string.
 	PostBody string `json:"-"`
 }
 
+// DurationThresholds defines the latency SLO (in milliseconds) of a request.
+// Exceeding Degraded marks the request as degraded, exceeding Failed marks it
+// as failed. A value of 0 disables the respective threshold.
+type DurationThresholds struct {
+	Degraded int64 `json:"degraded"`
+	Failed   int64 `json:"failed"`
+}
+
 // TestCases defines the input variations for the requests.
 type TestCases struct {
 	Name            string          `json:"name"`
-	ParamsData      string          `json:"paramsData"`
+	ParamsData      ParamsData      `json:"paramsData"`
 	PostBodyDataRaw json.RawMessage `json:"postBodyData"`
 
+	// Key names the output file of the test case (instead of the index).
+	Key string `json:"key"`
+
+	// Data fills the placeholders of the request (like a data file row).
+	Data map[string]string `json:"data"`
+
+	// Request overrides or removes parts of the base request.
+	Request *RequestOverride `json:"request"`
+
+	// Expect is the expected outcome, instead of the one of the request
+	// (default: a 2xx status code).
+	Expect *Expectation `json:"expect"`
+
 	// Target data type for the POST body format is string.
 	PostBodyData string `json:"-"`
 }
 
-// PreparePostBody prepares the request body (empty, x-www-form-urlencoded
-// or compacted JSON). Returns nil on success or an error if JSON compaction fails.
+// PreparePostBody prepares the request body (empty, x-www-form-urlencoded,
+// compacted JSON or the text of a JSON string, like a SOAP envelope).
+// Returns nil on success or an error if JSON compaction fails.
 func (req *APIRequest) PreparePostBody() error {
 	const emptyPostBodyLength = 2
 
-	if len(string(req.Request.PostBodyRaw)) == emptyPostBodyLength {
+	// The POST body is empty or omitted (e.g. in YAML definitions).
+	if len(string(req.Request.PostBodyRaw)) <= emptyPostBodyLength {
 		req.Request.PostBody = ""
 
 		return nil
@@ -75,6 +119,13 @@ func (req *APIRequest) PreparePostBody() error {
 
 	req.Request.PostBody = buf.String()
 
+	// Case POST body is text (like XML).
+	if text, isText := textPostBody(buf.Bytes()); isText {
+		req.Request.PostBody = text
+
+		return nil
+	}
+
 	// Case POST body is JSON.
 	if !util.ContainsSubstring(req.Request.Headers, "x-www-form-urlencoded") {
 		return nil
@@ -101,7 +152,7 @@ func (req *APIRequest) PreparePostBodyData() error {
 
 		const emptyPostBodyDataLength = 2
 
-		if len(string(testCase.PostBodyDataRaw)) == emptyPostBodyDataLength {
+		if len(string(testCase.PostBodyDataRaw)) <= emptyPostBodyDataLength {
 			testCase.PostBodyData = ""
 
 			continue
@@ -116,6 +167,13 @@ func (req *APIRequest) PreparePostBodyData() error {
 
 		testCase.PostBodyData = buf.String()
 
+		// Case POST body is text (like XML).
+		if text, isText := textPostBody(buf.Bytes()); isText {
+			testCase.PostBodyData = text
+
+			continue
+		}
+
 		// Case POST body is JSON.
 		if !util.ContainsSubstring(req.Request.Headers, "x-www-form-urlencoded") {
 			continue
@@ -133,6 +191,18 @@ func (req *APIRequest) PreparePostBodyData() error {
 	return nil
 }
 
+// textPostBody returns the text of a POST body which is a JSON string (like
+// an XML document), which is sent as it is.
+func textPostBody(postBody []byte) (string, bool) {
+	var text string
+
+	if err := json.Unmarshal(postBody, &text); err != nil {
+		return "", false
+	}
+
+	return text, true
+}
+
 // transformToFormURL converts a JSON string representing a flat map[string]string
 // into a URL-encoded form string and returns the decoded form. An error is
 // returned if JSON unmarshalling or URL query unescape fails.
@@ -162,7 +232,8 @@ func transformToFormURL(jsonStr string) (string, error) {
 }
 
 // BuildRequestURL constructs the full request URL by concatenating the BaseURL,
-// Endpoint, and optional query parameters defined in the APIRequest.
+// Endpoint, and optional query parameters (with encoded keys and values)
+// defined in the APIRequest.
 func (req *APIRequest) BuildRequestURL() string {
 	var requestURL strings.Builder
 
@@ -171,12 +242,16 @@ func (req *APIRequest) BuildRequestURL() string {
 
 	if len(req.Request.Params) > 0 {
 		requestURL.WriteString("?")
-		requestURL.WriteString(url.PathEscape(strings.Join(req.Request.Params, "&")))
+		requestURL.WriteString(encodeQuery(req.Request.Params))
 	}
 
 	return requestURL.String()
 }
 
+// CurlWriteOutMarker separates the response body from the appended curl
+// write-out data (timings and HTTP status code).
+const CurlWriteOutMarker = "<apiprobe-write-out>"
+
 // CurlCmdArguments builds the command-line arguments for a curl invocation
 // based on the HTTP method, URL, headers, authentication and payload
 // specified in the APIRequest.
@@ -187,7 +262,8 @@ func (req *APIRequest) CurlCmdArguments() []string {
 		"--connect-timeout", "8",
 		"--max-time", "24",
 		"--url", req.BuildRequestURL(),
-		"--write-out", "%{http_code}",
+		"--write-out", CurlWriteOutMarker +
+			"%{time_namelookup} %{time_connect} %{time_appconnect} %{time_starttransfer} %{time_total} %{http_code}",
 	}
 
 	if req.Request.Method == http.MethodGet {
@@ -218,11 +294,9 @@ func (req *APIRequest) CurlCmdArguments() []string {
 	return cmdArgs
 }
 
-// LoadAllRequests recursively walks the input directory, parses all JSON files
-// and returns APIRequest pointers.
-func LoadAllRequests() ([]*APIRequest, error) {
-	const inputDir = "./data/input"
-
+// LoadAllRequests recursively walks the input directory, parses all JSON and
+// YAML definition files and returns APIRequest pointers.
+func LoadAllRequests(inputDir string) ([]*APIRequest, error) {
 	var requests []*APIRequest
 
 	err := filepath.Walk(inputDir, func(path string, _ os.FileInfo, err error) error {
@@ -232,7 +306,7 @@ func LoadAllRequests() ([]*APIRequest, error) {
 			return err
 		}
 
-		if filepath.Ext(path) == ".json" {
+		if IsDefinitionFile(path) {
 			fileRequest, loadErr := loadRequestFromFile(path, inputDir)
 			if loadErr != nil {
 				return loadErr
@@ -247,8 +321,43 @@ func LoadAllRequests() ([]*APIRequest, error) {
 	return requests, err
 }
 
-// loadRequestFromFile reads a JSON file, unmarshals it into APIRequest structs
-// and assigns the file path.
+// prepareRequestTypes prepares the GraphQL operations, gRPC calls and streams
+// of the request and of the inline scenario steps.
+func (req *APIRequest) prepareRequestTypes(definitionDir string) error {
+	requests := []*Request{&req.Request}
+
+	for _, step := range req.ScenarioSteps() {
+		if step.Request != nil {
+			requests = append(requests, step.Request)
+		}
+	}
+
+	for _, request := range requests {
+		var err error
+
+		switch {
+		case request.IsGraphQL() && request.IsGRPC() || request.IsStream() && (request.IsGraphQL() || request.IsGRPC()):
+			err = errors.New(`only one of "graphql", "grpc" and "stream" can be defined`)
+		case request.IsGraphQL():
+			err = request.prepareGraphQL(definitionDir)
+		case request.IsGRPC():
+			err = request.prepareGRPC(definitionDir)
+		case request.IsStream():
+			err = request.prepareStream()
+		}
+
+		if err != nil {
+			logger.Errorf(`Invalid request "%s". Error: %v`, req.ID, err)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadRequestFromFile reads a JSON or YAML file, unmarshals it into APIRequest
+// structs and assigns the file path.
 func loadRequestFromFile(path string, inputDir string) ([]*APIRequest, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
@@ -257,6 +366,14 @@ func loadRequestFromFile(path string, inputDir string) ([]*APIRequest, error) {
 		return nil, err
 	}
 
+	if IsYAMLFile(path) {
+		if bytes, err = YAMLToJSON(bytes); err != nil {
+			logger.Errorf(`Failed to convert YAML "%s". Error: %v`, path, err)
+
+			return nil, err
+		}
+	}
+
 	var requestData []APIRequest
 
 	if err = json.Unmarshal(bytes, &requestData); err != nil {
@@ -265,7 +382,7 @@ func loadRequestFromFile(path string, inputDir string) ([]*APIRequest, error) {
 		return nil, err
 	}
 
-	// Store JSON file path in each request (relative to ./data/input).
+	// Store JSON file path in each request (relative to the input directory).
 	relPath, err := filepath.Rel(inputDir, path)
 	if err != nil {
 		logger.Errorf(`Failed to get relative path "%s". Error: %v`, path, err)
@@ -278,6 +395,22 @@ func loadRequestFromFile(path string, inputDir string) ([]*APIRequest, error) {
 	for idx := range requestData {
 		requestData[idx].JSONFilePath = relPath
 		request[idx] = &requestData[idx]
+
+		if err = requestData[idx].prepareRequestTypes(filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+
+		if requestData[idx].IsDataDriven() {
+			if err = requestData[idx].loadTestData(filepath.Dir(path)); err != nil {
+				return nil, err
+			}
+		}
+
+		if err = requestData[idx].checkTestCaseKeys(); err != nil {
+			logger.Errorf(`Invalid test cases of request "%s". Error: %v`, requestData[idx].ID, err)
+
+			return nil, err
+		}
 	}
 
 	return request, nil
@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"slices"
+	"strings"
+)
+
+// RequestOverride defines the parts of the base request which a test case
+// overrides or removes (like a missing auth header, a wrong content type,
+// an alternative endpoint or a different method). Empty fields are
+// inherited from the base request.
+type RequestOverride struct {
+	Method    string `json:"method"`
+	BaseURL   string `json:"url"`
+	Endpoint  string `json:"endpoint"`
+	BasicAuth string `json:"basicAuth"`
+
+	// Headers replace the base headers of the same name or are added.
+	Headers []string `json:"headers"`
+
+	// RemoveHeaders are the names of base headers to remove.
+	RemoveHeaders []string `json:"removeHeaders"`
+
+	RemoveBasicAuth bool `json:"removeBasicAuth"`
+	RemovePostBody  bool `json:"removePostBody"`
+}
+
+// Apply returns a copy of the base request with the overrides and removals
+// applied. Headers are removed before the override headers are set.
+func (o *RequestOverride) Apply(base Request) Request {
+	request := base
+
+	if o.Method != "" {
+		request.Method = o.Method
+	}
+
+	if o.BaseURL != "" {
+		request.BaseURL = o.BaseURL
+	}
+
+	if o.Endpoint != "" {
+		request.Endpoint = o.Endpoint
+	}
+
+	if o.BasicAuth != "" {
+		request.BasicAuth = o.BasicAuth
+	}
+
+	if o.RemoveBasicAuth {
+		request.BasicAuth = ""
+	}
+
+	if o.RemovePostBody {
+		request.PostBody = ""
+	}
+
+	request.Headers = slices.DeleteFunc(slices.Clone(base.Headers), func(header string) bool {
+		return slices.ContainsFunc(o.RemoveHeaders, func(name string) bool {
+			return strings.EqualFold(headerName(header), strings.TrimSpace(name))
+		})
+	})
+
+	for _, header := range o.Headers {
+		index := slices.IndexFunc(request.Headers, func(baseHeader string) bool {
+			return strings.EqualFold(headerName(baseHeader), headerName(header))
+		})
+
+		if index < 0 {
+			request.Headers = append(request.Headers, header)
+
+			continue
+		}
+
+		request.Headers[index] = header
+	}
+
+	return request
+}
+
+// headerName returns the name of the header ('name: value').
+func headerName(header string) string {
+	name, _, _ := strings.Cut(header, ":")
+
+	return strings.TrimSpace(name)
+}
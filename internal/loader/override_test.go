@@ -0,0 +1,42 @@
+package loader_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestRequestOverrideApply(t *testing.T) {
+	base := loader.Request{
+		Method:    "POST",
+		Endpoint:  "/api/users",
+		BasicAuth: "user:pass",
+		Headers:   []string{"Authorization: Bearer token", "Content-Type: application/json", "Accept: */*"},
+		PostBody:  `{"name":"morpheus"}`,
+	}
+
+	override := &loader.RequestOverride{
+		Method:          "PUT",
+		Endpoint:        "/api/users/2",
+		Headers:         []string{"content-type: text/plain", "X-Trace: 1"},
+		RemoveHeaders:   []string{"authorization"},
+		RemoveBasicAuth: true,
+		RemovePostBody:  true,
+	}
+
+	request := override.Apply(base)
+
+	if request.Method != "PUT" || request.Endpoint != "/api/users/2" || request.BasicAuth != "" || request.PostBody != "" {
+		t.Errorf("unexpected request %+v", request)
+	}
+
+	want := []string{"content-type: text/plain", "Accept: */*", "X-Trace: 1"}
+	if !reflect.DeepEqual(request.Headers, want) {
+		t.Errorf("got headers %v, want %v", request.Headers, want)
+	}
+
+	if len(base.Headers) != 3 || base.Headers[0] != "Authorization: Bearer token" {
+		t.Errorf("base headers changed: %v", base.Headers)
+	}
+}
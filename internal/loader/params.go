@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ParamsData defines the query param changes of a test case. A JSON string
+// ('key=value') is a single set, like in former definitions.
+type ParamsData struct {
+	// Set replaces all params of the same key (or is added).
+	Set []string `json:"set"`
+
+	// Add adds params, also repeated keys (like 'id=1' and 'id=2').
+	Add []string `json:"add"`
+
+	// Remove are the keys of params to remove.
+	Remove []string `json:"remove"`
+}
+
+// UnmarshalJSON accepts the params data as object or 'key=value' string.
+func (p *ParamsData) UnmarshalJSON(data []byte) error {
+	var param string
+
+	if err := json.Unmarshal(data, &param); err == nil {
+		*p = ParamsData{} //nolint:exhaustruct
+
+		if param != "" {
+			p.Set = []string{param}
+		}
+
+		return nil
+	}
+
+	type paramsData ParamsData
+
+	var object paramsData
+
+	if err := json.Unmarshal(data, &object); err != nil {
+		return errors.Join(errors.New(`"paramsData" must be an object with set, add and remove lists`), err)
+	}
+
+	*p = ParamsData(object)
+
+	return nil
+}
+
+// IsEmpty returns whether the params data changes nothing.
+func (p *ParamsData) IsEmpty() bool {
+	return len(p.Set) == 0 && len(p.Add) == 0 && len(p.Remove) == 0
+}
+
+// Apply returns a copy of the params with the keys removed, the set params
+// replaced (or added) and the add params added.
+func (p *ParamsData) Apply(params []string) []string {
+	result := slices.DeleteFunc(slices.Clone(params), func(param string) bool {
+		return slices.Contains(p.Remove, paramKey(param))
+	})
+
+	for _, param := range p.Set {
+		key := paramKey(param)
+		index := slices.IndexFunc(result, func(existing string) bool {
+			return paramKey(existing) == key
+		})
+
+		if index < 0 {
+			result = append(result, param)
+
+			continue
+		}
+
+		result[index] = param
+		result = slices.Concat(result[:index+1], slices.DeleteFunc(result[index+1:], func(existing string) bool {
+			return paramKey(existing) == key
+		}))
+	}
+
+	return append(result, p.Add...)
+}
+
+// paramKey returns the key of the param ('key=value').
+func paramKey(param string) string {
+	key, _, _ := strings.Cut(param, "=")
+
+	return key
+}
+
+// encodeQuery joins the params ('key=value') to a query string with
+// encoded keys and values.
+func encodeQuery(params []string) string {
+	encoded := make([]string, len(params))
+
+	for idx, param := range params {
+		key, value, hasValue := strings.Cut(param, "=")
+
+		encoded[idx] = url.QueryEscape(key)
+		if hasValue {
+			encoded[idx] += "=" + url.QueryEscape(value)
+		}
+	}
+
+	return strings.Join(encoded, "&")
+}
@@ -0,0 +1,57 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestParamsDataApply(t *testing.T) {
+	params := []string{"page=2", "id=1", "sort=name", "id=2"}
+
+	paramsData := &loader.ParamsData{
+		Set:    []string{"id=7", "limit=10"},
+		Add:    []string{"tag=a", "tag=b"},
+		Remove: []string{"sort"},
+	}
+
+	want := []string{"page=2", "id=7", "limit=10", "tag=a", "tag=b"}
+	if got := paramsData.Apply(params); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if params[1] != "id=1" || len(params) != 4 {
+		t.Errorf("params changed: %v", params)
+	}
+}
+
+func TestParamsDataUnmarshal(t *testing.T) {
+	var testCases []loader.TestCases
+
+	data := `[{"paramsData": "page=33"}, {"paramsData": ""}, {"paramsData": {"remove": ["page"]}}]`
+	if err := json.Unmarshal([]byte(data), &testCases); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(testCases[0].ParamsData.Set, []string{"page=33"}) {
+		t.Errorf("unexpected params data %+v", testCases[0].ParamsData)
+	}
+
+	if !testCases[1].ParamsData.IsEmpty() || testCases[2].ParamsData.Remove[0] != "page" {
+		t.Errorf("unexpected params data %+v, %+v", testCases[1].ParamsData, testCases[2].ParamsData)
+	}
+}
+
+func TestBuildRequestURLEncodesParams(t *testing.T) {
+	req := &loader.APIRequest{}
+	req.Request.BaseURL = "https://example.com"
+	req.Request.Endpoint = "/search"
+	req.Request.Params = []string{"q=a b+c&d", "id=1", "id=2", "flag"}
+
+	want := "https://example.com/search?q=a+b%2Bc%26d&id=1&id=2&flag"
+	if got := req.BuildRequestURL(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
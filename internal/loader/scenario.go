@@ -0,0 +1,154 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Scenario is an ordered multi-step flow (like login, create cart, add item,
+// checkout and verify order) with shared variables and one verdict.
+type Scenario struct {
+	Steps []ScenarioStep `json:"steps"`
+
+	// Cleanup steps run at the end, also if a step failed.
+	Cleanup []ScenarioStep `json:"cleanup"`
+}
+
+// ScenarioStep is a single step of a scenario: a reference to an existing
+// request (by ID) or an inline request. The extracted variables fill the
+// placeholders (like '{{.orderId}}') of the following steps.
+type ScenarioStep struct {
+	Name      string   `json:"name"`
+	RequestID string   `json:"requestId"`
+	Request   *Request `json:"request"`
+
+	// Extract maps variable names to jq filters on the response body.
+	Extract map[string]string `json:"extract"`
+
+	// Expect is the expected outcome, instead of the one of the request.
+	Expect *Expectation `json:"expect"`
+
+	// ContinueOnFailure runs the following steps even if this step failed.
+	ContinueOnFailure bool `json:"continueOnFailure"`
+
+	// Target is the resolved request of the step.
+	Target *APIRequest `json:"-"`
+}
+
+// IsScenario returns whether the definition is a scenario.
+func (req *APIRequest) IsScenario() bool {
+	return req.Scenario != nil
+}
+
+// ScenarioSteps returns all steps (including the cleanup steps) of the
+// scenario, nil for requests.
+func (req *APIRequest) ScenarioSteps() []*ScenarioStep {
+	if !req.IsScenario() {
+		return nil
+	}
+
+	steps := make([]*ScenarioStep, 0, len(req.Scenario.Steps)+len(req.Scenario.Cleanup))
+
+	for idx := range req.Scenario.Steps {
+		steps = append(steps, &req.Scenario.Steps[idx])
+	}
+
+	for idx := range req.Scenario.Cleanup {
+		steps = append(steps, &req.Scenario.Cleanup[idx])
+	}
+
+	return steps
+}
+
+// ResolveScenarios resolves the steps of the scenarios within the requests
+// into the target requests: a copy of the referenced (loaded) request or the
+// inline request. Returns an error for scenarios without steps, steps with
+// neither or both of request ID and inline request and unknown request IDs.
+func ResolveScenarios(loadedRequests []*APIRequest, requests []*APIRequest) error {
+	lookupMap := make(map[string]*APIRequest, len(loadedRequests))
+
+	for _, loadedReq := range loadedRequests {
+		lookupMap[loadedReq.ID] = loadedReq
+	}
+
+	for _, req := range requests {
+		if !req.IsScenario() {
+			continue
+		}
+
+		if len(req.Scenario.Steps) == 0 {
+			logger.Errorf(`Scenario "%s" has no steps.`, req.ID)
+
+			return errors.New("scenario without steps")
+		}
+
+		for idx, step := range req.ScenarioSteps() {
+			target, err := resolveScenarioStep(req, step, lookupMap)
+			if err != nil {
+				logger.Errorf(`Invalid step %d of scenario "%s". Error: %v`, idx+1, req.ID, err)
+
+				return err
+			}
+
+			step.Target = target
+		}
+	}
+
+	return nil
+}
+
+// resolveScenarioStep returns the target request of the step. Inline
+// requests inherit the ID, tags and file path of the scenario.
+func resolveScenarioStep(scenario *APIRequest, step *ScenarioStep, lookupMap map[string]*APIRequest) (*APIRequest, error) {
+	switch {
+	case step.RequestID != "" && step.Request != nil:
+		return nil, errors.New(`both "requestId" and "request" defined`)
+	case step.Request != nil:
+		return &APIRequest{ //nolint:exhaustruct
+			ID:           scenario.ID,
+			IsActive:     true,
+			Request:      *step.Request,
+			Tags:         scenario.Tags,
+			JSONFilePath: scenario.JSONFilePath,
+		}, nil
+	case step.RequestID == "":
+		return nil, errors.New(`neither "requestId" nor "request" defined`)
+	}
+
+	referenced, found := lookupMap[step.RequestID]
+	if !found {
+		return nil, fmt.Errorf(`request ID "%s" not found`, step.RequestID)
+	}
+
+	if referenced.IsScenario() {
+		return nil, fmt.Errorf(`request ID "%s" is a scenario`, step.RequestID)
+	}
+
+	// Copy with own params and headers (placeholders are replaced in-place).
+	target := *referenced
+	target.TestCases = nil
+	target.Request.Params = slices.Clone(referenced.Request.Params)
+	target.Request.Headers = slices.Clone(referenced.Request.Headers)
+
+	return &target, nil
+}
+
+// PrepareScenarioSteps prepares the POST bodies of the scenario step
+// requests. The templates (placeholders of extracted variables) are executed
+// per step on execution.
+func (req *APIRequest) PrepareScenarioSteps() error {
+	for _, step := range req.ScenarioSteps() {
+		if step.Target == nil {
+			continue
+		}
+
+		if err := step.Target.PreparePostBody(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MethodWebSocket is the method of WebSocket streams (e.g. in the report).
+const MethodWebSocket = "WEBSOCKET"
+
+// Stream defines a streaming request: a WebSocket connection (url with
+// scheme 'ws://' or 'wss://') or Server-Sent Events (url with scheme
+// 'http://' or 'https://'). The received messages are collected for the
+// duration or until the count is reached.
+type Stream struct {
+	// Messages are sent after the WebSocket connection is established
+	// (JSON strings as text, other JSON values compacted).
+	Messages []json.RawMessage `json:"messages"`
+
+	// Count of messages to collect. Less messages within the duration
+	// (default is the request timeout) are a request error.
+	Count int `json:"count"`
+
+	// DurationMs is the time window (in milliseconds) of the collection.
+	DurationMs int64 `json:"durationMs"`
+
+	// IsWebSocket is set on preparation (by the url scheme).
+	IsWebSocket bool `json:"-"`
+}
+
+// IsStream returns whether the request is a streaming request.
+func (r *Request) IsStream() bool {
+	return r.Stream != nil
+}
+
+// prepareStream checks the url, count and duration of the streaming request
+// and compacts the messages.
+func (r *Request) prepareStream() error {
+	stream := r.Stream
+
+	switch {
+	case strings.HasPrefix(r.BaseURL, "ws://") || strings.HasPrefix(r.BaseURL, "wss://"):
+		stream.IsWebSocket = true
+	case strings.HasPrefix(r.BaseURL, "http://") || strings.HasPrefix(r.BaseURL, "https://"):
+		if len(stream.Messages) > 0 {
+			return errors.New("messages can only be sent on WebSocket streams")
+		}
+	default:
+		return errors.New(`url must start with "ws://" or "wss://" (WebSocket), "http://" or "https://" (SSE)`)
+	}
+
+	if stream.Count < 0 || stream.DurationMs < 0 {
+		return errors.New("negative count or duration")
+	}
+
+	if stream.Count == 0 && stream.DurationMs == 0 {
+		return errors.New(`neither "count" nor "durationMs" defined`)
+	}
+
+	for idx, message := range stream.Messages {
+		var buf bytes.Buffer
+
+		if err := json.Compact(&buf, message); err != nil {
+			return err
+		}
+
+		stream.Messages[idx] = buf.Bytes()
+	}
+
+	if r.Method == "" {
+		r.Method = http.MethodGet
+		if stream.IsWebSocket {
+			r.Method = MethodWebSocket
+		}
+	}
+
+	return nil
+}
+
+// StreamMessages returns the messages to send: JSON strings unquoted, other
+// JSON values as they are.
+func (s *Stream) StreamMessages() []string {
+	messages := make([]string, 0, len(s.Messages))
+
+	for _, message := range s.Messages {
+		var text string
+
+		if err := json.Unmarshal(message, &text); err != nil {
+			text = string(message)
+		}
+
+		messages = append(messages, text)
+	}
+
+	return messages
+}
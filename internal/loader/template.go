@@ -0,0 +1,396 @@
+package loader
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SecretFunc returns the secret of a '<secret-…>' placeholder for the
+// template function 'secret' (like '{{base64 (secret "<secret-…>")}}').
+type SecretFunc func(placeholder string) (string, error)
+
+// PrepareTemplates executes the templates (template functions like
+// '{{uuid}}' or '{{now "2006-01-02" "-7d"}}') in all string fields of the
+// request and its test cases. The request of a data file and the params,
+// POST body and request overrides of test cases with data are executed per
+// test case together with the placeholder values (see WithData). Inactive
+// requests (like requests only referenced by scenario steps) are not executed
+// and keep their templates. The templates run before the secret placeholders
+// are replaced, secrets are looked up by the function 'secret' only. Returns
+// an error on invalid templates, unknown functions or placeholders without
+// value.
+func (req *APIRequest) PrepareTemplates(secret SecretFunc) error {
+	req.secret = secret
+
+	for _, step := range req.ScenarioSteps() {
+		if step.Target != nil {
+			step.Target.secret = secret
+		}
+	}
+
+	if !req.IsActive {
+		return nil
+	}
+
+	if !req.IsDataDriven() {
+		if err := req.Request.executeTemplates(map[string]string{}, map[string]string{}, secret); err != nil {
+			return err
+		}
+	}
+
+	for idx := range req.TestCases {
+		testCase := &req.TestCases[idx]
+
+		fields := []templateField{
+			{"name", &testCase.Name},
+			{"key", &testCase.Key},
+		}
+
+		if testCase.Data == nil {
+			fields = append(fields, testCase.ParamsData.templateFields()...)
+			fields = append(fields, templateField{"postBodyData", &testCase.PostBodyData})
+
+			if testCase.Request != nil {
+				fields = append(fields, testCase.Request.templateFields()...)
+			}
+		}
+
+		for column, value := range testCase.Data {
+			filled, err := executeTemplate(value, map[string]string{}, secret)
+			if err != nil {
+				return fmt.Errorf(`test case %d, data "%s": %w`, idx+1, column, err)
+			}
+
+			testCase.Data[column] = filled
+		}
+
+		if err := executeTemplateFields(fields, map[string]string{}, secret); err != nil {
+			return fmt.Errorf("test case %d, %w", idx+1, err)
+		}
+	}
+
+	// The executed templates may change the keys.
+	return req.checkTestCaseKeys()
+}
+
+// templateField is a named string field which may contain a template.
+type templateField struct {
+	name  string
+	value *string
+}
+
+// executeTemplates executes the templates of all string fields of the
+// request in-place with the data as placeholder values. A JSON POST body and
+// the stream messages are executed with the jsonData instead.
+func (r *Request) executeTemplates(data map[string]string, jsonData map[string]string, secret SecretFunc) error {
+	fields := []templateField{
+		{"description", &r.Description},
+		{"method", &r.Method},
+		{"url", &r.BaseURL},
+		{"endpoint", &r.Endpoint},
+		{"basicAuth", &r.BasicAuth},
+		{"name", &r.Name},
+	}
+
+	for idx := range r.Headers {
+		fields = append(fields, templateField{"headers", &r.Headers[idx]})
+	}
+
+	for idx := range r.Params {
+		fields = append(fields, templateField{"params", &r.Params[idx]})
+	}
+
+	if err := executeTemplateFields(fields, data, secret); err != nil {
+		return err
+	}
+
+	// Text and form URL encoded bodies are no JSON objects or arrays.
+	bodyData := data
+	if body := strings.TrimSpace(r.PostBody); strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[") {
+		bodyData = jsonData
+	}
+
+	if err := executeTemplateFields([]templateField{{"postBody", &r.PostBody}}, bodyData, secret); err != nil {
+		return err
+	}
+
+	if r.Stream == nil {
+		return nil
+	}
+
+	// The messages of streams are executed as raw JSON (like the POST body).
+	messages := make([]string, len(r.Stream.Messages))
+	fields = make([]templateField, 0, len(messages))
+
+	for idx, message := range r.Stream.Messages {
+		messages[idx] = string(message)
+		fields = append(fields, templateField{"stream.messages", &messages[idx]})
+	}
+
+	if err := executeTemplateFields(fields, jsonData, secret); err != nil {
+		return err
+	}
+
+	for idx, message := range messages {
+		r.Stream.Messages[idx] = json.RawMessage(message)
+	}
+
+	return nil
+}
+
+// escapeJSONData returns the data with the values escaped as content of a
+// JSON string (without the enclosing quotes), like quotes and backslashes.
+func escapeJSONData(data map[string]string) map[string]string {
+	escaped := make(map[string]string, len(data))
+
+	for name, value := range data {
+		// Keep characters like '<' or '&' readable (no HTML escaping).
+		var buf bytes.Buffer
+
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(value)
+
+		encoded := bytes.TrimSpace(buf.Bytes())
+		escaped[name] = string(encoded[1 : len(encoded)-1])
+	}
+
+	return escaped
+}
+
+// templateFields returns the string fields of the params data.
+func (p *ParamsData) templateFields() []templateField {
+	fields := make([]templateField, 0, len(p.Set)+len(p.Add)+len(p.Remove))
+
+	for idx := range p.Set {
+		fields = append(fields, templateField{"paramsData.set", &p.Set[idx]})
+	}
+
+	for idx := range p.Add {
+		fields = append(fields, templateField{"paramsData.add", &p.Add[idx]})
+	}
+
+	for idx := range p.Remove {
+		fields = append(fields, templateField{"paramsData.remove", &p.Remove[idx]})
+	}
+
+	return fields
+}
+
+// templateFields returns the string fields of the request override.
+func (o *RequestOverride) templateFields() []templateField {
+	fields := []templateField{
+		{"request.method", &o.Method},
+		{"request.url", &o.BaseURL},
+		{"request.endpoint", &o.Endpoint},
+		{"request.basicAuth", &o.BasicAuth},
+	}
+
+	for idx := range o.Headers {
+		fields = append(fields, templateField{"request.headers", &o.Headers[idx]})
+	}
+
+	return fields
+}
+
+// executeTemplateFields executes the templates of the fields in-place.
+func executeTemplateFields(fields []templateField, data map[string]string, secret SecretFunc) error {
+	for _, field := range fields {
+		filled, err := executeTemplate(*field.value, data, secret)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+
+		*field.value = filled
+	}
+
+	return nil
+}
+
+// executeTemplate executes the value as template with the template
+// functions and the data as placeholder values.
+func executeTemplate(value string, data map[string]string, secret SecretFunc) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := template.New("").Funcs(templateFuncs(secret)).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// templateFuncs returns the functions of definition templates.
+func templateFuncs(secret SecretFunc) template.FuncMap {
+	return template.FuncMap{
+		"uuid":         templateUUID,
+		"now":          templateNow,
+		"randomInt":    templateRandomInt,
+		"randomString": templateRandomString,
+		"base64":       templateBase64,
+		"sha256":       templateSHA256,
+		"env":          templateEnv,
+		"secret":       templateSecret(secret),
+	}
+}
+
+// templateSecret returns the template function 'secret' of the secret
+// lookup. Without lookup (like on checking the placeholders of data files),
+// the placeholder is returned.
+func templateSecret(secret SecretFunc) SecretFunc {
+	if secret == nil {
+		return func(placeholder string) (string, error) {
+			return placeholder, nil
+		}
+	}
+
+	return secret
+}
+
+// templateUUID returns a random (version 4) UUID.
+func templateUUID() (string, error) {
+	const uuidLength = 16
+
+	uuid := make([]byte, uuidLength)
+
+	if _, err := rand.Read(uuid); err != nil {
+		return "", err
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 //nolint:mnd
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 //nolint:mnd
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
+
+// templateNow returns the current time (UTC), optionally formatted by a Go
+// layout (default RFC 3339) or 'unix' and shifted by an offset, like "-7d",
+// "+2h" or "-1h30m".
+func templateNow(args ...string) (string, error) {
+	const maxArgs = 2
+
+	if len(args) > maxArgs {
+		return "", fmt.Errorf("now: %d arguments, expected format and offset", len(args))
+	}
+
+	now := time.Now().UTC()
+
+	if len(args) == maxArgs {
+		offset, err := parseOffset(args[1])
+		if err != nil {
+			return "", err
+		}
+
+		now = now.Add(offset)
+	}
+
+	layout := time.RFC3339
+	if len(args) > 0 && args[0] != "" {
+		layout = args[0]
+	}
+
+	if layout == "unix" {
+		return strconv.FormatInt(now.Unix(), 10), nil
+	}
+
+	return now.Format(layout), nil
+}
+
+// parseOffset parses a duration which additionally supports days, like "-7d".
+func parseOffset(offset string) (time.Duration, error) {
+	const day = 24 * time.Hour
+
+	if days, isDays := strings.CutSuffix(offset, "d"); isDays {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf(`now: invalid offset "%s"`, offset)
+		}
+
+		return time.Duration(count) * day, nil
+	}
+
+	duration, err := time.ParseDuration(offset)
+	if err != nil {
+		return 0, fmt.Errorf(`now: invalid offset "%s"`, offset)
+	}
+
+	return duration, nil
+}
+
+// templateRandomInt returns a random integer between min and max (inclusive).
+func templateRandomInt(minValue int, maxValue int) (int, error) {
+	if maxValue < minValue {
+		return 0, fmt.Errorf("randomInt: max %d is less than min %d", maxValue, minValue)
+	}
+
+	number, err := rand.Int(rand.Reader, big.NewInt(int64(maxValue-minValue)+1))
+	if err != nil {
+		return 0, err
+	}
+
+	return minValue + int(number.Int64()), nil
+}
+
+// templateRandomString returns a random string of letters and digits.
+func templateRandomString(length int) (string, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	if length < 0 {
+		return "", fmt.Errorf("randomString: negative length %d", length)
+	}
+
+	var builder strings.Builder
+
+	for range length {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteByte(alphabet[index.Int64()])
+	}
+
+	return builder.String(), nil
+}
+
+// templateBase64 returns the standard base64 encoding of the value.
+func templateBase64(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+// templateSHA256 returns the hex encoded SHA-256 hash of the value.
+func templateSHA256(value string) string {
+	hash := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// templateEnv returns the value of the environment variable. Returns an
+// error if the variable is not set.
+func templateEnv(name string) (string, error) {
+	value, isSet := os.LookupEnv(name)
+	if !isSet {
+		return "", fmt.Errorf(`env: environment variable "%s" is not set`, name)
+	}
+
+	return value, nil
+}
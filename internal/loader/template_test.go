@@ -0,0 +1,130 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+func TestPrepareTemplates(t *testing.T) {
+	t.Setenv("APIPROBE_TEST_USER", "morpheus")
+
+	req := &loader.APIRequest{IsActive: true}
+	req.Request.Endpoint = "/api/users/{{randomInt 1 12}}"
+	req.Request.Headers = []string{"Authorization: Basic {{base64 `user:pass`}}"}
+	req.Request.Params = []string{`since={{now "2006-01-02" "-7d"}}`}
+	req.Request.PostBody = "{\"name\":\"{{env `APIPROBE_TEST_USER`}}\",\"id\":\"{{uuid}}\",\"code\":\"{{randomString 8}}\"}"
+	req.TestCases = []loader.TestCases{{Name: "hash", ParamsData: loader.ParamsData{Set: []string{"hash={{sha256 `abc`}}"}}}}
+
+	if err := req.PrepareTemplates(nil); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	if !regexp.MustCompile(`^/api/users/([1-9]|1[0-2])$`).MatchString(req.Request.Endpoint) {
+		t.Errorf("unexpected endpoint %s", req.Request.Endpoint)
+	}
+
+	if req.Request.Headers[0] != "Authorization: Basic dXNlcjpwYXNz" {
+		t.Errorf("unexpected header %s", req.Request.Headers[0])
+	}
+
+	if want := "since=" + time.Now().UTC().AddDate(0, 0, -7).Format("2006-01-02"); req.Request.Params[0] != want {
+		t.Errorf("got param %s, want %s", req.Request.Params[0], want)
+	}
+
+	body := regexp.MustCompile(`^{"name":"morpheus","id":"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}","code":"[a-zA-Z0-9]{8}"}$`)
+	if !body.MatchString(req.Request.PostBody) {
+		t.Errorf("unexpected POST body %s", req.Request.PostBody)
+	}
+
+	if !strings.HasSuffix(req.TestCases[0].ParamsData.Set[0], "=ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad") {
+		t.Errorf("unexpected params data %s", req.TestCases[0].ParamsData.Set)
+	}
+}
+
+func TestPrepareTemplatesKeepsPlaceholdersOfDataFile(t *testing.T) {
+	req := &loader.APIRequest{IsActive: true, TestData: &loader.TestData{File: "users.csv", Key: ""}}
+	req.Request.Endpoint = "/api/users/{{.id}}"
+
+	if err := req.PrepareTemplates(nil); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	if req.Request.Endpoint != "/api/users/{{.id}}" {
+		t.Errorf("placeholder executed on preparation: %s", req.Request.Endpoint)
+	}
+}
+
+func TestPrepareTemplatesFails(t *testing.T) {
+	for _, endpoint := range []string{"/{{unknown}}", "/{{.id}}", "/{{env `APIPROBE_UNSET_VARIABLE`}}", `/{{now "" "-7x"}}`} {
+		req := &loader.APIRequest{IsActive: true}
+		req.Request.Endpoint = endpoint
+
+		if err := req.PrepareTemplates(nil); err == nil {
+			t.Errorf("expected error for %s", endpoint)
+		}
+	}
+}
+
+func TestPrepareTemplatesOfStreamMessages(t *testing.T) {
+	req := &loader.APIRequest{IsActive: true}
+	req.Request.Stream = &loader.Stream{
+		Messages: []json.RawMessage{[]byte("{\"auth\":\"{{base64 `user:pass`}}\"}"), []byte(`"ping"`)},
+		Count:    1,
+	}
+
+	if err := req.PrepareTemplates(nil); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	messages := req.Request.Stream.StreamMessages()
+	if len(messages) != 2 || messages[0] != `{"auth":"dXNlcjpwYXNz"}` || messages[1] != "ping" {
+		t.Errorf("unexpected messages %v", messages)
+	}
+}
+
+func TestSecretTemplateFunction(t *testing.T) {
+	secret := func(placeholder string) (string, error) {
+		if placeholder != "<secret-ab12cd34ef>" {
+			return "", errors.New("unknown secret")
+		}
+
+		return "pa{{ss", nil
+	}
+
+	req := &loader.APIRequest{IsActive: true}
+	req.Request.Headers = []string{"Authorization: Basic {{base64 (secret `<secret-ab12cd34ef>`)}}"}
+
+	if err := req.PrepareTemplates(secret); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	if req.Request.Headers[0] != "Authorization: Basic cGF7e3Nz" {
+		t.Errorf("unexpected header %s", req.Request.Headers[0])
+	}
+
+	// The request of a data file is executed per test case with the same lookup.
+	req = &loader.APIRequest{IsActive: true, TestData: &loader.TestData{File: "users.csv", Key: ""}}
+	req.Request.Headers = []string{"x-key: {{secret `<secret-ab12cd34ef>`}}-{{.id}}"}
+
+	if err := req.PrepareTemplates(secret); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	filled, err := req.WithData(map[string]string{"id": "2"})
+	if err != nil || filled.Request.Headers[0] != "x-key: pa{{ss-2" {
+		t.Errorf("unexpected header %v (%v)", filled, err)
+	}
+
+	req = &loader.APIRequest{IsActive: true}
+	req.Request.Endpoint = "/{{secret `<secret-0000000000>`}}"
+
+	if err = req.PrepareTemplates(secret); err == nil {
+		t.Errorf("expected error of an unknown secret")
+	}
+}
@@ -0,0 +1,256 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Data file extensions of data driven test cases.
+const (
+	ExtCSV   = ".csv"
+	ExtJSONL = ".jsonl"
+)
+
+// Reserved columns of data file rows.
+const (
+	defaultKeyColumn   = "key"
+	expectStatusColumn = "expectStatus"
+)
+
+// TestData references an external data file (CSV with header row or JSONL)
+// of a definition. Each row becomes a test case, the columns fill the
+// placeholders (like '{{.email}}') of the request.
+type TestData struct {
+	// File is the data file path, relative to the definition file.
+	File string `json:"file"`
+
+	// Key is the column which names the test case (default 'key').
+	Key string `json:"key"`
+}
+
+// IsDataDriven returns whether the test cases of the request are read from
+// a data file. The request itself is a template and is not executed.
+func (req *APIRequest) IsDataDriven() bool {
+	return req.TestData != nil
+}
+
+// WithData returns a copy of the request with the templates (placeholders
+// and template functions) of all string fields executed with the data of a
+// data file. The values are JSON escaped within a JSON POST body and stream
+// messages. Returns an error if a placeholder has no value.
+func (req *APIRequest) WithData(data map[string]string) (*APIRequest, error) {
+	return req.withData(data, escapeJSONData(data))
+}
+
+// WithVariables returns a copy of the request with the templates executed
+// with the extracted variables of scenario steps. The values are inserted
+// as they are (also in JSON), so extracted JSON objects and arrays can be
+// used as values. Returns an error if a placeholder has no value.
+func (req *APIRequest) WithVariables(variables map[string]string) (*APIRequest, error) {
+	return req.withData(variables, variables)
+}
+
+// withData returns a copy of the request with the templates executed (see
+// Request.executeTemplates).
+func (req *APIRequest) withData(data map[string]string, jsonData map[string]string) (*APIRequest, error) {
+	filled := *req
+	filled.Request.Params = slices.Clone(req.Request.Params)
+	filled.Request.Headers = slices.Clone(req.Request.Headers)
+
+	if req.Request.Stream != nil {
+		stream := *req.Request.Stream
+		stream.Messages = slices.Clone(stream.Messages)
+		filled.Request.Stream = &stream
+	}
+
+	if err := filled.Request.executeTemplates(data, jsonData, req.secret); err != nil {
+		return nil, err
+	}
+
+	return &filled, nil
+}
+
+// loadTestData reads the data file of the request (relative to the
+// definition directory) and appends a test case per row. The placeholders
+// are checked against the columns of each row.
+func (req *APIRequest) loadTestData(definitionDir string) error {
+	dataFile := filepath.Join(definitionDir, req.TestData.File)
+
+	rows, err := readDataFile(dataFile)
+	if err != nil {
+		logger.Errorf(`Failed to read data file "%s". Error: %v`, dataFile, err)
+
+		return err
+	}
+
+	keyColumn := req.TestData.Key
+	if keyColumn == "" {
+		keyColumn = defaultKeyColumn
+	}
+
+	// Check the placeholders with the unprepared POST body.
+	base := *req
+	base.Request.PostBody = string(req.Request.PostBodyRaw)
+
+	for idx, row := range rows {
+		key := row[keyColumn]
+
+		if key == "" {
+			err = fmt.Errorf(`row %d: no value in key column "%s"`, idx+1, keyColumn)
+			logger.Errorf(`Invalid data file "%s". Error: %v`, dataFile, err)
+
+			return err
+		}
+
+		testCase := TestCases{Name: key, Key: key, Data: row} //nolint:exhaustruct
+
+		if status, hasStatus := row[expectStatusColumn]; hasStatus {
+			delete(row, expectStatusColumn)
+
+			testCase.Expect = &Expectation{Status: StatusCode(status)}
+		}
+
+		if _, err = base.WithData(row); err != nil {
+			logger.Errorf(`Invalid data file "%s", row %d. Error: %v`, dataFile, idx+1, err)
+
+			return err
+		}
+
+		req.TestCases = append(req.TestCases, testCase)
+	}
+
+	return nil
+}
+
+// readDataFile reads the rows of a CSV (with header row) or JSONL file as
+// column-value maps.
+func readDataFile(dataFile string) ([]map[string]string, error) {
+	file, err := os.Open(dataFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(dataFile)) {
+	case ExtCSV:
+		return readCSVRows(file)
+	case ExtJSONL:
+		return readJSONLRows(file)
+	default:
+		return nil, fmt.Errorf(`unsupported data file "%s" (CSV or JSONL)`, dataFile)
+	}
+}
+
+// readCSVRows reads the CSV records, the first record names the columns.
+func readCSVRows(reader io.Reader) ([]map[string]string, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+
+		for idx, column := range header {
+			row[strings.TrimSpace(column)] = record[idx]
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// readJSONLRows reads a JSON object per line. Values which are no strings
+// are used as JSON literals (like numbers or booleans).
+func readJSONLRows(reader io.Reader) ([]map[string]string, error) {
+	var rows []map[string]string
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var object map[string]json.RawMessage
+
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		row := make(map[string]string, len(object))
+
+		for column, raw := range object {
+			var value string
+
+			if err := json.Unmarshal(raw, &value); err != nil {
+				value = string(raw)
+			}
+
+			row[column] = value
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+// FileKey returns the test case key as file name part of the output file:
+// characters other than letters, digits, '.', '_' and '-' are replaced by '-'.
+func FileKey(key string) string {
+	return strings.Map(func(char rune) rune {
+		switch {
+		case unicode.IsLetter(char), unicode.IsDigit(char), char == '.', char == '_', char == '-':
+			return char
+		default:
+			return '-'
+		}
+	}, key)
+}
+
+// checkTestCaseKeys returns an error if the keys of two test cases (inline
+// or data file rows) name the same output file (see FileKey), like "a b"
+// and "a/b", because one would overwrite the output file of the other.
+func (req *APIRequest) checkTestCaseKeys() error {
+	keys := make(map[string]string, len(req.TestCases))
+
+	for _, testCase := range req.TestCases {
+		if testCase.Key == "" {
+			continue
+		}
+
+		fileKey := FileKey(testCase.Key)
+
+		if other, exists := keys[fileKey]; exists {
+			return fmt.Errorf(`duplicate key "%s" of test cases "%s" and "%s" (same output file)`, fileKey, other, testCase.Name)
+		}
+
+		keys[fileKey] = testCase.Name
+	}
+
+	return nil
+}
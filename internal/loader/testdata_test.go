@@ -0,0 +1,146 @@
+package loader_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+const dataDrivenDefinition = `[
+    {
+        "id": "ab12cd34ef",
+        "isActive": true,
+        "request": {
+            "method": "GET",
+            "url": "https://reqres.in",
+            "endpoint": "/api/users/{{.id}}",
+            "headers": ["x-api-key: {{.apiKey}}"],
+            "params": ["lang={{.lang}}"]
+        },
+        "testData": { "file": "users.csv", "key": "case" }
+    }
+]`
+
+const usersCSV = `case,id,apiKey,lang,expectStatus
+existing user,2,reqres-free-v1,en,200
+unknown user,23,reqres-free-v1,de,404
+`
+
+func writeFile(t *testing.T, file string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", file, err)
+	}
+}
+
+func TestDataFileRowsBecomeTestCases(t *testing.T) {
+	inputDir := t.TempDir()
+	writeFile(t, filepath.Join(inputDir, "users.json"), dataDrivenDefinition)
+	writeFile(t, filepath.Join(inputDir, "users.csv"), usersCSV)
+
+	requests, err := loader.LoadAllRequests(inputDir)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	req := requests[0]
+	if !req.IsDataDriven() || len(req.TestCases) != 2 {
+		t.Fatalf("expected 2 test cases of data file, got %d", len(req.TestCases))
+	}
+
+	testCase := req.TestCases[1]
+	if testCase.Key != "unknown user" || testCase.Expect == nil || testCase.Expect.Status != "404" {
+		t.Errorf("unexpected test case %+v", testCase)
+	}
+
+	filled, err := req.WithData(testCase.Data)
+	if err != nil {
+		t.Fatalf("fill: %v", err)
+	}
+
+	if filled.Request.Endpoint != "/api/users/23" || filled.Request.Params[0] != "lang=de" ||
+		filled.Request.Headers[0] != "x-api-key: reqres-free-v1" {
+		t.Errorf("unexpected filled request %+v", filled.Request)
+	}
+
+	if req.Request.Endpoint != "/api/users/{{.id}}" {
+		t.Errorf("template request changed: %s", req.Request.Endpoint)
+	}
+}
+
+func TestDataFileWithMissingColumnFails(t *testing.T) {
+	inputDir := t.TempDir()
+	writeFile(t, filepath.Join(inputDir, "users.json"), dataDrivenDefinition)
+	writeFile(t, filepath.Join(inputDir, "users.csv"), "case,id,apiKey\nfirst,2,key\n")
+
+	if _, err := loader.LoadAllRequests(inputDir); err == nil {
+		t.Error("expected error for placeholder without column")
+	}
+}
+
+func TestExpectationMatchStatus(t *testing.T) {
+	tests := []struct {
+		expected   loader.StatusCode
+		statusCode string
+		want       bool
+	}{
+		{"", "201", true},
+		{"", "404", false},
+		{"404", "404", true},
+		{"4xx", "422", true},
+		{"4xx", "200", false},
+		{"", "OK", true},
+		{"NOT_FOUND", "NotFound", true},
+		{"NotFound", "Unavailable", false},
+	}
+
+	for _, test := range tests {
+		expect := &loader.Expectation{Status: test.expected}
+
+		if got := expect.MatchStatus(test.statusCode); got != test.want {
+			t.Errorf("MatchStatus(%q) with %q = %v, want %v", test.statusCode, test.expected, got, test.want)
+		}
+	}
+}
+
+func TestDataValuesAreEscapedInJSONPostBody(t *testing.T) {
+	req := &loader.APIRequest{IsActive: true} //nolint:exhaustruct
+	req.Request.Endpoint = "/api/users/{{.name}}"
+	req.Request.PostBody = `{"name":"{{.name}}","age":{{.age}}}`
+
+	filled, err := req.WithData(map[string]string{"name": `Tom "T" O'Neil \ <&>`, "age": "42"})
+	if err != nil {
+		t.Fatalf("fill: %v", err)
+	}
+
+	if want := `{"name":"Tom \"T\" O'Neil \\ <&>","age":42}`; filled.Request.PostBody != want {
+		t.Errorf("got POST body %s, want %s", filled.Request.PostBody, want)
+	}
+
+	if want := `/api/users/Tom "T" O'Neil \ <&>`; filled.Request.Endpoint != want {
+		t.Errorf("got endpoint %s, want %s", filled.Request.Endpoint, want)
+	}
+}
+
+func TestKeysOfTheSameOutputFileAreDuplicates(t *testing.T) {
+	const inlineDefinition = `[{"id": "ab12cd34ef", "isActive": true, "request": {"url": "https://reqres.in", "endpoint": "/api/users"},
+		"testCases": [{"name": "first", "key": "a b", "postBodyData": "{}"}, {"name": "second", "key": "a/b", "postBodyData": "{}"}]}]`
+
+	for name, files := range map[string]map[string]string{
+		"data file rows": {"users.json": dataDrivenDefinition, "users.csv": "case,id,apiKey,lang\na b,1,k,en\na/b,2,k,en\n"},
+		"inline keys":    {"users.json": inlineDefinition},
+	} {
+		inputDir := t.TempDir()
+
+		for file, content := range files {
+			writeFile(t, filepath.Join(inputDir, file), content)
+		}
+
+		if _, err := loader.LoadAllRequests(inputDir); err == nil {
+			t.Errorf("%s: expected an error of the keys of the same output file", name)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition file extensions.
+const (
+	ExtJSON = ".json"
+	ExtYAML = ".yaml"
+	ExtYML  = ".yml"
+)
+
+// IsDefinitionFile returns whether the file is a JSON or YAML definition file.
+func IsDefinitionFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ExtJSON, ExtYAML, ExtYML:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsYAMLFile returns whether the file is a YAML definition file.
+func IsYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	return ext == ExtYAML || ext == ExtYML
+}
+
+// YAMLToJSON converts YAML definitions into a JSON array of definitions.
+// Each YAML document is a single definition (mapping) or a list of
+// definitions. The key order is kept. String values of 'postBody' and
+// 'postBodyData' are JSON literals (e.g. multi-line block scalars) and are
+// embedded as JSON; text which is no JSON object, array or string (like a
+// SOAP envelope) is embedded as JSON string.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("[")
+
+	count := 0
+
+	for {
+		var document yaml.Node
+
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(document.Content) == 0 {
+			continue
+		}
+
+		root := document.Content[0]
+
+		definitions := []*yaml.Node{root}
+		if root.Kind == yaml.SequenceNode {
+			definitions = root.Content
+		}
+
+		for _, definition := range definitions {
+			tagIDsAsStrings(definition)
+
+			if count > 0 {
+				buffer.WriteString(",")
+			}
+
+			if err = writeJSONNode(&buffer, definition, ""); err != nil {
+				return nil, err
+			}
+
+			count++
+		}
+	}
+
+	buffer.WriteString("]")
+
+	var indented bytes.Buffer
+
+	if err := json.Indent(&indented, buffer.Bytes(), "", "    "); err != nil {
+		return nil, err
+	}
+
+	indented.WriteString("\n")
+
+	return indented.Bytes(), nil
+}
+
+// tagIDsAsStrings tags the IDs of the definition (and the request IDs of
+// scenario steps) as strings, so unquoted hex hashes (like 1234567890 or
+// 12e4567890) are no numbers.
+func tagIDsAsStrings(definition *yaml.Node) {
+	if definition.Kind != yaml.MappingNode {
+		return
+	}
+
+	for idx := 0; idx+1 < len(definition.Content); idx += 2 {
+		key, value := definition.Content[idx].Value, definition.Content[idx+1]
+
+		switch {
+		case (key == "id" || key == "preRequestId" || key == "requestId") && value.Kind == yaml.ScalarNode:
+			value.Tag = "!!str"
+		case key == "scenario":
+			tagIDsAsStrings(value)
+		case (key == "steps" || key == "cleanup") && value.Kind == yaml.SequenceNode:
+			for _, step := range value.Content {
+				tagIDsAsStrings(step)
+			}
+		}
+	}
+}
+
+// writeJSONNode writes the YAML node as JSON. The key is the mapping key
+// of the node (to embed the JSON literals of POST bodies).
+func writeJSONNode(buffer *bytes.Buffer, node *yaml.Node, key string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeJSONNode(buffer, node.Content[0], key)
+	case yaml.AliasNode:
+		return writeJSONNode(buffer, node.Alias, key)
+	case yaml.MappingNode:
+		buffer.WriteString("{")
+
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			if idx > 0 {
+				buffer.WriteString(",")
+			}
+
+			name, _ := json.Marshal(node.Content[idx].Value)
+			buffer.Write(name)
+			buffer.WriteString(":")
+
+			if err := writeJSONNode(buffer, node.Content[idx+1], node.Content[idx].Value); err != nil {
+				return err
+			}
+		}
+
+		buffer.WriteString("}")
+	case yaml.SequenceNode:
+		buffer.WriteString("[")
+
+		for idx, item := range node.Content {
+			if idx > 0 {
+				buffer.WriteString(",")
+			}
+
+			if err := writeJSONNode(buffer, item, ""); err != nil {
+				return err
+			}
+		}
+
+		buffer.WriteString("]")
+	case yaml.ScalarNode:
+		return writeJSONScalar(buffer, node, key)
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+
+	return nil
+}
+
+// writeJSONScalar writes the YAML scalar as JSON value.
+func writeJSONScalar(buffer *bytes.Buffer, node *yaml.Node, key string) error {
+	if node.ShortTag() == "!!str" && isPostBodyKey(key) {
+		literal := strings.TrimSpace(node.Value)
+		if literal == "" {
+			literal = "{}"
+		}
+
+		// Text POST body (see textPostBody).
+		if !strings.HasPrefix(literal, "{") && !strings.HasPrefix(literal, "[") && !strings.HasPrefix(literal, `"`) {
+			encoded, _ := json.Marshal(node.Value)
+			buffer.Write(encoded)
+
+			return nil
+		}
+
+		if !json.Valid([]byte(literal)) {
+			return fmt.Errorf(`line %d: "%s" is no valid JSON`, node.Line, key)
+		}
+
+		buffer.WriteString(literal)
+
+		return nil
+	}
+
+	var value any
+
+	if err := node.Decode(&value); err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+
+	buffer.Write(encoded)
+
+	return nil
+}
+
+// JSONToYAML converts a JSON array of definitions into YAML. The key order
+// is kept and multi-line strings (like jq filters) are written as literal
+// block scalars. Text POST bodies (JSON strings) are written as JSON string
+// literals, so YAMLToJSON restores them unchanged.
+func JSONToYAML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	node, err := readYAMLNode(decoder, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2) //nolint:mnd
+
+	if err = encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// readYAMLNode reads the next JSON value (of the mapping key) of the decoder
+// as YAML node.
+func readYAMLNode(decoder *json.Decoder, key string) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"} //nolint:exhaustruct
+		if value == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"} //nolint:exhaustruct
+		}
+
+		for decoder.More() {
+			childKey := ""
+
+			if node.Kind == yaml.MappingNode {
+				keyToken, keyErr := decoder.Token()
+				if keyErr != nil {
+					return nil, keyErr
+				}
+
+				childKey = fmt.Sprint(keyToken)
+				node.Content = append(node.Content, stringNode(childKey))
+			}
+
+			child, childErr := readYAMLNode(decoder, childKey)
+			if childErr != nil {
+				return nil, childErr
+			}
+
+			node.Content = append(node.Content, child)
+		}
+
+		// Consume the closing delimiter.
+		if _, err = decoder.Token(); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	case string:
+		if isPostBodyKey(key) {
+			encoded, _ := json.Marshal(value)
+
+			return stringNode(string(encoded)), nil
+		}
+
+		return stringNode(value), nil
+	case json.Number:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "", Value: value.String()}, nil //nolint:exhaustruct
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)}, nil //nolint:exhaustruct
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil //nolint:exhaustruct
+	}
+}
+
+// stringNode returns the YAML node of a string, multi-line strings as
+// literal block scalar.
+func stringNode(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value} //nolint:exhaustruct
+	if strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+
+	return node
+}
+
+// isPostBodyKey returns whether the key is a POST body (JSON literal) key.
+func isPostBodyKey(key string) bool {
+	return key == "postBody" || key == "postBodyData"
+}
@@ -0,0 +1,135 @@
+package loader_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+const definitionsYAML = `
+id: 1234567890
+isActive: true
+request:
+  method: POST
+  url: https://reqres.in
+  endpoint: /api/users
+  headers:
+    - "Content-Type: application/json"
+  postBody: |
+    {
+        "name": "morpheus",
+        "job": "leader"
+    }
+jq: |
+  .data
+  | map(.id)
+---
+- id: ff00fceb61
+  isActive: false
+  request:
+    method: GET
+    endpoint: /api/users
+`
+
+func TestYAMLToJSON(t *testing.T) {
+	data, err := loader.YAMLToJSON([]byte(definitionsYAML))
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	var requests []loader.APIRequest
+
+	if err = json.Unmarshal(data, &requests); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, data)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 definitions of 2 documents, got %d", len(requests))
+	}
+
+	if requests[0].ID != "1234567890" || requests[1].ID != "ff00fceb61" {
+		t.Errorf("unexpected IDs %q and %q", requests[0].ID, requests[1].ID)
+	}
+
+	if requests[0].JqCommand != ".data\n| map(.id)\n" {
+		t.Errorf("unexpected multi-line jq %q", requests[0].JqCommand)
+	}
+
+	if err = requests[0].PreparePostBody(); err != nil {
+		t.Fatalf("prepare POST body: %v", err)
+	}
+
+	if requests[0].Request.PostBody != `{"name":"morpheus","job":"leader"}` {
+		t.Errorf("unexpected POST body %q", requests[0].Request.PostBody)
+	}
+
+	if err = requests[1].PreparePostBody(); err != nil {
+		t.Errorf("omitted POST body: %v", err)
+	}
+}
+
+func TestJSONToYAMLRoundTrip(t *testing.T) {
+	original := []byte(`[{"id":"0123456789","isActive":true,"tags":["env-prod"],` +
+		`"request":{"method":"POST","postBody":{"b":1,"a":[true,null]}},"jq":".a\n| .b","maxDurationMs":{"failed":1.5}}]`)
+
+	yamlData, err := loader.JSONToYAML(original)
+	if err != nil {
+		t.Fatalf("to YAML: %v", err)
+	}
+
+	jsonData, err := loader.YAMLToJSON(yamlData)
+	if err != nil {
+		t.Fatalf("to JSON: %v\n%s", err, yamlData)
+	}
+
+	var expected, actual any
+
+	_ = json.Unmarshal(original, &expected)
+	_ = json.Unmarshal(jsonData, &actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("round trip differs:\n%s\n%s", yamlData, jsonData)
+	}
+}
+
+func TestJSONToYAMLRoundTripTextPostBody(t *testing.T) {
+	original := []byte(`[{"id":"0123456789","isActive":true,"request":{"method":"POST",` +
+		`"postBody":"<soap:Envelope xmlns:soap=\"http://www.w3.org/2003/05/soap-envelope\">\n  <soap:Body/>\n</soap:Envelope>"}}]`)
+
+	yamlData, err := loader.JSONToYAML(original)
+	if err != nil {
+		t.Fatalf("to YAML: %v", err)
+	}
+
+	jsonData, err := loader.YAMLToJSON(yamlData)
+	if err != nil {
+		t.Fatalf("to JSON: %v\n%s", err, yamlData)
+	}
+
+	var expected, actual any
+
+	_ = json.Unmarshal(original, &expected)
+	_ = json.Unmarshal(jsonData, &actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("round trip differs:\n%s\n%s", yamlData, jsonData)
+	}
+
+	// Written by hand, XML text is no JSON literal.
+	jsonData, err = loader.YAMLToJSON([]byte("id: 0123456789\nrequest:\n  postBody: |\n    <soap:Envelope/>\n"))
+	if err != nil {
+		t.Fatalf("to JSON: %v", err)
+	}
+
+	var requests []loader.APIRequest
+
+	if err = json.Unmarshal(jsonData, &requests); err != nil || requests[0].PreparePostBody() != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, jsonData)
+	}
+
+	if requests[0].Request.PostBody != "<soap:Envelope/>\n" {
+		t.Errorf("unexpected text POST body %q", requests[0].Request.PostBody)
+	}
+}
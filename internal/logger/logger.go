@@ -10,14 +10,14 @@ import (
 	"time"
 )
 
-// Init sets up the logger, creates a new log file,
+// Init sets up the logger, creates a new log file in the logs directory,
 // directs output to both console and file, and
 // returns an error if initialization fails.
-func Init() error {
+func Init(logsRoot string) error {
 	now := time.Now()
 	yearMonth := now.Format("2006-01")
 	day := now.Format("02")
-	logsDir := filepath.Join(".", "logs", yearMonth, day)
+	logsDir := filepath.Join(logsRoot, yearMonth, day)
 
 	if err := os.MkdirAll(logsDir, os.ModePerm); err != nil { //nolint:gosec
 		Errorf(`Failed to create logs directory "%s". Error: %v`, logsDir, err)
@@ -0,0 +1,319 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// Transports of a delivery.
+const (
+	TransportHTTP = "http"
+	TransportSMTP = "smtp"
+)
+
+// Delivery is a single notification message of a notifier. It is stored
+// as JSON in the spool directory if it could not be delivered. Secrets stay
+// placeholders ('<secret-…>') and are resolved on sending only.
+type Delivery struct {
+	Tool       string            `json:"tool"`
+	Transport  string            `json:"transport"`
+	Method     string            `json:"method,omitempty"`
+	URL        string            `json:"url,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Recipients []string          `json:"recipients,omitempty"`
+	Payload    []byte            `json:"payload"`
+	CreatedAt  string            `json:"createdAt"`
+	Attempts   int               `json:"attempts"`
+	LastError  string            `json:"lastError,omitempty"`
+}
+
+// deliveryError is a failed delivery attempt. Permanent errors (like a
+// rejected payload) are neither retried nor spooled.
+type deliveryError struct {
+	permanent bool
+	err       error
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// deliverySender delivers notifications with retries and timeout.
+type deliverySender struct {
+	cfg     *config.Config
+	conn    *sqlite.Conn
+	client  *http.Client
+	policy  config.Delivery
+	backoff time.Duration
+}
+
+// newHTTPDelivery returns a delivery of the payload to the webhook URL.
+// The HTTP method defaults to POST.
+func newHTTPDelivery(notificationTool string, method string, webhookURL string, headers map[string]string, payload []byte) Delivery {
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	return Delivery{
+		Tool:       notificationTool,
+		Transport:  TransportHTTP,
+		Method:     method,
+		URL:        webhookURL,
+		Headers:    headers,
+		Recipients: nil,
+		Payload:    payload,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Attempts:   0,
+		LastError:  "",
+	}
+}
+
+// newDeliverySender returns a sender with the delivery settings of the
+// config (defaults for unset values).
+func newDeliverySender(cfg *config.Config, conn *sqlite.Conn) *deliverySender {
+	policy := normalizeDeliveryPolicy(cfg.Notification.Delivery)
+
+	return &deliverySender{
+		cfg:     cfg,
+		conn:    conn,
+		client:  &http.Client{Timeout: time.Duration(policy.TimeoutSeconds) * time.Second}, //nolint:exhaustruct
+		policy:  policy,
+		backoff: time.Duration(policy.BackoffSeconds) * time.Second,
+	}
+}
+
+// normalizeDeliveryPolicy returns the delivery settings with defaults for unset values.
+func normalizeDeliveryPolicy(policy config.Delivery) config.Delivery {
+	const (
+		defaultRetries          = 3
+		defaultBackoffSeconds   = 2
+		defaultTimeoutSeconds   = 10
+		defaultMaxSpoolAgeHours = 24
+	)
+
+	if policy.Retries < 0 {
+		policy.Retries = 0
+	} else if policy.Retries == 0 {
+		policy.Retries = defaultRetries
+	}
+
+	if policy.BackoffSeconds <= 0 {
+		policy.BackoffSeconds = defaultBackoffSeconds
+	}
+
+	if policy.TimeoutSeconds <= 0 {
+		policy.TimeoutSeconds = defaultTimeoutSeconds
+	}
+
+	if policy.MaxSpoolAgeHours <= 0 {
+		policy.MaxSpoolAgeHours = defaultMaxSpoolAgeHours
+	}
+
+	return policy
+}
+
+// deliver sends the delivery and retries failed attempts with exponential
+// backoff. Permanent errors are not retried. Returns the last error.
+func (s *deliverySender) deliver(ctx context.Context, delivery *Delivery) error {
+	backoff := s.backoff
+
+	var err error
+
+	for attempt := 0; attempt <= s.policy.Retries; attempt++ {
+		if attempt > 0 {
+			logger.Warnf("%s notification failed (attempt %d). Retry in %s. Error: %v", delivery.Tool, attempt, backoff, err)
+
+			select {
+			case <-ctx.Done():
+				err = errors.Join(err, ctx.Err())
+				delivery.LastError = err.Error()
+
+				return err
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+		}
+
+		delivery.Attempts++
+
+		err = s.send(ctx, delivery)
+		if err == nil {
+			logger.Infof("%s notification sent successfully", delivery.Tool)
+
+			return nil
+		}
+
+		var delErr *deliveryError
+		if errors.As(err, &delErr) && delErr.permanent {
+			break
+		}
+	}
+
+	logger.Errorf("%s notification could not be delivered. Error: %v", delivery.Tool, err)
+	delivery.LastError = err.Error()
+
+	return err
+}
+
+// send executes a single delivery attempt by its transport.
+func (s *deliverySender) send(ctx context.Context, delivery *Delivery) error {
+	switch delivery.Transport {
+	case TransportHTTP:
+		return s.sendHTTP(ctx, delivery)
+	case TransportSMTP:
+		emailCfg := s.cfg.Notification.Email
+		if emailCfg == nil {
+			return &deliveryError{permanent: true, err: errors.New("email notification is not configured")}
+		}
+
+		timeout := time.Duration(s.policy.TimeoutSeconds) * time.Second
+
+		return classifySMTPError(sendEmail(ctx, s.conn, emailCfg, timeout, delivery.Recipients, delivery.Payload))
+	default:
+		return &deliveryError{permanent: true, err: fmt.Errorf(`unknown transport "%s"`, delivery.Transport)}
+	}
+}
+
+// sendHTTP sends the payload to the webhook URL and validates the status
+// code: 2xx is a success, 429 and 5xx are retried, other codes are permanent
+// errors. Secrets in the URL and header values are replaced before sending.
+func (s *deliverySender) sendHTTP(ctx context.Context, delivery *Delivery) error {
+	const maxErrorBodyBytes = 512
+
+	url := resolveSecret(s.conn, delivery.URL)
+
+	req, err := http.NewRequestWithContext(ctx, delivery.Method, url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return &deliveryError{permanent: true, err: err}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range delivery.Headers {
+		req.Header.Set(key, resolveSecret(s.conn, value))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &deliveryError{permanent: false, err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+
+	return &deliveryError{
+		permanent: !retryable,
+		err:       fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body))),
+	}
+}
+
+// spoolDelivery stores the undelivered notification in the spool directory,
+// to retry it on the next run. Permanent failures are not spooled.
+func spoolDelivery(spoolDir string, delivery *Delivery, deliveryErr error) {
+	const permissions = 0o600
+
+	var delErr *deliveryError
+	if errors.As(deliveryErr, &delErr) && delErr.permanent {
+		logger.Errorf("%s notification is discarded (permanent error).", delivery.Tool)
+
+		return
+	}
+
+	if err := os.MkdirAll(spoolDir, os.ModePerm); err != nil { //nolint:gosec
+		logger.Errorf(`Failed to create spool directory "%s". Error: %v`, spoolDir, err)
+
+		return
+	}
+
+	data, err := json.MarshalIndent(delivery, "", "    ")
+	if err != nil {
+		logger.Errorf("Failed to marshal %s notification. Error: %v", delivery.Tool, err)
+
+		return
+	}
+
+	toolName := regexp.MustCompile(`[^a-zA-Z0-9]+`).ReplaceAllString(strings.ToLower(delivery.Tool), "-")
+	fileName := fmt.Sprintf("%s-%s.json", time.Now().Format("2006-01-02-15-04-05.000000"), toolName)
+	filePath := filepath.Join(spoolDir, fileName)
+
+	if err = os.WriteFile(filePath, data, permissions); err != nil {
+		logger.Errorf(`Failed to spool %s notification to "%s". Error: %v`, delivery.Tool, filePath, err)
+
+		return
+	}
+
+	logger.Warnf(`%s notification spooled to "%s", it will be retried on the next run.`, delivery.Tool, filePath)
+}
+
+// loadSpool returns the spooled notifications of previous runs and removes
+// their files (undelivered ones are spooled again). Notifications older
+// than the maximum spool age are discarded.
+func loadSpool(spoolDir string, delivery config.Delivery) []Delivery {
+	maxAge := time.Duration(normalizeDeliveryPolicy(delivery).MaxSpoolAgeHours) * time.Hour
+
+	files, err := filepath.Glob(filepath.Join(spoolDir, "*.json"))
+	if err != nil || len(files) == 0 {
+		return nil
+	}
+
+	deliveries := make([]Delivery, 0, len(files))
+
+	for _, file := range files {
+		data, readErr := os.ReadFile(file)
+		_ = os.Remove(file)
+
+		if readErr != nil {
+			logger.Errorf(`Failed to read spooled notification "%s". Error: %v`, file, readErr)
+
+			continue
+		}
+
+		var spooled Delivery
+
+		if err = json.Unmarshal(data, &spooled); err != nil {
+			logger.Errorf(`Invalid spooled notification "%s" discarded. Error: %v`, file, err)
+
+			continue
+		}
+
+		createdAt, parseErr := time.Parse(time.RFC3339, spooled.CreatedAt)
+		if parseErr != nil || time.Since(createdAt) > maxAge {
+			logger.Warnf(`Spooled %s notification of %s discarded (too old).`, spooled.Tool, spooled.CreatedAt)
+
+			continue
+		}
+
+		deliveries = append(deliveries, spooled)
+	}
+
+	if len(deliveries) > 0 {
+		logger.Infof("Retry %d spooled notifications of previous runs.", len(deliveries))
+	}
+
+	return deliveries
+}
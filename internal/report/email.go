@@ -0,0 +1,398 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// SMTP connection security modes (see config.Email).
+const (
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+	SecurityNone     = "none"
+)
+
+// email is a composed notification mail.
+type email struct {
+	subject     string
+	body        string
+	attachments []emailAttachment
+}
+
+type emailAttachment struct {
+	fileName    string
+	contentType string
+	data        []byte
+}
+
+// emailNotifier sends notification mails via SMTP.
+type emailNotifier struct {
+	cfg *config.Email
+}
+
+// Name returns the name of the notification tool.
+func (n *emailNotifier) Name() string {
+	return "Email"
+}
+
+// Deliveries returns the heartbeat, recovery or report mail (with attached
+// report files), depending on the notice kind, for the recipients of the channel.
+func (n *emailNotifier) Deliveries(notice *Notice, channelName string) ([]Delivery, error) {
+	recipients, exists := n.cfg.Recipients[channelName]
+	if !exists || len(recipients) == 0 {
+		logger.Warnf(n.Name()+" recipients of channel '%s' not found in config. No notification sent.", channelName)
+
+		return nil, nil
+	}
+
+	hostnameMessage := fmt.Sprintf("Message from: %s (hostname)", notice.Hostname)
+
+	var mail *email
+
+	switch notice.Kind {
+	case KindHeartbeat:
+		mail = buildHeartbeatEmail(hostnameMessage)
+	case KindRecovery:
+		mail = buildRecoveryEmail(notice.Report, notice.RunName, hostnameMessage)
+	default:
+		mail = buildReportEmail(n.cfg, notice.Result, notice.Report, notice.RunName, notice.ReportFilePath, hostnameMessage)
+	}
+
+	message, err := composeEmail(n.cfg.From, recipients, mail)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Delivery{{
+		Tool:       n.Name(),
+		Transport:  TransportSMTP,
+		Method:     "",
+		URL:        "",
+		Headers:    nil,
+		Recipients: recipients,
+		Payload:    message,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Attempts:   0,
+		LastError:  "",
+	}}, nil
+}
+
+// buildHeartbeatEmail creates the heartbeat email.
+func buildHeartbeatEmail(hostnameMessage string) *email {
+	return &email{
+		subject:     "💙 " + config.Version + " - Heartbeat: still alive",
+		body:        fmt.Sprintf("%s\nHeartbeat: still alive\n\n%s\n", config.Version, hostnameMessage),
+		attachments: nil,
+	}
+}
+
+// buildRecoveryEmail creates the recovery email, sent if endpoints
+// recovered and no other issues exist.
+func buildRecoveryEmail(rep *Report, runName string, hostnameMessage string) *email {
+	return &email{
+		subject: buildEmailSubject("🟢", runName, fmt.Sprintf("%d endpoints recovered", len(rep.Recoveries))),
+		body: fmt.Sprintf("%s\n%s%s\n%s\n",
+			config.Version, buildRunNameLine(runName), toPlainText(buildRecoveriesMarkdown(rep)), hostnameMessage),
+		attachments: nil,
+	}
+}
+
+// buildReportEmail creates the report email with a summary body and the
+// configured report formats as attachments (JSON by default).
+func buildReportEmail(
+	emailCfg *config.Email,
+	res *Result,
+	rep *Report,
+	runName string,
+	reportFilePath string,
+	hostnameMessage string,
+) *email {
+	trafficLight := "🔴"
+	if !res.HasFailures() {
+		trafficLight = "🟡"
+	}
+
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "%s\n%s\n", config.Version, buildRunNameLine(runName))
+	fmt.Fprintf(&body, "Files with changed content: %d\nRequest errors: %d\nFormat response errors: %d\n"+
+		"Latency failed: %d\nLatency degraded: %d\nHook errors: %d\n",
+		res.ChangedFilesCount, res.RequestErrorCount, res.FormatResponseErrorCount,
+		res.LatencyFailedCount, res.LatencyDegradedCount, res.HookErrorCount)
+
+	body.WriteString(toPlainText(buildSlowestRequestsMarkdown(rep)))
+	body.WriteString(toPlainText(buildRecoveriesMarkdown(rep)))
+	body.WriteString("\nIssues:\n")
+
+	for _, issue := range rep.Requests {
+		fmt.Fprintf(&body, "- [%s] %s %s (%s)", issue.Issue, issue.Method, issue.Endpoint, issue.ID)
+
+		if issue.TestCase != "" {
+			fmt.Fprintf(&body, ", test case: %s", issue.TestCase)
+		}
+
+		if issue.StatusCode != "" {
+			fmt.Fprintf(&body, ", status code: %s", issue.StatusCode)
+		}
+
+		body.WriteString("\n")
+	}
+
+	fmt.Fprintf(&body, "\nReport file: %s\n\n%s\n", reportFilePath, hostnameMessage)
+
+	formats := emailCfg.Attachments
+	if len(formats) == 0 {
+		formats = []string{FormatJSON}
+	}
+
+	attachments := make([]emailAttachment, 0, len(formats))
+
+	for _, format := range formats {
+		data, fileName, contentType, err := rep.Render(format, res, runName)
+		if err != nil {
+			logger.Warnf(`Report format "%s" not attached. Error: %v`, format, err)
+
+			continue
+		}
+
+		attachments = append(attachments, emailAttachment{fileName: fileName, contentType: contentType, data: data})
+	}
+
+	return &email{
+		subject:     buildEmailSubject(trafficLight, runName, fmt.Sprintf("%d issues", len(rep.Requests))),
+		body:        body.String(),
+		attachments: attachments,
+	}
+}
+
+// buildEmailSubject returns the subject line like "🔴 APIProbe ... [prod] - 3 issues".
+func buildEmailSubject(trafficLight string, runName string, summary string) string {
+	subject := trafficLight + " " + config.Version
+	if runName != "" {
+		subject += " [" + runName + "]"
+	}
+
+	return subject + " - " + summary
+}
+
+// buildRunNameLine returns the run name as own line or an empty string.
+func buildRunNameLine(runName string) string {
+	if runName == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("Run: %s\n", runName)
+}
+
+// toPlainText removes the markdown emphasis and code markers of the shared
+// (WebEx) markdown snippets.
+func toPlainText(markdown string) string {
+	return strings.NewReplacer("__", "", "`", "").Replace(markdown)
+}
+
+// sendEmail connects to the configured SMTP server (implicit TLS, STARTTLS
+// or plain), authenticates if credentials are configured and sends the
+// message to all recipients. Rejected commands are permanent errors.
+func sendEmail(
+	ctx context.Context,
+	conn *sqlite.Conn,
+	emailCfg *config.Email,
+	timeout time.Duration,
+	recipients []string,
+	message []byte,
+) error {
+	security := strings.ToLower(emailCfg.Security)
+	if security == "" {
+		security = SecurityStartTLS
+	}
+
+	address := net.JoinHostPort(emailCfg.Host, strconv.Itoa(smtpPort(emailCfg.Port, security)))
+	tlsConfig := &tls.Config{ServerName: emailCfg.Host, MinVersion: tls.VersionTLS12} //nolint:exhaustruct
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{Timeout: timeout} //nolint:exhaustruct
+
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	if security == SecurityTLS {
+		netConn = tls.Client(netConn, tlsConfig)
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = netConn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(netConn, emailCfg.Host)
+	if err != nil {
+		netConn.Close()
+
+		return err
+	}
+	defer client.Close()
+
+	if security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return &deliveryError{permanent: true, err: errors.New("SMTP server does not support STARTTLS")}
+		}
+
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if emailCfg.Username != "" {
+		auth := smtp.PlainAuth("", resolveSecret(conn, emailCfg.Username), resolveSecret(conn, emailCfg.Password), emailCfg.Host)
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(emailCfg.From); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err = client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// classifySMTPError marks errors of rejected SMTP commands (5xx reply
+// codes, like a failed authentication or an unknown recipient) as permanent.
+func classifySMTPError(err error) error {
+	const permanentReplyCode = 500
+
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) && protocolErr.Code >= permanentReplyCode {
+		return &deliveryError{permanent: true, err: err}
+	}
+
+	return err
+}
+
+// smtpPort returns the configured port or the default port of the security mode.
+func smtpPort(port int, security string) int {
+	const (
+		portTLS      = 465
+		portStartTLS = 587
+		portNone     = 25
+	)
+
+	if port > 0 {
+		return port
+	}
+
+	switch security {
+	case SecurityTLS:
+		return portTLS
+	case SecurityNone:
+		return portNone
+	default:
+		return portStartTLS
+	}
+}
+
+// composeEmail returns the MIME message (multipart/mixed) with the plain
+// text body and base64 encoded attachments.
+func composeEmail(from string, recipients []string, mail *email) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	writer := multipart.NewWriter(&buffer)
+
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buffer, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buffer, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	bodyPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeBase64(bodyPart, []byte(mail.body)); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range mail.attachments {
+		part, partErr := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, attachment.fileName)},
+		})
+		if partErr != nil {
+			return nil, partErr
+		}
+
+		if err = writeBase64(part, attachment.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// writeBase64 writes the data base64 encoded with line breaks (RFC 2045).
+func writeBase64(writer io.Writer, data []byte) error {
+	const lineLength = 76
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	for len(encoded) > 0 {
+		length := min(lineLength, len(encoded))
+
+		if _, err := writer.Write([]byte(encoded[:length] + "\r\n")); err != nil {
+			return err
+		}
+
+		encoded = encoded[length:]
+	}
+
+	return nil
+}
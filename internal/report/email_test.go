@@ -0,0 +1,123 @@
+package report_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// startSMTPStandIn starts a minimal SMTP server which accepts a single mail
+// and sends the received DATA content to the returned channel.
+func startSMTPStandIn(t *testing.T) (int, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, acceptErr := listener.Accept()
+		if acceptErr != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP stand-in")
+
+		for {
+			line, readErr := reader.ReadString('\n')
+			if readErr != nil {
+				return
+			}
+
+			command := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+
+				var data strings.Builder
+
+				for {
+					dataLine, dataErr := reader.ReadString('\n')
+					if dataErr != nil || dataLine == ".\r\n" {
+						break
+					}
+
+					data.WriteString(dataLine)
+				}
+
+				received <- data.String()
+
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, received //nolint:forcetypeassert
+}
+
+func TestEmailNotificationWithAttachments(t *testing.T) {
+	port, received := startSMTPStandIn(t)
+
+	cfg, _ := newWorkspaceConfig(t)
+	cfg.Notification.Email = &config.Email{
+		Active:      true,
+		Host:        "127.0.0.1",
+		Port:        port,
+		Security:    report.SecurityNone,
+		From:        "apiprobe@example.com",
+		Recipients:  map[string][]string{"default": {"team@example.com"}},
+		Attachments: []string{report.FormatJSON, report.FormatHTML, report.FormatJUnit},
+	}
+
+	req := &loader.APIRequest{ID: "0f1e2d3c4b"}
+	req.Request.Method = "GET"
+	req.Request.Endpoint = "/users"
+	req.Request.Name = "Users"
+
+	res := &report.Result{ChangedFilesCount: 1}
+	rep := &report.Report{}
+	rep.AddReportData(req, report.IssueChanged, "200", "", "./data/output/users.json", -1, nil)
+
+	if err := report.Notification(context.Background(), cfg, nil, res, rep, "test", ""); err != nil {
+		t.Fatalf("notification: %v", err)
+	}
+
+	mail := <-received
+
+	for _, expected := range []string{
+		"To: team@example.com",
+		"Subject: =?utf-8?q?",
+		`filename="report.json"`,
+		`filename="report.html"`,
+		`filename="report-junit.xml"`,
+	} {
+		if !strings.Contains(mail, expected) {
+			t.Errorf("mail does not contain %s", strconv.Quote(expected))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package report
+
+// Exported for the tests of the alert policy (package report_test).
+var (
+	NormalizeAlertPolicy = normalizeAlertPolicy
+	ShouldAlert          = shouldAlert
+	CollectRecoveries    = collectRecoveries
+	ConsecutiveFailures  = consecutiveFailures
+	FlakinessScore       = flakinessScore
+)
+
+// Exported for the golden tests of the notification payloads.
+var (
+	BuildMSTeamsReportPayload   = buildMSTeamsReportPayload
+	BuildMSTeamsRecoveryPayload = buildMSTeamsRecoveryPayload
+)
+
+// Exported for the golden tests of the Slack payloads.
+var (
+	BuildSlackReportPayload   = buildSlackReportPayload
+	BuildSlackRecoveryPayload = buildSlackRecoveryPayload
+)
+
+// BuildGenericPayload is exported for the tests of the generic webhook.
+var BuildGenericPayload = buildGenericPayload
@@ -0,0 +1,288 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Report formats (e.g. for email attachments).
+const (
+	FormatJSON  = "json"
+	FormatHTML  = "html"
+	FormatJUnit = "junit"
+)
+
+// Render returns the report in the given format (FormatJSON, FormatHTML or
+// FormatJUnit) together with the file name and MIME type of the format.
+func (r *Report) Render(format string, res *Result, runName string) ([]byte, string, string, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(r, "", "    ")
+
+		return data, "report.json", "application/json", err
+	case FormatHTML:
+		data, err := r.HTML(res, runName)
+
+		return data, "report.html", "text/html; charset=utf-8", err
+	case FormatJUnit:
+		data, err := r.JUnit(runName)
+
+		return data, "report-junit.xml", "application/xml", err
+	default:
+		return nil, "", "", fmt.Errorf(`unknown report format "%s"`, format)
+	}
+}
+
+// htmlReportTemplate renders the result counters, the issues and the
+// timings of all requests as simple HTML page.
+const htmlReportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Version}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.failure { background: #fde2e4; }
+.warning { background: #fff4ce; }
+pre { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h2>{{.TrafficLight}} {{.Version}}</h2>
+{{if .RunName}}<p><code>{{.RunName}}</code></p>{{end}}
+<table>
+<tr><th>Files with changed content</th><td>{{.Result.ChangedFilesCount}}</td></tr>
+<tr><th>Request errors</th><td>{{.Result.RequestErrorCount}}</td></tr>
+<tr><th>Format response errors</th><td>{{.Result.FormatResponseErrorCount}}</td></tr>
+<tr><th>Latency failed</th><td>{{.Result.LatencyFailedCount}}</td></tr>
+<tr><th>Latency degraded</th><td>{{.Result.LatencyDegradedCount}}</td></tr>
+<tr><th>Hook errors</th><td>{{.Result.HookErrorCount}}</td></tr>
+</table>
+{{if .Report.Requests}}<h3>Issues</h3>
+<table>
+<tr><th>Issue</th><th>ID</th><th>Request</th><th>Test case</th><th>Status code</th><th>Duration</th><th>Output file</th><th>Error response</th></tr>
+{{range .Report.Requests}}<tr class="{{if .IsFailure}}failure{{else}}warning{{end}}">
+<td>{{.Issue}}{{if .Latency}} (latency {{.Latency}}){{end}}</td><td>{{.ID}}</td><td>{{.Method}} {{.Endpoint}}</td><td>{{.TestCase}}</td>
+<td>{{.StatusCode}}</td><td>{{with .Timings}}{{printf "%.0f" .TotalMs}}ms{{end}}</td><td>{{.OutputFile}}</td><td><pre>{{.ErrorResponse}}</pre></td>
+</tr>
+{{end}}</table>{{end}}
+{{if .Report.Recoveries}}<h3>Recovered endpoints</h3>
+<ul>
+{{range .Report.Recoveries}}<li>{{.Method}} {{.Endpoint}} ({{.ID}}) is back after {{.Failures}} failed runs</li>
+{{end}}</ul>{{end}}
+{{if .Report.Timings}}<h3>Timings</h3>
+<table>
+<tr><th>ID</th><th>Request</th><th>Test case</th><th>DNS</th><th>Connect</th><th>TLS</th><th>TTFB</th><th>Total</th></tr>
+{{range .Report.Timings}}<tr{{if .Latency}} class="{{if eq .Latency "failed"}}failure{{else}}warning{{end}}"{{end}}>
+<td>{{.ID}}</td><td>{{.Method}} {{.Endpoint}}</td><td>{{.TestCase}}</td>
+<td>{{printf "%.0f" .Timings.DNSMs}}ms</td><td>{{printf "%.0f" .Timings.ConnectMs}}ms</td><td>{{printf "%.0f" .Timings.TLSMs}}ms</td>
+<td>{{printf "%.0f" .Timings.TTFBMs}}ms</td><td>{{printf "%.0f" .Timings.TotalMs}}ms</td>
+</tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`
+
+// HTML renders the report (result counters, issues, recoveries and timings)
+// as HTML page.
+func (r *Report) HTML(res *Result, runName string) ([]byte, error) {
+	tmpl, err := template.New("report").Parse(htmlReportTemplate)
+	if err != nil {
+		logger.Errorf("Failure on parse HTML report template. Error: %v", err)
+
+		return nil, err
+	}
+
+	trafficLight := "🟢"
+
+	switch {
+	case res.HasFailures():
+		trafficLight = "🔴"
+	case res.HasIssues():
+		trafficLight = "🟡"
+	}
+
+	data := map[string]any{
+		"Version":      config.Version,
+		"TrafficLight": trafficLight,
+		"RunName":      runName,
+		"Result":       res,
+		"Report":       r,
+	}
+
+	var buffer bytes.Buffer
+
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		logger.Errorf("Failure on render HTML report. Error: %v", err)
+
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders the report as JUnit XML. Every executed request (or test
+// case) is a test case, failures and changed content are reported as
+// failure. Degraded latency is noted as system output only.
+func (r *Report) JUnit(runName string) ([]byte, error) {
+	suiteName := "apiprobe"
+	if runName != "" {
+		suiteName = runName
+	}
+
+	issues := make(map[string]Request, len(r.Requests))
+	for _, issue := range r.Requests {
+		issues[issue.ID+"\x00"+issue.TestCase] = issue
+	}
+
+	suite := junitTestSuite{
+		Name:      suiteName,
+		Tests:     0,
+		Failures:  0,
+		Time:      "",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		TestCases: make([]junitTestCase, 0, len(r.Timings)),
+	}
+
+	var totalMs float64
+
+	for _, timing := range r.Timings {
+		key := timing.ID + "\x00" + timing.TestCase
+		issue, hasIssue := issues[key]
+		delete(issues, key)
+
+		testCase := newJUnitTestCase(timing.ID, timing.Method, timing.Endpoint, timing.TestCase, timing.Timings.TotalMs)
+		if hasIssue {
+			applyJUnitIssue(&testCase, issue)
+		}
+
+		totalMs += timing.Timings.TotalMs
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	// Issues without timings (e.g. request errors before a response).
+	for _, issue := range r.Requests {
+		if _, exists := issues[issue.ID+"\x00"+issue.TestCase]; !exists {
+			continue
+		}
+
+		testCase := newJUnitTestCase(issue.ID, issue.Method, issue.Endpoint, issue.TestCase, 0)
+		applyJUnitIssue(&testCase, issue)
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	for _, testCase := range suite.TestCases {
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	suite.Tests = len(suite.TestCases)
+	suite.Time = formatJUnitSeconds(totalMs)
+
+	suites := junitTestSuites{
+		XMLName:  xml.Name{Space: "", Local: "testsuites"},
+		Name:     config.Version,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "    ")
+	if err != nil {
+		logger.Errorf("Failure on marshal JUnit report. Error: %v", err)
+
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+// newJUnitTestCase returns a (passed) JUnit test case of the request.
+func newJUnitTestCase(id string, method string, endpoint string, testCaseName string, totalMs float64) junitTestCase {
+	name := fmt.Sprintf("%s %s", method, endpoint)
+	if testCaseName != "" {
+		name += " - " + testCaseName
+	}
+
+	return junitTestCase{
+		Name:      name,
+		ClassName: id,
+		Time:      formatJUnitSeconds(totalMs),
+		Failure:   nil,
+		SystemOut: "",
+	}
+}
+
+// applyJUnitIssue marks the test case as failed (failures and changed
+// content) or adds a note (degraded latency).
+func applyJUnitIssue(testCase *junitTestCase, issue Request) {
+	if !issue.IsFailure() && issue.Issue != IssueChanged {
+		testCase.SystemOut = fmt.Sprintf("latency %s", issue.Latency)
+
+		return
+	}
+
+	message := issue.Issue
+	if issue.StatusCode != "" {
+		message += ", status code " + issue.StatusCode
+	}
+
+	if issue.Latency != "" {
+		message += ", latency " + issue.Latency
+	}
+
+	text := issue.ErrorResponse
+	if issue.Issue == IssueChanged {
+		text = "Response content changed, see " + issue.OutputFile
+	}
+
+	testCase.Failure = &junitFailure{Message: message, Type: issue.Issue, Text: text}
+}
+
+// formatJUnitSeconds formats milliseconds as seconds (JUnit time attribute).
+func formatJUnitSeconds(milliseconds float64) string {
+	const millisecondsPerSecond = 1000
+
+	return fmt.Sprintf("%.3f", milliseconds/millisecondsPerSecond)
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// TemplateData is passed to the payload template of a generic webhook.
+type TemplateData struct {
+	Kind       string
+	Result     *Result
+	Report     *Report
+	RunName    string
+	Hostname   string
+	Version    string
+	ReportFile string
+}
+
+// genericNotifier sends user-defined payloads (rendered from a text/template
+// file) to any HTTP endpoint.
+type genericNotifier struct {
+	cfg          *config.GenericWebhook
+	templateFile string
+}
+
+// Name returns the configured name of the generic webhook.
+func (n *genericNotifier) Name() string {
+	if n.cfg.Name != "" {
+		return n.cfg.Name
+	}
+
+	return "Generic webhook"
+}
+
+// Deliveries renders the payload template of the generic webhook with the
+// notice (see TemplateData.Kind) for the webhook URL of the channel, using
+// the configured HTTP method and headers.
+func (n *genericNotifier) Deliveries(notice *Notice, channelName string) ([]Delivery, error) {
+	webhookURL, exists := n.cfg.Webhooks[channelName]
+	if !exists {
+		logger.Warnf(n.Name()+" webhook channel '%s' not found in config. No notification sent.", channelName)
+
+		return nil, nil
+	}
+
+	data := TemplateData{
+		Kind:       notice.Kind,
+		Result:     notice.Result,
+		Report:     notice.Report,
+		RunName:    notice.RunName,
+		Hostname:   notice.Hostname,
+		Version:    config.Version,
+		ReportFile: notice.ReportFilePath,
+	}
+
+	webhookPayload, err := buildGenericPayload(n.templateFile, data)
+	if err != nil {
+		return nil, err
+	}
+
+	method := strings.ToUpper(n.cfg.Method)
+
+	return []Delivery{newHTTPDelivery(n.Name(), method, webhookURL, n.cfg.Headers, webhookPayload)}, nil
+}
+
+// buildGenericPayload renders the text/template file with the template data.
+// Besides the built-in functions, the template can use 'json' (value as JSON,
+// e.g. for quoted strings) and 'markdown' (slowest endpoints and recoveries
+// as markdown list). Returns the payload as a byte slice.
+func buildGenericPayload(templateFile string, data TemplateData) ([]byte, error) {
+	funcs := template.FuncMap{
+		"json": func(value any) (string, error) {
+			encoded, err := json.Marshal(value)
+
+			return string(encoded), err
+		},
+		"markdown": func(rep *Report) string {
+			return buildSlowestRequestsMarkdown(rep) + buildRecoveriesMarkdown(rep)
+		},
+	}
+
+	content, err := os.ReadFile(templateFile)
+	if err != nil {
+		logger.Errorf(`Failed to read payload template "%s". Error: %v`, templateFile, err)
+
+		return nil, err
+	}
+
+	tmpl, err := template.New(templateFile).Funcs(funcs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		logger.Errorf(`Failed to parse payload template "%s". Error: %v`, templateFile, err)
+
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		logger.Errorf(`Failed to render payload template "%s". Error: %v`, templateFile, err)
+
+		return nil, fmt.Errorf("render payload template: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package report_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+
+	templateFile := filepath.Join(t.TempDir(), "payload.tmpl")
+
+	if err := os.WriteFile(templateFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	return templateFile
+}
+
+func TestBuildGenericPayload(t *testing.T) {
+	const content = `{"kind":{{ json .Kind }},"run":{{ json .RunName }},"errors":{{ .Result.RequestErrorCount }},` +
+		`"issues":[{{ range $i, $r := .Report.Requests }}{{ if $i }},{{ end }}{{ json $r.ID }}{{ end }}],` +
+		`"text":{{ json (markdown .Report) }}}`
+
+	res, rep := failureReport()
+	data := report.TemplateData{
+		Kind:       "report",
+		Result:     res,
+		Report:     rep,
+		RunName:    `night "ly"`,
+		Hostname:   "probe-01",
+		Version:    "v1.0.0",
+		ReportFile: "./reports/report.json",
+	}
+
+	payload, err := report.BuildGenericPayload(writeTemplate(t, content), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := string(payload)
+
+	for _, want := range []string{
+		`{"kind":"report","run":"night \"ly\"","errors":1,"issues":["0f1e2d3c4b","ab12cd34ef"],"text":"`,
+		`POST /orders`,
+		`POST /carts`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("payload %s does not contain %s", got, want)
+		}
+	}
+}
+
+func TestBuildGenericPayloadExecutionError(t *testing.T) {
+	templateFile := writeTemplate(t, `{"count":{{ .Result.RequestErrorCount }}}`)
+
+	// The nil result cannot be dereferenced while the template is executed.
+	_, err := report.BuildGenericPayload(templateFile, report.TemplateData{Kind: "report"})
+
+	if err == nil || !strings.Contains(err.Error(), "render payload template") {
+		t.Fatalf("expected a render error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// update rewrites the golden files by the current output (go test -update).
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// assertGolden compares the (indented) JSON payload with the golden file in
+// testdata. The version is replaced by "<version>" to keep the golden files
+// stable across releases.
+func assertGolden(t *testing.T, name string, payload []byte) {
+	t.Helper()
+
+	var indented bytes.Buffer
+
+	if err := json.Indent(&indented, payload, "", "  "); err != nil {
+		t.Fatalf("invalid JSON payload: %v", err)
+	}
+
+	got := strings.ReplaceAll(indented.String(), config.Version, "<version>") + "\n"
+	goldenFile := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.WriteFile(goldenFile, []byte(got), 0o600); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+
+	if got != string(want) {
+		t.Errorf("payload differs from %s (run go test -update to accept):\n%s", goldenFile, got)
+	}
+}
+
+// failureReport returns a result and report with a request error, a changed
+// file, a degraded latency and a recovery.
+func failureReport() (*report.Result, *report.Report) {
+	users := &loader.APIRequest{ID: "0f1e2d3c4b", Tags: []string{"reqres"}}
+	users.Request.Method = "GET"
+	users.Request.Endpoint = "/users/23"
+	users.TestCases = []loader.TestCases{{Name: "unknown user"}}
+
+	orders := &loader.APIRequest{ID: "ab12cd34ef"}
+	orders.Request.Method = "POST"
+	orders.Request.Endpoint = "/orders"
+	orders.MaxDurationMs = loader.DurationThresholds{Degraded: 500, Failed: 0}
+
+	usersTimings := &report.Timings{DNSMs: 1, ConnectMs: 2, TLSMs: 3, TTFBMs: 40, TotalMs: 120}
+	ordersTimings := &report.Timings{DNSMs: 1, ConnectMs: 2, TLSMs: 3, TTFBMs: 600, TotalMs: 650}
+
+	rep := &report.Report{RunID: 7}
+	rep.AddReportData(users, report.IssueRequestError, "404", `{"error":"not found"}`, "", 0, usersTimings)
+	rep.AddReportData(orders, report.IssueChanged, "201", "", "./data/output/orders-test-case-00.json", -1, ordersTimings)
+	rep.AddTiming(users, *usersTimings, 0)
+	rep.AddTiming(orders, *ordersTimings, -1)
+	rep.Recoveries = []report.Recovery{
+		{ID: "5ce7a1f0b2", Endpoint: "/carts", Method: "POST", Tags: nil, TestCase: "", Failures: 3},
+	}
+
+	res := &report.Result{RequestErrorCount: 1, ChangedFilesCount: 1, LatencyDegradedCount: 1}
+
+	return res, rep
+}
+
+// recoveryReport returns a report with recovered endpoints only.
+func recoveryReport() *report.Report {
+	return &report.Report{
+		RunID: 8,
+		Recoveries: []report.Recovery{
+			{ID: "0f1e2d3c4b", Endpoint: "/users/23", Method: "GET", Tags: nil, TestCase: "unknown user", Failures: 1},
+			{ID: "5ce7a1f0b2", Endpoint: "/carts", Method: "POST", Tags: nil, TestCase: "", Failures: 4},
+		},
+	}
+}
@@ -1,83 +1,304 @@
 package report
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
-	"os"
 
 	"github.com/sven-seyfert/apiprobe/internal/config"
-	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
 
-	"zombiezen.com/go/sqlite"
+// Adaptive Card styles and colors (by severity).
+const (
+	cardStyleAttention = "attention"
+	cardStyleWarning   = "warning"
+	cardStyleGood      = "good"
+	cardStyleAccent    = "accent"
 )
 
-// sendMSTeamsNotifications sends a notification to MS Teams based on the result and report.
-// It sends either a heartbeat or a report payload depending on the result counts.
-func sendMSTeamsNotifications(
-	ctx context.Context,
-	cfg *config.Config,
-	conn *sqlite.Conn,
+// msTeamsNotifier sends Adaptive Cards to MS Teams (Power Automate
+// Workflows) webhooks.
+type msTeamsNotifier struct {
+	webhooks map[string]string
+}
+
+// Name returns the name of the notification tool.
+func (n *msTeamsNotifier) Name() string {
+	return "MS Teams"
+}
+
+// Deliveries returns the heartbeat, recovery or report card
+// (depending on the notice kind) for the webhook of the channel.
+func (n *msTeamsNotifier) Deliveries(notice *Notice, channelName string) ([]Delivery, error) {
+	hostnameMessage := fmt.Sprintf("Message from: **%s** (hostname)", notice.Hostname)
+
+	var webhookPayload []byte
+
+	switch notice.Kind {
+	case KindHeartbeat:
+		webhookPayload = buildMSTeamsHeartbeatPayload(hostnameMessage)
+	case KindRecovery:
+		webhookPayload = buildMSTeamsRecoveryPayload(notice.Report, notice.RunName, hostnameMessage)
+	default:
+		webhookPayload = buildMSTeamsReportPayload(
+			notice.Result, notice.Report, notice.RunName, notice.ReportFilePath, hostnameMessage)
+	}
+
+	return webhookDeliveries(n.Name(), n.webhooks, channelName, webhookPayload), nil
+}
+
+// buildMSTeamsHeartbeatPayload creates the Adaptive Card payload for a
+// heartbeat notification. Returns the payload as a byte slice.
+func buildMSTeamsHeartbeatPayload(hostnameMessage string) []byte {
+	body := []any{
+		cardTitle("💙 "+config.Version, cardStyleAccent),
+		cardText("Heartbeat: **still alive**"),
+		cardText(hostnameMessage),
+	}
+
+	return buildAdaptiveCardMessage(body)
+}
+
+// buildMSTeamsReportPayload creates the Adaptive Card payload for a report
+// notification: a facts table of the result, one section per issue (colored
+// by severity), recoveries and the slowest endpoints. Returns the payload as
+// a byte slice.
+func buildMSTeamsReportPayload(
 	res *Result,
 	rep *Report,
 	runName string,
-	channelName string,
-) {
-	const notificationTool = "MS Teams"
+	reportFilePath string,
+	hostnameMessage string,
+) []byte {
+	style := cardStyleAttention
+	trafficLight := "🔴"
+
+	if !res.HasFailures() {
+		style = cardStyleWarning
+		trafficLight = "🟡"
+	}
 
-	webhookURL, exists := cfg.Notification.MSTeams.Webhooks[channelName]
-	if !exists {
-		logger.Warnf(notificationTool+" webhook channel '%s' not found in config. No notification sent.", channelName)
+	body := []any{cardTitle(trafficLight+" "+config.Version, style)}
 
-		return
+	if runName != "" {
+		body = append(body, cardText("`"+runName+"`"))
 	}
 
-	reportFilePath := buildReportFilePath()
-	hostname, _ := os.Hostname()
-	hostnameMessage := fmt.Sprintf("Message from: __%s__ (hostname)", hostname)
+	body = append(body, cardFactSet([][2]string{
+		{"Files with changed content", fmt.Sprint(res.ChangedFilesCount)},
+		{"Request errors", fmt.Sprint(res.RequestErrorCount)},
+		{"Format response errors", fmt.Sprint(res.FormatResponseErrorCount)},
+		{"Latency failed", fmt.Sprint(res.LatencyFailedCount)},
+		{"Latency degraded", fmt.Sprint(res.LatencyDegradedCount)},
+		{"Hook errors", fmt.Sprint(res.HookErrorCount)},
+		{"Report file", reportFilePath},
+	}))
+
+	body = append(body, buildMSTeamsIssueSections(rep)...)
+	body = append(body, buildMSTeamsRecoverySection(rep)...)
+	body = append(body, buildMSTeamsSlowestSection(rep)...)
+	body = append(body, cardText(hostnameMessage))
+
+	return buildAdaptiveCardMessage(body)
+}
+
+// buildMSTeamsRecoveryPayload creates the Adaptive Card payload for a recovery
+// notification, sent if endpoints recovered and no other issues exist.
+// Returns the payload as a byte slice.
+func buildMSTeamsRecoveryPayload(rep *Report, runName string, hostnameMessage string) []byte {
+	body := []any{cardTitle("🟢 "+config.Version, cardStyleGood)}
+
+	if runName != "" {
+		body = append(body, cardText("`"+runName+"`"))
+	}
+
+	body = append(body, buildMSTeamsRecoverySection(rep)...)
+	body = append(body, cardText(hostnameMessage))
+
+	return buildAdaptiveCardMessage(body)
+}
+
+// buildMSTeamsIssueSections returns one container per issue, styled by the
+// severity of the issue. The number of sections is limited to keep the
+// card below the message size limit of MS Teams.
+func buildMSTeamsIssueSections(rep *Report) []any {
+	const (
+		maxIssueSections      = 20
+		maxErrorResponseChars = 500
+	)
+
+	sections := make([]any, 0, len(rep.Requests))
+
+	for idx, issue := range rep.Requests {
+		if idx == maxIssueSections {
+			sections = append(sections, cardText(fmt.Sprintf(
+				"... and %d more issues, see report file.", len(rep.Requests)-maxIssueSections)))
+
+			break
+		}
+
+		style := cardStyleWarning
+		if issue.IsFailure() {
+			style = cardStyleAttention
+		}
+
+		facts := [][2]string{
+			{"ID", issue.ID},
+			{"Test case", issue.TestCase},
+			{"Status code", issue.StatusCode},
+			{"Output file", issue.OutputFile},
+		}
 
-	if res.RequestErrorCount == 0 && res.FormatResponseErrorCount == 0 && res.ChangedFilesCount == 0 {
-		webhookPayload := buildMSTeamsHeartbeatPayload(reportFilePath, cfg, hostnameMessage)
-		if webhookPayload != nil {
-			sendNotification(ctx, conn, webhookURL, webhookPayload, notificationTool)
+		if issue.Timings != nil {
+			facts = append(facts, [2]string{"Duration", fmt.Sprintf("%.0fms", issue.Timings.TotalMs)})
 		}
 
-		return
+		if issue.Latency != "" {
+			facts = append(facts, [2]string{"Latency", issue.Latency})
+		}
+
+		if issue.Flakiness > 0 {
+			facts = append(facts, [2]string{"Flakiness", fmt.Sprintf("%.2f", issue.Flakiness)})
+		}
+
+		if issue.ErrorResponse != "" {
+			facts = append(facts, [2]string{"Error response", truncateText(issue.ErrorResponse, maxErrorResponseChars)})
+		}
+
+		sections = append(sections, map[string]any{
+			"type":      "Container",
+			"style":     style,
+			"separator": true,
+			"items": []any{
+				cardText(fmt.Sprintf("**%s** `%s %s`", issue.Issue, issue.Method, issue.Endpoint)),
+				cardFactSet(facts),
+			},
+		})
 	}
 
-	if err := rep.SaveToFile(reportFilePath); err != nil {
-		logger.Errorf("Error on save file. Error: %v", err)
+	return sections
+}
 
-		return
+// buildMSTeamsRecoverySection returns the recovered endpoints as
+// (good styled) container, or nothing if there are none.
+func buildMSTeamsRecoverySection(rep *Report) []any {
+	if len(rep.Recoveries) == 0 {
+		return nil
 	}
 
-	data, err := os.ReadFile(reportFilePath)
-	if err != nil {
-		logger.Errorf("Error on read file. Error: %v", err)
+	facts := make([][2]string, 0, len(rep.Recoveries))
 
-		return
+	for _, recovery := range rep.Recoveries {
+		facts = append(facts, [2]string{
+			fmt.Sprintf("✅ %s %s", recovery.Method, recovery.Endpoint),
+			fmt.Sprintf("back after %d failed runs (%s)", recovery.Failures, recovery.ID),
+		})
 	}
 
-	webhookPayload := buildMSTeamsReportPayload(res, runName, reportFilePath, data, hostnameMessage)
+	return []any{map[string]any{
+		"type":      "Container",
+		"style":     cardStyleGood,
+		"separator": true,
+		"items":     []any{cardText("**Recovered endpoints**"), cardFactSet(facts)},
+	}}
+}
+
+// buildMSTeamsSlowestSection returns the slowest endpoints as facts,
+// or nothing if no timings exist.
+func buildMSTeamsSlowestSection(rep *Report) []any {
+	const slowestRequestsCount = 3
+
+	slowest := rep.SlowestRequests(slowestRequestsCount)
+	if len(slowest) == 0 {
+		return nil
+	}
 
-	sendNotification(ctx, conn, webhookURL, webhookPayload, notificationTool)
+	facts := make([][2]string, 0, len(slowest))
+
+	for _, timing := range slowest {
+		value := fmt.Sprintf("%.0fms", timing.Timings.TotalMs)
+		if timing.Latency != "" {
+			value += " (" + timing.Latency + ")"
+		}
+
+		facts = append(facts, [2]string{timing.Method + " " + timing.Endpoint, value})
+	}
+
+	return []any{cardText("**Slowest endpoints**"), cardFactSet(facts)}
+}
+
+// buildAdaptiveCardMessage wraps the card body elements into an Adaptive Card
+// message, as expected by Power Automate Workflows webhooks.
+// Returns the payload as a byte slice.
+func buildAdaptiveCardMessage(body []any) []byte {
+	payload := map[string]any{
+		"type": "message",
+		"attachments": []any{
+			map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"contentUrl":  nil,
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"msteams": map[string]any{"width": "Full"},
+					"body":    body,
+				},
+			},
+		},
+	}
+
+	webhookPayload, _ := json.Marshal(payload)
+
+	return webhookPayload
+}
+
+// cardTitle returns a large, bold TextBlock within a container of the given style.
+func cardTitle(text string, style string) map[string]any {
+	return map[string]any{
+		"type":  "Container",
+		"style": style,
+		"bleed": true,
+		"items": []any{
+			map[string]any{
+				"type":   "TextBlock",
+				"text":   text,
+				"size":   "Large",
+				"weight": "Bolder",
+				"wrap":   true,
+			},
+		},
+	}
+}
+
+// cardText returns a wrapping TextBlock (markdown subset supported).
+func cardText(text string) map[string]any {
+	return map[string]any{
+		"type": "TextBlock",
+		"text": text,
+		"wrap": true,
+	}
 }
 
-func buildMSTeamsHeartbeatPayload(reportFilePath string, cfg *config.Config, hostnameMessage string) []byte {
-	// TODO
-	fmt.Println(reportFilePath)
-	fmt.Println(cfg)
-	fmt.Println(hostnameMessage)
+// cardFactSet returns a FactSet of title/value pairs.
+func cardFactSet(facts [][2]string) map[string]any {
+	cardFacts := make([]any, 0, len(facts))
 
-	return nil
+	for _, fact := range facts {
+		cardFacts = append(cardFacts, map[string]any{"title": fact[0], "value": fact[1]})
+	}
+
+	return map[string]any{
+		"type":  "FactSet",
+		"facts": cardFacts,
+	}
 }
 
-func buildMSTeamsReportPayload(res *Result, runName string, reportFilePath string, data []byte, hostnameMessage string) []byte {
-	// TODO
-	fmt.Println(res)
-	fmt.Println(runName)
-	fmt.Println(reportFilePath)
-	fmt.Println(data)
-	fmt.Println(hostnameMessage)
+// truncateText shortens text to maxChars characters (runes).
+func truncateText(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
 
-	return nil
+	return string(runes[:maxChars]) + "..."
 }
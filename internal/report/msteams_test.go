@@ -0,0 +1,23 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+const testHostnameMessage = "Message from: **probe-01** (hostname)"
+
+func TestMSTeamsReportCard(t *testing.T) {
+	res, rep := failureReport()
+
+	payload := report.BuildMSTeamsReportPayload(res, rep, "nightly", "./reports/report.json", testHostnameMessage)
+
+	assertGolden(t, "msteams-report.golden.json", payload)
+}
+
+func TestMSTeamsRecoveryCard(t *testing.T) {
+	payload := report.BuildMSTeamsRecoveryPayload(recoveryReport(), "nightly", testHostnameMessage)
+
+	assertGolden(t, "msteams-recovery.golden.json", payload)
+}
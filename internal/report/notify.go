@@ -1,10 +1,11 @@
 package report
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,8 +17,44 @@ import (
 	"zombiezen.com/go/sqlite"
 )
 
-// Notification sends summary notifications via WebEx and MS Teams webhooks.
-// It selects the notification channel and triggers the appropriate send function.
+// Kinds of notifications (see Notice).
+const (
+	KindReport    = "report"
+	KindHeartbeat = "heartbeat"
+	KindRecovery  = "recovery"
+)
+
+// Notice is the content of a notification, shared by all notifiers.
+type Notice struct {
+	Kind           string
+	Result         *Result
+	Report         *Report
+	RunName        string
+	Hostname       string
+	ReportFilePath string
+}
+
+// Notifier is a notification tool (like WebEx, MS Teams, Slack or email).
+// It builds the messages of a notice, which are delivered (with retries)
+// and spooled on failure by the caller.
+type Notifier interface {
+	// Name returns the name of the notification tool.
+	Name() string
+
+	// Deliveries returns the messages of the notice for the channel,
+	// or none if the channel is not configured.
+	Deliveries(notice *Notice, channelName string) ([]Delivery, error)
+}
+
+// ErrDeliveryFailed is returned if at least one notification could not be delivered.
+var ErrDeliveryFailed = errors.New("notification delivery failed")
+
+// Notification sends summary notifications via all active notifiers (WebEx,
+// MS Teams, Slack, email and generic webhooks). It applies the alert policy,
+// routes the issues to the channels (see config.Route), decides the kind of
+// notification per channel (report, heartbeat or recovery), delivers
+// the messages together with the spooled ones of previous runs and spools
+// the undelivered messages. Returns ErrDeliveryFailed if any delivery failed.
 func Notification(
 	ctx context.Context,
 	cfg *config.Config,
@@ -26,69 +63,194 @@ func Notification(
 	rep *Report,
 	runName string,
 	notifyChannel string,
-) {
+) error {
 	if notifyChannel == "" {
 		notifyChannel = "default"
 	}
 
-	if cfg.Notification.WebEx != nil && cfg.Notification.WebEx.Active {
-		sendWebExNotifications(ctx, cfg, conn, res, rep, runName, notifyChannel)
+	// Suppress failures by the alert policy (run history) and collect recoveries.
+	res, rep = applyAlertPolicy(cfg, conn, res, rep)
+
+	sender := newDeliverySender(cfg, conn)
+	deliveries := loadSpool(cfg.Locations.Spool, cfg.Notification.Delivery)
+
+	// The report file contains all issues, regardless of the routing.
+	reportFilePath := ""
+
+	if res.HasIssues() {
+		reportFilePath = buildReportFilePath(cfg.Locations.Reports)
+		if err := rep.SaveToFile(reportFilePath); err != nil {
+			logger.Errorf("Error on save file. Error: %v", err)
+		}
+	}
+
+	// Heartbeats are sent to the default channel only, if nothing else is notified.
+	isHeartbeat := false
+	allowHeartbeat := !res.HasIssues() && len(rep.Recoveries) == 0
+
+	for _, routed := range routeReport(cfg.Notification.Routes, res, rep, notifyChannel) {
+		notice := buildNotice(cfg, routed.result, routed.report, runName, reportFilePath,
+			allowHeartbeat && routed.channel == notifyChannel)
+		if notice == nil {
+			continue
+		}
+
+		isHeartbeat = isHeartbeat || notice.Kind == KindHeartbeat
+
+		for _, notifier := range buildNotifiers(cfg) {
+			notifierDeliveries, err := notifier.Deliveries(notice, routed.channel)
+			if err != nil {
+				logger.Errorf("Failed to build %s notification. Error: %v", notifier.Name(), err)
+
+				continue
+			}
+
+			deliveries = append(deliveries, notifierDeliveries...)
+		}
+	}
+
+	failed := 0
+
+	for idx := range deliveries {
+		if err := sender.deliver(ctx, &deliveries[idx]); err != nil {
+			failed++
+
+			spoolDelivery(cfg.Locations.Spool, &deliveries[idx], err)
+		}
+	}
+
+	// The heartbeat time is updated once, after all notifiers had
+	// the chance to send their heartbeat.
+	if isHeartbeat {
+		_ = UpdateHeartbeatTime(cfg)
 	}
 
-	if cfg.Notification.MSTeams != nil && cfg.Notification.MSTeams.Active {
-		sendMSTeamsNotifications(ctx, cfg, conn, res, rep, runName, notifyChannel)
+	if failed > 0 {
+		logger.Errorf("%d of %d notifications could not be delivered.", failed, len(deliveries))
+
+		return ErrDeliveryFailed
 	}
+
+	return nil
 }
 
-// buildReportFilePath generates a timestamped JSON file path for saving reports.
-// The format is ./reports/YYYY-MM-DD-HH-MM-SS.mmm.json.
-func buildReportFilePath() string {
-	const reportsPath = "./reports"
-	const ext = "json"
+// buildNotifiers returns the active notifiers of the config.
+func buildNotifiers(cfg *config.Config) []Notifier {
+	notification := cfg.Notification
 
-	now := time.Now()
-	timestamp := now.Format("2006-01-02-15-04-05.000")
+	var notifiers []Notifier
+
+	if notification.WebEx != nil && notification.WebEx.Active {
+		notifiers = append(notifiers, &webExNotifier{webhooks: notification.WebEx.Webhooks})
+	}
 
-	return fmt.Sprintf("%s/%s.%s", reportsPath, timestamp, ext)
+	if notification.MSTeams != nil && notification.MSTeams.Active {
+		notifiers = append(notifiers, &msTeamsNotifier{webhooks: notification.MSTeams.Webhooks})
+	}
+
+	if notification.Slack != nil && notification.Slack.Active {
+		notifiers = append(notifiers, &slackNotifier{webhooks: notification.Slack.Webhooks})
+	}
+
+	if notification.Email != nil && notification.Email.Active {
+		notifiers = append(notifiers, &emailNotifier{cfg: notification.Email})
+	}
+
+	for idx := range notification.Generic {
+		if notification.Generic[idx].Active {
+			notifiers = append(notifiers, &genericNotifier{
+				cfg:          &notification.Generic[idx],
+				templateFile: cfg.Locations.TemplateFile(cfg.Workspace, notification.Generic[idx].PayloadTemplate),
+			})
+		}
+	}
+
+	return notifiers
 }
 
-// sendNotification sends the given JSON payload to the configured incoming webhook URL.
-// It handles secret replacement in the webhook URL and logs the result.
-func sendNotification(
-	ctx context.Context,
-	conn *sqlite.Conn,
-	webhookURL string,
-	webhookPayload []byte,
-	notificationTool string,
-) {
-	const secretPrefix = "<secret-"
-	const secretSuffix = ">"
+// buildNotice decides the kind of notification for the (routed) result and
+// report: a report if issues exist, a recovery if endpoints recovered,
+// otherwise a heartbeat if allowed and the heartbeat interval elapsed.
+// Returns nil if nothing is to be notified.
+func buildNotice(
+	cfg *config.Config,
+	res *Result,
+	rep *Report,
+	runName string,
+	reportFilePath string,
+	allowHeartbeat bool,
+) *Notice {
+	hostname, _ := os.Hostname()
 
-	url := webhookURL
+	notice := &Notice{
+		Kind:           KindReport,
+		Result:         res,
+		Report:         rep,
+		RunName:        runName,
+		Hostname:       hostname,
+		ReportFilePath: reportFilePath,
+	}
 
-	if strings.Contains(webhookURL, secretPrefix) {
-		urlSecret := crypto.ExtractSecretHash(webhookURL)
-		urlIdentifier, _ := db.SelectHash(conn, urlSecret)
-		webhookIdentifier := crypto.Deobfuscate(urlIdentifier)
-		url = strings.Replace(webhookURL, secretPrefix+urlSecret+secretSuffix, webhookIdentifier, 1)
+	switch {
+	case res.HasIssues():
+		return notice
+	case len(rep.Recoveries) > 0:
+		notice.Kind = KindRecovery
+		notice.ReportFilePath = ""
+	default:
+		if !allowHeartbeat {
+			return nil
+		}
+
+		isHeartbeatTime, err := IsHeartbeatTime(cfg)
+		if err != nil || !isHeartbeatTime {
+			return nil
+		}
+
+		notice.Kind = KindHeartbeat
+		notice.ReportFilePath = ""
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(webhookPayload))
-	if err != nil {
-		logger.Errorf("Error creating new request. Error: %v", err)
+	return notice
+}
+
+// webhookDeliveries returns the delivery of the payload to the webhook URL
+// of the channel, or none (with warning) if the channel is not configured.
+func webhookDeliveries(notificationTool string, webhooks map[string]string, channelName string, payload []byte) []Delivery {
+	webhookURL, exists := webhooks[channelName]
+	if !exists {
+		logger.Warnf(notificationTool+" webhook channel '%s' not found in config. No notification sent.", channelName)
 
-		return
+		return nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	return []Delivery{newHTTPDelivery(notificationTool, "", webhookURL, nil, payload)}
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		logger.Errorf("Error sending request. Error: %v", err)
+// buildReportFilePath generates a timestamped JSON file path for saving reports
+// in the reports directory. The format is ./reports/YYYY-MM-DD-HH-MM-SS.mmm.json.
+func buildReportFilePath(reportsDir string) string {
+	const ext = "json"
 
-		return
+	now := time.Now()
+	timestamp := now.Format("2006-01-02-15-04-05.000")
+
+	return filepath.Join(reportsDir, fmt.Sprintf("%s.%s", timestamp, ext))
+}
+
+// resolveSecret replaces a '<secret-<hash>>' placeholder in the value (like
+// a webhook URL or SMTP credential) by the deobfuscated secret of the database.
+func resolveSecret(conn *sqlite.Conn, value string) string {
+	const secretPrefix = "<secret-"
+	const secretSuffix = ">"
+
+	if !strings.Contains(value, secretPrefix) {
+		return value
 	}
-	defer resp.Body.Close()
 
-	logger.Infof(notificationTool+" notification sent successfully (status: %d)", resp.StatusCode)
+	secretHash := crypto.ExtractSecretHash(value)
+	identifier, _ := db.SelectHash(conn, secretHash)
+	secret := crypto.Deobfuscate(identifier)
+
+	return strings.Replace(value, secretPrefix+secretHash+secretSuffix, secret, 1)
 }
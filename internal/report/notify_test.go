@@ -0,0 +1,181 @@
+package report_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// newWorkspaceConfig returns an empty config with the paths of a temporary
+// workspace directory (no files written to the working directory).
+func newWorkspaceConfig(t *testing.T) (*config.Config, string) {
+	t.Helper()
+
+	workDir := t.TempDir()
+
+	cfg := &config.Config{}
+	cfg.Workspace = workDir
+	cfg.Locations = config.DefaultPaths(workDir)
+
+	return cfg, workDir
+}
+
+// changedReport returns a result and report with a single changed file.
+func changedReport() (*report.Result, *report.Report) {
+	req := &loader.APIRequest{ID: "0f1e2d3c4b"}
+	req.Request.Method = "GET"
+	req.Request.Endpoint = "/users"
+
+	rep := &report.Report{}
+	rep.AddReportData(req, report.IssueChanged, "200", "", "./data/output/users.json", -1, nil)
+
+	return &report.Result{ChangedFilesCount: 1}, rep
+}
+
+func TestUndeliveredNotificationIsSpooledAndRetried(t *testing.T) {
+	var available atomic.Bool
+
+	var received atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		if !available.Load() {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		received.Add(1)
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg, workDir := newWorkspaceConfig(t)
+	cfg.Notification.Delivery.Retries = -1
+	cfg.Notification.Slack = &struct {
+		Active   bool              `json:"active"`
+		Webhooks map[string]string `json:"webhooks"`
+	}{Active: true, Webhooks: map[string]string{"default": server.URL}}
+
+	res, rep := changedReport()
+
+	err := report.Notification(context.Background(), cfg, nil, res, rep, "", "")
+	if !errors.Is(err, report.ErrDeliveryFailed) {
+		t.Fatalf("expected delivery failure, got %v", err)
+	}
+
+	spooled, _ := filepath.Glob(filepath.Join(workDir, "spool", "*.json"))
+	if len(spooled) != 1 {
+		t.Fatalf("expected 1 spooled notification, got %d", len(spooled))
+	}
+
+	// Next run: the spooled and the new notification are delivered.
+	available.Store(true)
+
+	res, rep = changedReport()
+
+	if err = report.Notification(context.Background(), cfg, nil, res, rep, "", ""); err != nil {
+		t.Fatalf("notification: %v", err)
+	}
+
+	if received.Load() != 2 {
+		t.Errorf("expected 2 delivered notifications, got %d", received.Load())
+	}
+
+	spooled, _ = filepath.Glob(filepath.Join(workDir, "spool", "*.json"))
+	if len(spooled) != 0 {
+		t.Errorf("expected empty spool, got %d files", len(spooled))
+	}
+}
+
+func TestRejectedNotificationIsNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		writer.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	cfg, workDir := newWorkspaceConfig(t)
+	cfg.Notification.Slack = &struct {
+		Active   bool              `json:"active"`
+		Webhooks map[string]string `json:"webhooks"`
+	}{Active: true, Webhooks: map[string]string{"default": server.URL}}
+
+	res, rep := changedReport()
+
+	err := report.Notification(context.Background(), cfg, nil, res, rep, "", "")
+	if !errors.Is(err, report.ErrDeliveryFailed) {
+		t.Fatalf("expected delivery failure, got %v", err)
+	}
+
+	if attempts.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts.Load())
+	}
+
+	spooled, _ := filepath.Glob(filepath.Join(workDir, "spool", "*.json"))
+	if len(spooled) != 0 {
+		t.Errorf("expected no spooled notification, got %d", len(spooled))
+	}
+}
+
+func TestIssuesAreRoutedToChannels(t *testing.T) {
+	var mutex sync.Mutex
+
+	payloads := make(map[string]string)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		mutex.Lock()
+		payloads[req.URL.Path] = string(body)
+		mutex.Unlock()
+
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg, _ := newWorkspaceConfig(t)
+	cfg.Notification.Routes = []config.Route{{Tags: []string{"payments"}, Channels: []string{"payments"}}}
+	cfg.Notification.Slack = &struct {
+		Active   bool              `json:"active"`
+		Webhooks map[string]string `json:"webhooks"`
+	}{Active: true, Webhooks: map[string]string{"default": server.URL + "/default", "payments": server.URL + "/payments"}}
+
+	users := &loader.APIRequest{ID: "0f1e2d3c4b"}
+	users.Request.Method = "GET"
+	users.Request.Endpoint = "/users"
+
+	payments := &loader.APIRequest{ID: "a1b2c3d4e5", Tags: []string{"payments"}}
+	payments.Request.Method = "POST"
+	payments.Request.Endpoint = "/payments"
+
+	rep := &report.Report{}
+	rep.AddReportData(users, report.IssueChanged, "200", "", "./data/output/users.json", -1, nil)
+	rep.AddReportData(payments, report.IssueRequestError, "500", "", "./data/output/payments.json", -1, nil)
+
+	res := &report.Result{ChangedFilesCount: 1, RequestErrorCount: 1}
+
+	if err := report.Notification(context.Background(), cfg, nil, res, rep, "", ""); err != nil {
+		t.Fatalf("notification: %v", err)
+	}
+
+	if !strings.Contains(payloads["/payments"], "/payments") || strings.Contains(payloads["/payments"], "/users") {
+		t.Errorf("payments channel received unexpected issues: %s", payloads["/payments"])
+	}
+
+	if !strings.Contains(payloads["/default"], "/users") || strings.Contains(payloads["/default"], "/payments") {
+		t.Errorf("default channel received unexpected issues: %s", payloads["/default"])
+	}
+}
@@ -0,0 +1,209 @@
+package report
+
+import (
+	"zombiezen.com/go/sqlite"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/logger"
+)
+
+// Alert policy modes (see config.AlertPolicy).
+const (
+	PolicyAlways      = "always"
+	PolicyConsecutive = "consecutive"
+	PolicyTransitions = "transitions"
+)
+
+// Recovery is an endpoint which succeeded again after (notified) failures.
+type Recovery struct {
+	ID       string   `json:"id"`
+	Endpoint string   `json:"endpoint"`
+	Method   string   `json:"method"`
+	Tags     []string `json:"tags,omitempty"`
+	TestCase string   `json:"testCase,omitempty"`
+	Failures int      `json:"failures"`
+}
+
+// applyAlertPolicy evaluates the run history of every failure issue, sets its
+// flakiness score and removes the issues which should not be notified by the
+// configured alert policy. It also collects the recoveries of the run.
+// Returns the filtered Result and Report, the given ones stay untouched.
+func applyAlertPolicy(cfg *config.Config, conn *sqlite.Conn, res *Result, rep *Report) (*Result, *Report) {
+	policy := normalizeAlertPolicy(cfg.Notification.AlertPolicy)
+	filteredRes := *res
+	filteredRep := *rep
+	filteredRep.Requests = make([]Request, 0, len(rep.Requests))
+
+	for _, issue := range rep.Requests {
+		// Content changes and hook errors (no run history) are always notified,
+		// even with a failed latency.
+		if issue.Issue == IssueChanged || issue.Issue == IssueHookError || !issue.IsFailure() {
+			filteredRep.Requests = append(filteredRep.Requests, issue)
+
+			continue
+		}
+
+		states, err := db.SelectExecutionStates(conn, issue.ID, issue.testCaseIndex, policy.HistoryWindow)
+		if err != nil {
+			filteredRep.Requests = append(filteredRep.Requests, issue)
+
+			continue
+		}
+
+		issue.Flakiness = flakinessScore(states)
+		if issue.Flakiness >= policy.FlakyThreshold {
+			logger.Warnf(`Request "%s" (%s) is flaky, score %.2f.`, issue.ID, issue.Endpoint, issue.Flakiness)
+		}
+
+		if shouldAlert(policy, consecutiveFailures(states)) {
+			filteredRep.Requests = append(filteredRep.Requests, issue)
+
+			continue
+		}
+
+		logger.Infof(`Alert for request "%s" (%s) suppressed by alert policy "%s".`, issue.ID, issue.Endpoint, policy.Mode)
+		filteredRes.decreaseIssueCount(issue)
+	}
+
+	filteredRep.Recoveries = collectRecoveries(policy, conn, rep.RunID)
+
+	return &filteredRes, &filteredRep
+}
+
+// normalizeAlertPolicy returns the policy with defaults for unset values.
+func normalizeAlertPolicy(policy config.AlertPolicy) config.AlertPolicy {
+	const (
+		defaultConsecutiveFailures = 3
+		defaultHistoryWindow       = 20
+		defaultFlakyThreshold      = 0.3
+	)
+
+	if policy.Mode == "" {
+		policy.Mode = PolicyAlways
+	}
+
+	if policy.ConsecutiveFailures <= 0 {
+		policy.ConsecutiveFailures = defaultConsecutiveFailures
+	}
+
+	if policy.HistoryWindow <= 1 {
+		policy.HistoryWindow = defaultHistoryWindow
+	}
+
+	if policy.FlakyThreshold <= 0 {
+		policy.FlakyThreshold = defaultFlakyThreshold
+	}
+
+	return policy
+}
+
+// shouldAlert decides by the policy mode and the number of consecutive
+// failures (including the current run) whether a failure is notified.
+func shouldAlert(policy config.AlertPolicy, failures int) bool {
+	switch policy.Mode {
+	case PolicyConsecutive:
+		return failures >= policy.ConsecutiveFailures
+	case PolicyTransitions:
+		return failures == 1
+	default:
+		return true
+	}
+}
+
+// collectRecoveries returns the requests of the run which succeeded after
+// a failure streak which has been notified by the policy before.
+func collectRecoveries(policy config.AlertPolicy, conn *sqlite.Conn, runID int64) []Recovery {
+	if runID == 0 {
+		return nil
+	}
+
+	executions, err := db.SelectRunExecutions(conn, runID)
+	if err != nil {
+		return nil
+	}
+
+	var recoveries []Recovery
+
+	for _, execution := range executions {
+		if execution.IsFailure() {
+			continue
+		}
+
+		states, stateErr := db.SelectExecutionStates(conn, execution.RequestID, execution.TestCaseIndex, policy.HistoryWindow)
+		if stateErr != nil || len(states) < 2 {
+			continue
+		}
+
+		// Failure streak before the current (successful) execution.
+		failures := consecutiveFailures(states[1:])
+		if failures == 0 {
+			continue
+		}
+
+		if policy.Mode == PolicyConsecutive && failures < policy.ConsecutiveFailures {
+			continue
+		}
+
+		logger.Infof(`Request "%s" (%s) recovered after %d failures.`, execution.RequestID, execution.Endpoint, failures)
+
+		recoveries = append(recoveries, Recovery{
+			ID:       execution.RequestID,
+			Endpoint: execution.Endpoint,
+			Method:   execution.Method,
+			Tags:     execution.Tags,
+			TestCase: execution.TestCase,
+			Failures: failures,
+		})
+	}
+
+	return recoveries
+}
+
+// consecutiveFailures counts the failures in a row, starting at the newest state.
+func consecutiveFailures(states []db.ExecutionState) int {
+	count := 0
+
+	for idx := range states {
+		if !states[idx].IsFailure() {
+			break
+		}
+
+		count++
+	}
+
+	return count
+}
+
+// flakinessScore returns the share of state changes (success to failure and
+// vice versa) between consecutive executions: 0 is stable, 1 flips every run.
+func flakinessScore(states []db.ExecutionState) float64 {
+	if len(states) < 2 { //nolint:mnd
+		return 0
+	}
+
+	flips := 0
+
+	for idx := 1; idx < len(states); idx++ {
+		if states[idx].IsFailure() != states[idx-1].IsFailure() {
+			flips++
+		}
+	}
+
+	return float64(flips) / float64(len(states)-1)
+}
+
+// decreaseIssueCount decrements the Result counter matching the issue type.
+func (res *Result) decreaseIssueCount(issue Request) {
+	switch issue.Issue {
+	case IssueRequestError:
+		res.RequestErrorCount--
+	case IssueFormatError:
+		res.FormatResponseErrorCount--
+	}
+
+	// Request errors are not counted as latency failures (see exec package).
+	if issue.Issue != IssueRequestError && issue.Latency == LatencyFailed {
+		res.LatencyFailedCount--
+	}
+}
@@ -0,0 +1,198 @@
+package report_test
+
+import (
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+	"github.com/sven-seyfert/apiprobe/internal/db"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+// states returns the execution states of the statuses (newest first), where
+// 'f' is a request error, 'l' a failed latency and 's' a success.
+func states(statuses string) []db.ExecutionState {
+	result := make([]db.ExecutionState, 0, len(statuses))
+
+	for _, status := range statuses {
+		state := db.ExecutionState{Status: db.StatusSuccess} //nolint:exhaustruct
+
+		switch status {
+		case 'f':
+			state.Status = db.StatusRequestError
+		case 'l':
+			state.Latency = report.LatencyFailed
+		}
+
+		result = append(result, state)
+	}
+
+	return result
+}
+
+func TestFlakinessScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses string
+		want     float64
+	}{
+		{"no history", "", 0},
+		{"single execution", "f", 0},
+		{"stable success", "ssss", 0},
+		{"stable failure", "ffff", 0},
+		{"single flip", "fsss", 1.0 / 3},
+		{"flips every run", "fsfs", 1},
+		{"failed latency counts as failure", "lsls", 1},
+		{"full window", "ffffffffffssssssssss", 1.0 / 19},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := report.FlakinessScore(states(tc.statuses)); got != tc.want {
+				t.Errorf("expected score %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestConsecutiveFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses string
+		want     int
+	}{
+		{"no history", "", 0},
+		{"newest succeeded", "sfff", 0},
+		{"streak from newest", "ffsf", 2},
+		{"failed latency", "lfs", 2},
+		{"only failures", "fff", 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := report.ConsecutiveFailures(states(tc.statuses)); got != tc.want {
+				t.Errorf("expected %d failures, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestShouldAlert(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		failures int
+		want     bool
+	}{
+		{"always first failure", report.PolicyAlways, 1, true},
+		{"always later failure", report.PolicyAlways, 5, true},
+		{"consecutive below threshold", report.PolicyConsecutive, 2, false},
+		{"consecutive at threshold", report.PolicyConsecutive, 3, true},
+		{"consecutive above threshold", report.PolicyConsecutive, 4, true},
+		{"transitions first failure", report.PolicyTransitions, 1, true},
+		{"transitions ongoing failure", report.PolicyTransitions, 2, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := report.NormalizeAlertPolicy(config.AlertPolicy{Mode: tc.mode}) //nolint:exhaustruct
+
+			if got := report.ShouldAlert(policy, tc.failures); got != tc.want {
+				t.Errorf("expected %v for %d failures, got %v", tc.want, tc.failures, got)
+			}
+		})
+	}
+}
+
+func TestCollectRecoveries(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+
+		// Statuses of the previous runs (oldest first) and the current run.
+		history string
+		want    int
+	}{
+		{"first run without history", report.PolicyAlways, "s", 0},
+		{"success after success", report.PolicyAlways, "sss", 0},
+		{"recovery after one failure", report.PolicyAlways, "sfs", 1},
+		{"recovery after three failures", report.PolicyAlways, "fffs", 3},
+		{"consecutive below threshold", report.PolicyConsecutive, "sffs", 0},
+		{"consecutive at threshold", report.PolicyConsecutive, "fffs", 3},
+		{"current run failed", report.PolicyAlways, "fff", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := db.OpenMemory()
+			if err != nil {
+				t.Fatalf("database: %v", err)
+			}
+
+			defer conn.Close()
+
+			policy := report.NormalizeAlertPolicy(config.AlertPolicy{Mode: tc.mode}) //nolint:exhaustruct
+
+			var runID int64
+
+			for _, status := range states(tc.history) {
+				runID = insertExecution(t, conn, status.Status)
+			}
+
+			recoveries := report.CollectRecoveries(policy, conn, runID)
+
+			if tc.want == 0 {
+				if len(recoveries) != 0 {
+					t.Fatalf("expected no recovery, got %+v", recoveries)
+				}
+
+				return
+			}
+
+			if len(recoveries) != 1 || recoveries[0].ID != "0f1e2d3c4b" || recoveries[0].Failures != tc.want {
+				t.Fatalf("expected recovery after %d failures, got %+v", tc.want, recoveries)
+			}
+		})
+	}
+}
+
+func TestCollectRecoveriesWithoutRun(t *testing.T) {
+	conn, err := db.OpenMemory()
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+
+	defer conn.Close()
+
+	policy := report.NormalizeAlertPolicy(config.AlertPolicy{}) //nolint:exhaustruct
+
+	if recoveries := report.CollectRecoveries(policy, conn, 0); recoveries != nil {
+		t.Fatalf("expected no recoveries without run, got %+v", recoveries)
+	}
+}
+
+// insertExecution stores a run with a single execution of the status and
+// returns the run id.
+func insertExecution(t *testing.T, conn *sqlite.Conn, status string) int64 {
+	t.Helper()
+
+	runID, err := db.InsertRun(conn, "test", "", "")
+	if err != nil {
+		t.Fatalf("insert run: %v", err)
+	}
+
+	_, err = db.InsertExecution(conn, &db.Execution{ //nolint:exhaustruct
+		RunID:         runID,
+		RequestID:     "0f1e2d3c4b",
+		TestCaseIndex: -1,
+		Method:        "GET",
+		Endpoint:      "/users",
+		Status:        status,
+	})
+	if err != nil {
+		t.Fatalf("insert execution: %v", err)
+	}
+
+	return runID
+}
@@ -2,7 +2,9 @@ package report
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/sven-seyfert/apiprobe/internal/config"
@@ -14,6 +16,9 @@ type Result struct {
 	RequestErrorCount        int
 	FormatResponseErrorCount int
 	ChangedFilesCount        int
+	LatencyFailedCount       int
+	LatencyDegradedCount     int
+	HookErrorCount           int
 }
 
 // IncreaseRequestErrorCount increments the Result counter for failed HTTP requests.
@@ -31,56 +36,223 @@ func (res *Result) IncreaseChangedFilesCount() {
 	res.ChangedFilesCount++
 }
 
+// IncreaseHookErrorCount increments the Result counter for failed setup or teardown hooks.
+func (res *Result) IncreaseHookErrorCount() {
+	res.HookErrorCount++
+}
+
+// IncreaseLatencyCount increments the Result counter matching the given
+// latency state (LatencyFailed or LatencyDegraded).
+func (res *Result) IncreaseLatencyCount(latency string) {
+	switch latency {
+	case LatencyFailed:
+		res.LatencyFailedCount++
+	case LatencyDegraded:
+		res.LatencyDegradedCount++
+	}
+}
+
+// HasFailures returns true if any request failed (request, format
+// or latency failure) or any hook failed, otherwise false.
+func (res *Result) HasFailures() bool {
+	return res.RequestErrorCount > 0 || res.FormatResponseErrorCount > 0 || res.LatencyFailedCount > 0 ||
+		res.HookErrorCount > 0
+}
+
+// HasIssues returns true if any failure, changed file or degraded
+// latency was recorded, otherwise false.
+func (res *Result) HasIssues() bool {
+	return res.HasFailures() || res.ChangedFilesCount > 0 || res.LatencyDegradedCount > 0
+}
+
+// Issue types of the report entries.
+const (
+	IssueRequestError = "request-error"
+	IssueFormatError  = "format-error"
+	IssueChanged      = "changed"
+	IssueLatency      = "latency"
+	IssueHookError    = "hook-error"
+)
+
 type Request struct {
-	ID            string `json:"id"`
-	Description   string `json:"description"`
-	URL           string `json:"url"`
-	Endpoint      string `json:"endpoint"`
-	Method        string `json:"method"`
-	StatusCode    string `json:"statusCode"`
-	ErrorResponse string `json:"errorResponse,omitempty"`
-	TestCase      string `json:"testCase,omitempty"`
-	OutputFile    string `json:"outputFile"`
+	ID            string   `json:"id"`
+	Issue         string   `json:"issue"`
+	Description   string   `json:"description"`
+	URL           string   `json:"url"`
+	Endpoint      string   `json:"endpoint"`
+	Method        string   `json:"method"`
+	Tags          []string `json:"tags,omitempty"`
+	StatusCode    string   `json:"statusCode"`
+	ErrorResponse string   `json:"errorResponse,omitempty"`
+	TestCase      string   `json:"testCase,omitempty"`
+	OutputFile    string   `json:"outputFile"`
+	Latency       string   `json:"latency,omitempty"`
+	Timings       *Timings `json:"timings,omitempty"`
+	Flakiness     float64  `json:"flakiness,omitempty"`
+
+	// Steps of a scenario.
+	Steps []ScenarioStep `json:"steps,omitempty"`
+
+	// Hook kind of a failed setup or teardown hook.
+	Hook string `json:"hook,omitempty"`
+
+	// Test case index (-1 for the first request) to look up the run history.
+	testCaseIndex int
 }
 
 type Report struct {
-	Requests []Request `json:"issues"`
+	RunID      int64           `json:"runId,omitempty"`
+	Requests   []Request       `json:"issues"`
+	Recoveries []Recovery      `json:"recoveries,omitempty"`
+	Timings    []RequestTiming `json:"timings,omitempty"`
 }
 
 // AddReportData records a single API request’s result into the Report.
 func (r *Report) AddReportData(
 	req *loader.APIRequest,
+	issue string,
 	statusCode string,
 	errorResponse string,
 	outputFile string,
 	testCaseIndex int,
+	timings *Timings,
 ) {
-	const noTestCaseIndicator = -1
-
-	testCase := req.Request.Name
-
-	if testCaseIndex != noTestCaseIndicator {
-		testCase = req.TestCases[testCaseIndex].Name
+	latency := ""
+	if timings != nil {
+		latency = EvaluateLatency(req, *timings)
 	}
 
 	request := Request{
 		ID:            req.ID,
+		Issue:         issue,
 		Description:   req.Request.Description,
 		URL:           req.Request.BaseURL,
 		Endpoint:      req.Request.Endpoint,
 		Method:        req.Request.Method,
+		Tags:          req.Tags,
 		StatusCode:    statusCode,
 		ErrorResponse: errorResponse,
-		TestCase:      testCase,
+		TestCase:      TestCaseName(req, testCaseIndex),
 		OutputFile:    outputFile,
+		Latency:       latency,
+		Timings:       timings,
+		Flakiness:     0,
+		Steps:         nil,
+		Hook:          "",
+		testCaseIndex: testCaseIndex,
 	}
 
 	r.Requests = append(r.Requests, request)
 }
 
+// Statuses of scenario steps.
+const (
+	StepPassed  = "passed"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// ScenarioStep is the outcome of a single step of a scenario.
+type ScenarioStep struct {
+	Name       string  `json:"name"`
+	Method     string  `json:"method"`
+	Endpoint   string  `json:"endpoint"`
+	Status     string  `json:"status"`
+	StatusCode string  `json:"statusCode,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs,omitempty"`
+	Cleanup    bool    `json:"cleanup,omitempty"`
+}
+
+// AddScenarioData records a failed scenario as a single request error with
+// the outcome of all steps and returns the entry. The status code and error
+// response are the ones of the first failed step.
+func (r *Report) AddScenarioData(req *loader.APIRequest, steps []ScenarioStep) Request {
+	request := Request{
+		ID:            req.ID,
+		Issue:         IssueRequestError,
+		Description:   req.Request.Description,
+		URL:           "",
+		Endpoint:      "",
+		Method:        "SCENARIO",
+		Tags:          req.Tags,
+		StatusCode:    "",
+		ErrorResponse: "",
+		TestCase:      req.Request.Name,
+		OutputFile:    "",
+		Latency:       "",
+		Timings:       nil,
+		Flakiness:     0,
+		Steps:         steps,
+		Hook:          "",
+		testCaseIndex: -1,
+	}
+
+	for idx, step := range steps {
+		if step.Status == StepFailed {
+			request.Endpoint = step.Endpoint
+			request.StatusCode = step.StatusCode
+			request.ErrorResponse = fmt.Sprintf(`step %d "%s": %s`, idx+1, step.Name, step.Error)
+
+			break
+		}
+	}
+
+	r.Requests = append(r.Requests, request)
+
+	return request
+}
+
+// AddHookData records a failed setup or teardown hook (separately from the
+// request errors of the probes and without latency evaluation).
+func (r *Report) AddHookData(req *loader.APIRequest, statusCode string, errorResponse string) {
+	r.AddReportData(req, IssueHookError, statusCode, errorResponse, "", -1, nil)
+	r.Requests[len(r.Requests)-1].Hook = req.Hook
+}
+
+// IsFailure returns true if the issue is a failure (request error, format
+// error, hook error or failed latency), false for changes and degraded latency.
+func (req *Request) IsFailure() bool {
+	return req.Issue == IssueRequestError || req.Issue == IssueFormatError || req.Issue == IssueHookError ||
+		req.Latency == LatencyFailed
+}
+
+// TestCaseName returns the name of the test case by index or the
+// request name in case of the first (main) request.
+func TestCaseName(req *loader.APIRequest, testCaseIndex int) string {
+	const noTestCaseIndicator = -1
+
+	if testCaseIndex == noTestCaseIndicator {
+		return req.Request.Name
+	}
+
+	return req.TestCases[testCaseIndex].Name
+}
+
+// IssuesJSON returns the recorded issues (without the timings of all
+// requests) as pretty-printed JSON, suitable for notification messages.
+func (r *Report) IssuesJSON() ([]byte, error) {
+	issues := Report{RunID: r.RunID, Requests: r.Requests, Recoveries: r.Recoveries, Timings: nil}
+
+	data, err := json.MarshalIndent(issues, "", "    ")
+	if err != nil {
+		logger.Errorf("Failure on marshal report issues. Error: %v", err)
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
 // SaveToFile creates a file with the given name and writes the report as
 // pretty-printed JSON. Returns an error if file creation or writing fails.
 func (r *Report) SaveToFile(filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil { //nolint:gosec
+		logger.Errorf(`Failed to create directory "%s". Error: %v`, filepath.Dir(filename), err)
+
+		return err
+	}
+
 	file, err := os.Create(filename)
 	if err != nil {
 		logger.Errorf("Failure on create file. Error: %v", err)
@@ -131,7 +303,7 @@ func IsHeartbeatTime(cfg *config.Config) (bool, error) {
 func UpdateHeartbeatTime(cfg *config.Config) error {
 	cfg.Heartbeat.LastHeartbeatTime = time.Now().UTC().Format(time.RFC3339)
 
-	file, err := os.Create("./config/apiprobe.json")
+	file, err := os.Create(cfg.Locations.Config)
 	if err != nil {
 		logger.Errorf("Failure on create file. Error: %v", err)
 
@@ -0,0 +1,151 @@
+package report
+
+import (
+	"slices"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+)
+
+// channelReport is the part of the result and report routed to a channel.
+type channelReport struct {
+	channel string
+	result  *Result
+	report  *Report
+}
+
+// routeReport splits the issues and recoveries of the report by the routing
+// rules into channel reports. Issues without a matching route are sent to
+// the default channel. Recoveries follow the routes of their ID and tags
+// (the issue type is ignored). The default channel is always the first
+// channel report (e.g. for the heartbeat), routed channels without issues
+// and recoveries are omitted.
+func routeReport(routes []config.Route, res *Result, rep *Report, defaultChannel string) []channelReport {
+	if len(routes) == 0 {
+		return []channelReport{{channel: defaultChannel, result: res, report: rep}}
+	}
+
+	channels := []string{defaultChannel}
+	reports := map[string]*Report{defaultChannel: newChannelReport(rep)}
+
+	addTo := func(targets []string, add func(channelRep *Report)) {
+		if len(targets) == 0 {
+			targets = []string{defaultChannel}
+		}
+
+		for _, channel := range targets {
+			if _, exists := reports[channel]; !exists {
+				channels = append(channels, channel)
+				reports[channel] = newChannelReport(rep)
+			}
+
+			add(reports[channel])
+		}
+	}
+
+	for _, issue := range rep.Requests {
+		targets := matchingChannels(routes, issue.ID, issue.Tags, issue.Issue)
+		addTo(targets, func(channelRep *Report) { channelRep.Requests = append(channelRep.Requests, issue) })
+	}
+
+	for _, recovery := range rep.Recoveries {
+		targets := matchingChannels(routes, recovery.ID, recovery.Tags, "")
+		addTo(targets, func(channelRep *Report) { channelRep.Recoveries = append(channelRep.Recoveries, recovery) })
+	}
+
+	channelReports := make([]channelReport, 0, len(channels))
+
+	for _, channel := range channels {
+		channelRep := reports[channel]
+
+		if channel == defaultChannel {
+			// The default channel keeps the timings of all requests.
+			channelRep.Timings = rep.Timings
+			channelReports = append(channelReports, channelReport{channel: channel, result: resultOf(channelRep), report: channelRep})
+
+			continue
+		}
+
+		if len(channelRep.Requests) == 0 && len(channelRep.Recoveries) == 0 {
+			continue
+		}
+
+		channelRep.Timings = timingsOf(rep.Timings, channelRep.Requests)
+		channelReports = append(channelReports, channelReport{channel: channel, result: resultOf(channelRep), report: channelRep})
+	}
+
+	return channelReports
+}
+
+// matchingChannels returns the (distinct) channels of all routes matching
+// the issue. An empty issue type (e.g. of a recovery) matches any route.
+func matchingChannels(routes []config.Route, id string, tags []string, issue string) []string {
+	var channels []string
+
+	for _, route := range routes {
+		if len(route.IDs) > 0 && !slices.Contains(route.IDs, id) {
+			continue
+		}
+
+		if len(route.Tags) > 0 && !slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(route.Tags, tag) }) {
+			continue
+		}
+
+		if issue != "" && len(route.Issues) > 0 && !slices.Contains(route.Issues, issue) {
+			continue
+		}
+
+		for _, channel := range route.Channels {
+			if !slices.Contains(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+
+	return channels
+}
+
+// newChannelReport returns an empty report of the same run.
+func newChannelReport(rep *Report) *Report {
+	return &Report{RunID: rep.RunID, Requests: nil, Recoveries: nil, Timings: nil}
+}
+
+// resultOf counts the issues of the report, like the exec package does
+// while processing the requests.
+func resultOf(rep *Report) *Result {
+	res := &Result{}
+
+	for _, issue := range rep.Requests {
+		switch issue.Issue {
+		case IssueRequestError:
+			res.IncreaseRequestErrorCount()
+		case IssueFormatError:
+			res.IncreaseFormatErrorCount()
+		case IssueChanged:
+			res.IncreaseChangedFilesCount()
+		case IssueHookError:
+			res.IncreaseHookErrorCount()
+		}
+
+		// Request and hook errors are not counted as latency issues (see exec package).
+		if issue.Issue != IssueRequestError && issue.Issue != IssueHookError {
+			res.IncreaseLatencyCount(issue.Latency)
+		}
+	}
+
+	return res
+}
+
+// timingsOf returns the timings of the requests which have an issue.
+func timingsOf(timings []RequestTiming, issues []Request) []RequestTiming {
+	var filtered []RequestTiming
+
+	for _, timing := range timings {
+		if slices.ContainsFunc(issues, func(issue Request) bool {
+			return issue.ID == timing.ID && issue.TestCase == timing.TestCase
+		}) {
+			filtered = append(filtered, timing)
+		}
+	}
+
+	return filtered
+}
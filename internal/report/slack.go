@@ -0,0 +1,255 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sven-seyfert/apiprobe/internal/config"
+)
+
+// Slack attachment colors (traffic light).
+const (
+	slackColorFailure = "#E01E5A"
+	slackColorWarning = "#ECB22E"
+)
+
+// Slack message limits, see https://api.slack.com/reference/block-kit/blocks.
+const (
+	slackMaxSectionChars = 3000
+	slackMaxIssueBlocks  = 40
+)
+
+// slackNotifier sends Block Kit messages to Slack incoming webhooks.
+type slackNotifier struct {
+	webhooks map[string]string
+}
+
+// Name returns the name of the notification tool.
+func (n *slackNotifier) Name() string {
+	return "Slack"
+}
+
+// Deliveries returns the heartbeat, recovery or report message
+// (depending on the notice kind) for the webhook of the channel.
+func (n *slackNotifier) Deliveries(notice *Notice, channelName string) ([]Delivery, error) {
+	hostnameMessage := fmt.Sprintf("Message from: *%s* (hostname)", notice.Hostname)
+
+	var webhookPayload []byte
+
+	switch notice.Kind {
+	case KindHeartbeat:
+		webhookPayload = buildSlackHeartbeatPayload(hostnameMessage)
+	case KindRecovery:
+		webhookPayload = buildSlackRecoveryPayload(notice.Report, notice.RunName, hostnameMessage)
+	default:
+		webhookPayload = buildSlackReportPayload(
+			notice.Result, notice.Report, notice.RunName, notice.ReportFilePath, hostnameMessage)
+	}
+
+	return webhookDeliveries(n.Name(), n.webhooks, channelName, webhookPayload), nil
+}
+
+// buildSlackHeartbeatPayload creates the Block Kit payload for a heartbeat
+// notification. Returns the payload as a byte slice.
+func buildSlackHeartbeatPayload(hostnameMessage string) []byte {
+	payload := map[string]any{
+		"text": "💙 Heartbeat: still alive",
+		"blocks": []any{
+			slackHeader("💙 " + config.Version),
+			slackSection("Heartbeat: *still alive*"),
+			slackContext(hostnameMessage),
+		},
+	}
+
+	webhookPayload, _ := json.Marshal(payload)
+
+	return webhookPayload
+}
+
+// buildSlackReportPayload creates the Block Kit payload for a report
+// notification. The summary is sent as blocks, the issues as (collapsible)
+// attachment colored by the traffic light. Issues which exceed the message
+// limits are listed in a shortened form. Returns the payload as a byte slice.
+func buildSlackReportPayload(
+	res *Result,
+	rep *Report,
+	runName string,
+	reportFilePath string,
+	hostnameMessage string,
+) []byte {
+	trafficLight := "🔴"
+	color := slackColorFailure
+
+	if !res.HasFailures() {
+		trafficLight = "🟡"
+		color = slackColorWarning
+	}
+
+	blocks := []any{slackHeader(trafficLight + " " + config.Version)}
+
+	if runName != "" {
+		blocks = append(blocks, slackContext("`"+runName+"`"))
+	}
+
+	blocks = append(blocks,
+		map[string]any{
+			"type": "section",
+			"fields": []any{
+				slackText(fmt.Sprintf("Files with changed content: *%d*", res.ChangedFilesCount)),
+				slackText(fmt.Sprintf("Request errors: *%d*", res.RequestErrorCount)),
+				slackText(fmt.Sprintf("Format response errors: *%d*", res.FormatResponseErrorCount)),
+				slackText(fmt.Sprintf("Latency failed: *%d*", res.LatencyFailedCount)),
+				slackText(fmt.Sprintf("Latency degraded: *%d*", res.LatencyDegradedCount)),
+				slackText(fmt.Sprintf("Hook errors: *%d*", res.HookErrorCount)),
+			},
+		},
+		slackContext("📄 _"+reportFilePath+"_"),
+	)
+
+	if slowest := buildSlowestRequestsMarkdown(rep); slowest != "" {
+		blocks = append(blocks, slackSection(toSlackMarkdown(slowest)))
+	}
+
+	if recoveries := buildRecoveriesMarkdown(rep); recoveries != "" {
+		blocks = append(blocks, slackSection(toSlackMarkdown(recoveries)))
+	}
+
+	blocks = append(blocks, slackContext(hostnameMessage))
+
+	payload := map[string]any{
+		"text":        fmt.Sprintf("%s %s: %d issues", trafficLight, config.Version, len(rep.Requests)),
+		"blocks":      blocks,
+		"attachments": buildSlackIssueAttachments(rep, color),
+	}
+
+	webhookPayload, _ := json.Marshal(payload)
+
+	return webhookPayload
+}
+
+// buildSlackRecoveryPayload creates the Block Kit payload for a recovery
+// notification, sent if endpoints recovered and no other issues exist.
+// Returns the payload as a byte slice.
+func buildSlackRecoveryPayload(rep *Report, runName string, hostnameMessage string) []byte {
+	blocks := []any{slackHeader("🟢 " + config.Version)}
+
+	if runName != "" {
+		blocks = append(blocks, slackContext("`"+runName+"`"))
+	}
+
+	blocks = append(blocks, slackSection(toSlackMarkdown(buildRecoveriesMarkdown(rep))), slackContext(hostnameMessage))
+
+	payload := map[string]any{
+		"text":   fmt.Sprintf("🟢 %s: %d endpoints recovered", config.Version, len(rep.Recoveries)),
+		"blocks": blocks,
+	}
+
+	webhookPayload, _ := json.Marshal(payload)
+
+	return webhookPayload
+}
+
+// buildSlackIssueAttachments returns the issues as attachments. Slack shows
+// long attachments collapsed ("Show more"). Each issue is a section block,
+// issues beyond the block limit are listed as shortened text attachment.
+func buildSlackIssueAttachments(rep *Report, color string) []any {
+	const maxErrorResponseChars = 500
+
+	blocks := make([]any, 0, len(rep.Requests))
+	overflow := make([]string, 0)
+
+	for idx, issue := range rep.Requests {
+		if idx >= slackMaxIssueBlocks {
+			overflow = append(overflow, fmt.Sprintf("• *%s* `%s %s` (%s)", issue.Issue, issue.Method, issue.Endpoint, issue.ID))
+
+			continue
+		}
+
+		var builder strings.Builder
+
+		fmt.Fprintf(&builder, "*%s* `%s %s`\nID: `%s`", issue.Issue, issue.Method, issue.Endpoint, issue.ID)
+
+		if issue.TestCase != "" {
+			fmt.Fprintf(&builder, " | Test case: _%s_", issue.TestCase)
+		}
+
+		if issue.StatusCode != "" {
+			fmt.Fprintf(&builder, " | Status code: *%s*", issue.StatusCode)
+		}
+
+		if issue.Timings != nil {
+			fmt.Fprintf(&builder, " | Duration: *%.0fms*", issue.Timings.TotalMs)
+		}
+
+		if issue.Latency != "" {
+			fmt.Fprintf(&builder, " (%s)", issue.Latency)
+		}
+
+		if issue.Flakiness > 0 {
+			fmt.Fprintf(&builder, " | Flakiness: *%.2f*", issue.Flakiness)
+		}
+
+		if issue.OutputFile != "" {
+			fmt.Fprintf(&builder, "\nOutput file: _%s_", issue.OutputFile)
+		}
+
+		if issue.ErrorResponse != "" {
+			fmt.Fprintf(&builder, "\n```%s```", truncateText(issue.ErrorResponse, maxErrorResponseChars))
+		}
+
+		blocks = append(blocks, slackSection(builder.String()))
+	}
+
+	attachments := []any{map[string]any{"color": color, "blocks": blocks}}
+
+	if len(overflow) > 0 {
+		text := fmt.Sprintf("%d more issues (see report file):\n%s", len(overflow), strings.Join(overflow, "\n"))
+
+		attachments = append(attachments, map[string]any{
+			"color":     color,
+			"fallback":  fmt.Sprintf("%d more issues", len(overflow)),
+			"text":      truncateText(text, slackMaxSectionChars),
+			"mrkdwn_in": []string{"text"},
+		})
+	}
+
+	return attachments
+}
+
+// toSlackMarkdown converts the shared (WebEx) markdown to Slack mrkdwn,
+// which uses single asterisks for bold text.
+func toSlackMarkdown(markdown string) string {
+	return strings.TrimSpace(strings.ReplaceAll(markdown, "__", "*"))
+}
+
+// slackHeader returns a header block (plain text only, limited to 150 characters).
+func slackHeader(text string) map[string]any {
+	const maxHeaderChars = 150
+
+	return map[string]any{
+		"type": "header",
+		"text": map[string]any{"type": "plain_text", "text": truncateText(text, maxHeaderChars), "emoji": true},
+	}
+}
+
+// slackSection returns a section block with mrkdwn text.
+func slackSection(text string) map[string]any {
+	return map[string]any{
+		"type": "section",
+		"text": slackText(truncateText(text, slackMaxSectionChars)),
+	}
+}
+
+// slackContext returns a context block with a single mrkdwn element.
+func slackContext(text string) map[string]any {
+	return map[string]any{
+		"type":     "context",
+		"elements": []any{slackText(text)},
+	}
+}
+
+// slackText returns a mrkdwn text object.
+func slackText(text string) map[string]any {
+	return map[string]any{"type": "mrkdwn", "text": text}
+}
@@ -0,0 +1,61 @@
+package report_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+const testSlackHostnameMessage = "Message from: *probe-01* (hostname)"
+
+func TestSlackReportPayload(t *testing.T) {
+	res, rep := failureReport()
+
+	payload := report.BuildSlackReportPayload(res, rep, "nightly", "./reports/report.json", testSlackHostnameMessage)
+
+	assertGolden(t, "slack-report.golden.json", payload)
+}
+
+func TestSlackRecoveryPayload(t *testing.T) {
+	payload := report.BuildSlackRecoveryPayload(recoveryReport(), "nightly", testSlackHostnameMessage)
+
+	assertGolden(t, "slack-recovery.golden.json", payload)
+}
+
+// TestSlackReportPayloadLimits covers the truncated error response and the
+// issues beyond the block limit, which are listed in an overflow attachment.
+func TestSlackReportPayloadLimits(t *testing.T) {
+	const issueCount = 42
+
+	rep := &report.Report{RunID: 9}
+
+	for idx := range issueCount {
+		req := &loader.APIRequest{ID: fmt.Sprintf("%010d", idx)}
+		req.Request.Method = "GET"
+		req.Request.Endpoint = fmt.Sprintf("/items/%d", idx)
+
+		errorResponse := ""
+		if idx == 0 {
+			errorResponse = strings.Repeat("x", 600)
+		}
+
+		rep.AddReportData(req, report.IssueRequestError, "500", errorResponse, "", -1, nil)
+	}
+
+	res := &report.Result{RequestErrorCount: issueCount}
+
+	payload := report.BuildSlackReportPayload(res, rep, "nightly", "./reports/report.json", testSlackHostnameMessage)
+
+	if !strings.Contains(string(payload), "2 more issues (see report file):") {
+		t.Errorf("payload has no overflow attachment for the issues beyond the block limit")
+	}
+
+	if strings.Contains(string(payload), strings.Repeat("x", 501)) {
+		t.Errorf("error response is not truncated to 500 characters")
+	}
+
+	assertGolden(t, "slack-report-limits.golden.json", payload)
+}
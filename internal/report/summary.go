@@ -0,0 +1,43 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sven-seyfert/apiprobe/internal/exitcode"
+)
+
+// ExitCode returns the process exit code of the result: exitcode.Failures
+// for request, format, latency or hook failures, exitcode.Changes for changed
+// output files, otherwise exitcode.Success. Degraded latency is no failure.
+func (res *Result) ExitCode() int {
+	switch {
+	case res.HasFailures():
+		return exitcode.Failures
+	case res.ChangedFilesCount > 0:
+		return exitcode.Changes
+	default:
+		return exitcode.Success
+	}
+}
+
+// SummaryLine returns the final, machine-parseable summary of the run as
+// single line of space-separated key=value pairs, prefixed by
+// "apiprobe-summary" (e.g. to gate CI pipelines by grep or awk).
+func SummaryLine(res *Result, runID int64, executed int, exitCode int, duration time.Duration) string {
+	return fmt.Sprintf(
+		"apiprobe-summary status=%s exit_code=%d run_id=%d requests=%d request_errors=%d format_errors=%d "+
+			"changed=%d latency_failed=%d latency_degraded=%d hook_errors=%d duration_ms=%d",
+		exitcode.Name(exitCode),
+		exitCode,
+		runID,
+		executed,
+		res.RequestErrorCount,
+		res.FormatResponseErrorCount,
+		res.ChangedFilesCount,
+		res.LatencyFailedCount,
+		res.LatencyDegradedCount,
+		res.HookErrorCount,
+		duration.Milliseconds(),
+	)
+}
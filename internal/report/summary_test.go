@@ -0,0 +1,44 @@
+package report_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sven-seyfert/apiprobe/internal/exitcode"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+func TestResultExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *report.Result
+		expected int
+	}{
+		{name: "success", result: &report.Result{}, expected: exitcode.Success},
+		{name: "changes", result: &report.Result{ChangedFilesCount: 1}, expected: exitcode.Changes},
+		{name: "failures win over changes", result: &report.Result{ChangedFilesCount: 1, RequestErrorCount: 1}, expected: exitcode.Failures},
+	}
+
+	for _, test := range tests {
+		if code := test.result.ExitCode(); code != test.expected {
+			t.Errorf("%s: exit code %d, expected %d", test.name, code, test.expected)
+		}
+	}
+}
+
+func TestSummaryLine(t *testing.T) {
+	res := &report.Result{RequestErrorCount: 2}
+
+	line := report.SummaryLine(res, 7, 12, res.ExitCode(), 1500*time.Millisecond)
+
+	expected := "apiprobe-summary status=failures exit_code=2 run_id=7 requests=12 request_errors=2 format_errors=0 " +
+		"changed=0 latency_failed=0 latency_degraded=0 hook_errors=0 duration_ms=1500"
+	if line != expected {
+		t.Errorf("summary line %q, expected %q", line, expected)
+	}
+
+	if strings.Contains(line, "\n") {
+		t.Error("summary line contains a line break")
+	}
+}
@@ -0,0 +1,109 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+)
+
+const (
+	LatencyDegraded = "degraded"
+	LatencyFailed   = "failed"
+)
+
+// Timings holds the phase durations (in milliseconds) of a single request.
+type Timings struct {
+	DNSMs     float64 `json:"dnsMs"`
+	ConnectMs float64 `json:"connectMs"`
+	TLSMs     float64 `json:"tlsMs"`
+
+	// TTFBMs is the wait for the first response byte after the connection
+	// is established (streams: the first message after the request start).
+	TTFBMs  float64 `json:"ttfbMs"`
+	TotalMs float64 `json:"totalMs"`
+}
+
+// RequestTiming records the timings of one executed request (or test case).
+type RequestTiming struct {
+	ID       string  `json:"id"`
+	Endpoint string  `json:"endpoint"`
+	Method   string  `json:"method"`
+	TestCase string  `json:"testCase,omitempty"`
+	Latency  string  `json:"latency,omitempty"`
+	Timings  Timings `json:"timings"`
+}
+
+// EvaluateLatency compares the total duration against the 'maxDurationMs'
+// thresholds of the request definition. Returns LatencyFailed, LatencyDegraded
+// or an empty string if no threshold is exceeded (or none is defined).
+func EvaluateLatency(req *loader.APIRequest, timings Timings) string {
+	thresholds := req.MaxDurationMs
+
+	if thresholds.Failed > 0 && timings.TotalMs > float64(thresholds.Failed) {
+		return LatencyFailed
+	}
+
+	if thresholds.Degraded > 0 && timings.TotalMs > float64(thresholds.Degraded) {
+		return LatencyDegraded
+	}
+
+	return ""
+}
+
+// AddTiming records the timings of an executed request into the Report.
+func (r *Report) AddTiming(req *loader.APIRequest, timings Timings, testCaseIndex int) {
+	r.Timings = append(r.Timings, RequestTiming{
+		ID:       req.ID,
+		Endpoint: req.Request.Endpoint,
+		Method:   req.Request.Method,
+		TestCase: TestCaseName(req, testCaseIndex),
+		Latency:  EvaluateLatency(req, timings),
+		Timings:  timings,
+	})
+}
+
+// SlowestRequests returns up to count recorded request timings,
+// ordered by total duration (slowest first).
+func (r *Report) SlowestRequests(count int) []RequestTiming {
+	sorted := make([]RequestTiming, len(r.Timings))
+	copy(sorted, r.Timings)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timings.TotalMs > sorted[j].Timings.TotalMs
+	})
+
+	if len(sorted) > count {
+		sorted = sorted[:count]
+	}
+
+	return sorted
+}
+
+// buildSlowestRequestsMarkdown returns a markdown list of the slowest
+// requests of the report or an empty string if no timings exist.
+func buildSlowestRequestsMarkdown(rep *Report) string {
+	const slowestRequestsCount = 3
+
+	slowest := rep.SlowestRequests(slowestRequestsCount)
+	if len(slowest) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("\nSlowest endpoints:\n")
+
+	for _, timing := range slowest {
+		fmt.Fprintf(&builder, "- `%s %s` __%.0fms__", timing.Method, timing.Endpoint, timing.Timings.TotalMs)
+
+		if timing.Latency != "" {
+			fmt.Fprintf(&builder, " (%s)", timing.Latency)
+		}
+
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
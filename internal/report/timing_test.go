@@ -0,0 +1,37 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/sven-seyfert/apiprobe/internal/loader"
+	"github.com/sven-seyfert/apiprobe/internal/report"
+)
+
+func TestEvaluateLatency(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds loader.DurationThresholds
+		totalMs    float64
+		want       string
+	}{
+		{"no thresholds", loader.DurationThresholds{}, 5000, ""},
+		{"below degraded", loader.DurationThresholds{Degraded: 200, Failed: 1000}, 150, ""},
+		{"at degraded", loader.DurationThresholds{Degraded: 200, Failed: 1000}, 200, ""},
+		{"above degraded", loader.DurationThresholds{Degraded: 200, Failed: 1000}, 201, report.LatencyDegraded},
+		{"at failed", loader.DurationThresholds{Degraded: 200, Failed: 1000}, 1000, report.LatencyDegraded},
+		{"above failed", loader.DurationThresholds{Degraded: 200, Failed: 1000}, 1001, report.LatencyFailed},
+		{"only failed", loader.DurationThresholds{Degraded: 0, Failed: 1000}, 1500, report.LatencyFailed},
+		{"only degraded", loader.DurationThresholds{Degraded: 200, Failed: 0}, 1500, report.LatencyDegraded},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &loader.APIRequest{MaxDurationMs: tc.thresholds} //nolint:exhaustruct
+
+			got := report.EvaluateLatency(req, report.Timings{TotalMs: tc.totalMs}) //nolint:exhaustruct
+			if got != tc.want {
+				t.Errorf("expected latency %q for %.0fms, got %q", tc.want, tc.totalMs, got)
+			}
+		})
+	}
+}
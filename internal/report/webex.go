@@ -1,86 +1,51 @@
 package report
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/sven-seyfert/apiprobe/internal/config"
-	"github.com/sven-seyfert/apiprobe/internal/logger"
-
-	"zombiezen.com/go/sqlite"
 )
 
-// sendWebExNotifications sends a notification to WebEx based on the result and report.
-// It sends either a heartbeat or a report payload depending on the result counts.
-func sendWebExNotifications(
-	ctx context.Context,
-	cfg *config.Config,
-	conn *sqlite.Conn,
-	res *Result,
-	rep *Report,
-	runName string,
-	channelName string,
-) {
-	const notificationTool = "WebEx"
-
-	webhookURL, exists := cfg.Notification.WebEx.Webhooks[channelName]
-	if !exists {
-		logger.Warnf(notificationTool+" webhook channel '%s' not found in config. No notification sent.", channelName)
-
-		return
-	}
+// webExNotifier sends markdown messages to WebEx incoming webhooks.
+type webExNotifier struct {
+	webhooks map[string]string
+}
 
-	reportFilePath := buildReportFilePath()
-	hostname, _ := os.Hostname()
-	hostnameMessage := fmt.Sprintf("Message from: __%s__ (hostname)", hostname)
+// Name returns the name of the notification tool.
+func (n *webExNotifier) Name() string {
+	return "WebEx"
+}
 
-	if res.RequestErrorCount == 0 && res.FormatResponseErrorCount == 0 && res.ChangedFilesCount == 0 {
-		webhookPayload := buildWebExHeartbeatPayload(reportFilePath, cfg, hostnameMessage)
-		if webhookPayload != nil {
-			sendNotification(ctx, conn, webhookURL, webhookPayload, notificationTool)
+// Deliveries returns the heartbeat, recovery or report message
+// (depending on the notice kind) for the webhook of the channel.
+func (n *webExNotifier) Deliveries(notice *Notice, channelName string) ([]Delivery, error) {
+	hostnameMessage := fmt.Sprintf("Message from: __%s__ (hostname)", notice.Hostname)
+
+	var webhookPayload []byte
+
+	switch notice.Kind {
+	case KindHeartbeat:
+		webhookPayload = buildWebExHeartbeatPayload(hostnameMessage)
+	case KindRecovery:
+		webhookPayload = buildWebExRecoveryPayload(notice.Report, notice.RunName, hostnameMessage)
+	default:
+		data, err := notice.Report.IssuesJSON()
+		if err != nil {
+			return nil, err
 		}
 
-		return
+		webhookPayload = buildWebExReportPayload(
+			notice.Result, notice.Report, notice.RunName, notice.ReportFilePath, data, hostnameMessage)
 	}
 
-	if err := rep.SaveToFile(reportFilePath); err != nil {
-		logger.Errorf("Error on save file. Error: %v", err)
-
-		return
-	}
-
-	data, err := os.ReadFile(reportFilePath)
-	if err != nil {
-		logger.Errorf("Error on read file. Error: %v", err)
-
-		return
-	}
-
-	webhookPayload := buildWebExReportPayload(res, runName, reportFilePath, data, hostnameMessage)
-
-	sendNotification(ctx, conn, webhookURL, webhookPayload, notificationTool)
+	return webhookDeliveries(n.Name(), n.webhooks, channelName, webhookPayload), nil
 }
 
 // buildWebExHeartbeatPayload creates the payload for a heartbeat notification.
-// Returns the payload as a byte slice, or nil if heartbeat should not be sent.
-func buildWebExHeartbeatPayload(reportFilePath string, cfg *config.Config, hostnameMessage string) []byte {
-	_ = os.Remove(reportFilePath)
-
-	isHeartbeatTime, err := IsHeartbeatTime(cfg)
-	if err != nil {
-		return nil
-	}
-
-	if !isHeartbeatTime {
-		return nil
-	}
-
-	if err = UpdateHeartbeatTime(cfg); err != nil {
-		return nil
-	}
-
+// Returns the payload as a byte slice.
+func buildWebExHeartbeatPayload(hostnameMessage string) []byte {
 	mdMessage := fmt.Sprintf(
 		`{"markdown":"#### 💙 %s\nHeartbeat: __still alive__\n\n%s"}`,
 		config.Version,
@@ -93,9 +58,16 @@ func buildWebExHeartbeatPayload(reportFilePath string, cfg *config.Config, hostn
 // buildWebExReportPayload creates the payload for a report notification
 // including result details and the report file content. Returns the
 // payload as a byte slice.
-func buildWebExReportPayload(res *Result, runName string, reportFilePath string, data []byte, hostnameMessage string) []byte {
+func buildWebExReportPayload(
+	res *Result,
+	rep *Report,
+	runName string,
+	reportFilePath string,
+	data []byte,
+	hostnameMessage string,
+) []byte {
 	trafficLight := "🔴"
-	if res.RequestErrorCount == 0 && res.FormatResponseErrorCount == 0 && res.ChangedFilesCount > 0 {
+	if !res.HasFailures() {
 		trafficLight = "🟡"
 	}
 
@@ -105,21 +77,27 @@ func buildWebExReportPayload(res *Result, runName string, reportFilePath string,
 	}
 
 	mdResult := fmt.Sprintf(
-		"%sFiles with changed content: __%d__\nRequest errors: __%d__\nFormat response errors: __%d__\n\n📄 _%s_",
+		"%sFiles with changed content: __%d__\nRequest errors: __%d__\nFormat response errors: __%d__\n"+
+			"Latency failed: __%d__\nLatency degraded: __%d__\nHook errors: __%d__\n%s\n📄 _%s_",
 		testRunName,
 		res.ChangedFilesCount,
 		res.RequestErrorCount,
 		res.FormatResponseErrorCount,
+		res.LatencyFailedCount,
+		res.LatencyDegradedCount,
+		res.HookErrorCount,
+		buildSlowestRequestsMarkdown(rep),
 		reportFilePath,
 	)
 
 	mdCodeBlock := fmt.Sprintf("```json\n%s\n```", data)
 
 	mdMessage := fmt.Sprintf(
-		"#### %s %s\n%s\n%s\n\n%s",
+		"#### %s %s\n%s\n%s%s\n\n%s",
 		trafficLight,
 		config.Version,
 		mdResult,
+		buildRecoveriesMarkdown(rep),
 		mdCodeBlock,
 		hostnameMessage,
 	)
@@ -132,3 +110,48 @@ func buildWebExReportPayload(res *Result, runName string, reportFilePath string,
 
 	return webhookPayload
 }
+
+// buildWebExRecoveryPayload creates the payload for a recovery notification,
+// sent if endpoints recovered and no other issues exist. Returns the payload
+// as a byte slice.
+func buildWebExRecoveryPayload(rep *Report, runName string, hostnameMessage string) []byte {
+	testRunName := ""
+	if runName != "" {
+		testRunName = fmt.Sprintf("`%s`\n", runName)
+	}
+
+	mdMessage := fmt.Sprintf(
+		"#### 🟢 %s\n%s%s\n%s",
+		config.Version,
+		testRunName,
+		buildRecoveriesMarkdown(rep),
+		hostnameMessage,
+	)
+
+	payload := map[string]string{
+		"markdown": mdMessage,
+	}
+
+	webhookPayload, _ := json.Marshal(payload)
+
+	return webhookPayload
+}
+
+// buildRecoveriesMarkdown returns a markdown list of the recovered
+// endpoints or an empty string if there are none.
+func buildRecoveriesMarkdown(rep *Report) string {
+	if len(rep.Recoveries) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("\nRecovered endpoints:\n")
+
+	for _, recovery := range rep.Recoveries {
+		fmt.Fprintf(&builder, "- ✅ `%s %s` (%s) is back after __%d__ failed runs\n",
+			recovery.Method, recovery.Endpoint, recovery.ID, recovery.Failures)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,107 @@
+package selector
+
+import (
+	"strings"
+)
+
+// Selection selects requests by IDs (or ID prefixes), by tags (any of them)
+// and by a selection expression. A request is selected if it matches every
+// set criterion. An empty selection selects all requests.
+type Selection struct {
+	IDs  []string
+	Tags []string
+	Expr *Expr
+}
+
+// NewSelection returns the selection of the comma-separated IDs (or ID
+// prefixes), the comma-separated tags and the selection expression
+// (each optional). Returns an error if the expression is invalid.
+func NewSelection(ids string, tags string, expression string) (*Selection, error) {
+	selection := &Selection{IDs: SplitList(ids), Tags: SplitList(tags), Expr: nil}
+
+	if strings.TrimSpace(expression) != "" {
+		expr, err := Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		selection.Expr = expr
+	}
+
+	return selection, nil
+}
+
+// IsEmpty returns whether no criterion is set.
+func (s *Selection) IsEmpty() bool {
+	return len(s.IDs) == 0 && len(s.Tags) == 0 && s.Expr == nil
+}
+
+// Match returns whether a request with the ID and tags is selected.
+func (s *Selection) Match(id string, tags []string) bool {
+	if len(s.IDs) > 0 && !matchesAnyIDPrefix(id, s.IDs) {
+		return false
+	}
+
+	if len(s.Tags) > 0 && !containsAny(tags, s.Tags) {
+		return false
+	}
+
+	return s.Expr == nil || s.Expr.Match(id, tags)
+}
+
+// String returns the criteria of the selection (e.g. for log messages).
+func (s *Selection) String() string {
+	var criteria []string
+
+	if len(s.IDs) > 0 {
+		criteria = append(criteria, `ids "`+strings.Join(s.IDs, ", ")+`"`)
+	}
+
+	if len(s.Tags) > 0 {
+		criteria = append(criteria, `tags "`+strings.Join(s.Tags, ", ")+`"`)
+	}
+
+	if s.Expr != nil {
+		criteria = append(criteria, `select "`+s.Expr.String()+`"`)
+	}
+
+	return strings.Join(criteria, " and ")
+}
+
+// SplitList splits a comma-separated list and trims its (non-empty) values.
+func SplitList(list string) []string {
+	var values []string
+
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// matchesAnyIDPrefix returns whether the ID starts with any of the
+// prefixes (a full ID is a prefix of itself), ignoring the case.
+func matchesAnyIDPrefix(id string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(strings.ToLower(id), strings.ToLower(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsAny returns whether the tags contain any of the wanted tags.
+func containsAny(tags []string, wanted []string) bool {
+	for _, tag := range tags {
+		for _, wantedTag := range wanted {
+			if tag == wantedTag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
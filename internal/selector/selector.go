@@ -0,0 +1,223 @@
+package selector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Expr is a parsed boolean selection expression over the tags of a request,
+// like "(reqres or booker) and not slow and env-prod". The operators are
+// 'and', 'or', 'not' (by precedence from low to high: or, and, not) and
+// parentheses. An operand is a tag or 'id:<prefix>' for request IDs
+// starting with the prefix.
+type Expr struct {
+	source string
+	root   node
+}
+
+// node is an element of the expression tree.
+type node interface {
+	match(id string, tags []string) bool
+	sql(columns *sqlColumns) string
+}
+
+type (
+	andNode struct{ left, right node }
+	orNode  struct{ left, right node }
+	notNode struct{ operand node }
+	tagNode struct{ tag string }
+	idNode  struct{ prefix string }
+)
+
+func (n andNode) match(id string, tags []string) bool {
+	return n.left.match(id, tags) && n.right.match(id, tags)
+}
+
+func (n orNode) match(id string, tags []string) bool {
+	return n.left.match(id, tags) || n.right.match(id, tags)
+}
+
+func (n notNode) match(id string, tags []string) bool {
+	return !n.operand.match(id, tags)
+}
+
+func (n tagNode) match(_ string, tags []string) bool {
+	return slices.Contains(tags, n.tag)
+}
+
+func (n idNode) match(id string, _ []string) bool {
+	return strings.HasPrefix(strings.ToLower(id), strings.ToLower(n.prefix))
+}
+
+// Parse parses the selection expression. Returns an error with the
+// position of the first invalid token.
+func Parse(source string) (*Expr, error) {
+	p := &parser{tokens: tokenize(source), pos: 0}
+
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf(`empty selection expression "%s"`, source)
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf(`invalid selection expression "%s": %w`, source, err)
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf(`invalid selection expression "%s": unexpected "%s" at token %d`,
+			source, p.tokens[p.pos], p.pos+1)
+	}
+
+	return &Expr{source: source, root: root}, nil
+}
+
+// Match returns whether a request with the ID and tags matches the expression.
+func (e *Expr) Match(id string, tags []string) bool {
+	return e.root.match(id, tags)
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// tokenize splits the expression into parentheses and words.
+func tokenize(source string) []string {
+	var tokens []string
+
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, char := range source {
+		switch {
+		case char == '(' || char == ')':
+			flush()
+
+			tokens = append(tokens, string(char))
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			flush()
+		default:
+			word.WriteRune(char)
+		}
+	}
+
+	flush()
+
+	return tokens
+}
+
+// parser is a recursive descent parser of the tokens.
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the current token in lower case (empty at the end).
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+
+	return strings.ToLower(p.tokens[p.pos])
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "or" {
+		p.pos++
+
+		right, rightErr := p.parseAnd()
+		if rightErr != nil {
+			return nil, rightErr
+		}
+
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "and" {
+		p.pos++
+
+		right, rightErr := p.parseNot()
+		if rightErr != nil {
+			return nil, rightErr
+		}
+
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek() == "not" {
+		p.pos++
+
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{operand: operand}, nil
+	}
+
+	return p.parseOperand()
+}
+
+func (p *parser) parseOperand() (node, error) {
+	token := p.peek()
+
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected end after token %d", p.pos)
+	case "(":
+		p.pos++
+
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ")" {
+			return nil, fmt.Errorf(`missing ")" at token %d`, p.pos+1)
+		}
+
+		p.pos++
+
+		return inner, nil
+	case ")", "and", "or":
+		return nil, fmt.Errorf(`unexpected "%s" at token %d`, p.tokens[p.pos], p.pos+1)
+	}
+
+	operand := p.tokens[p.pos]
+	p.pos++
+
+	if prefix, isID := strings.CutPrefix(operand, "id:"); isID {
+		if prefix == "" {
+			return nil, fmt.Errorf(`empty ID prefix at token %d`, p.pos)
+		}
+
+		return idNode{prefix: prefix}, nil
+	}
+
+	return tagNode{tag: operand}, nil
+}
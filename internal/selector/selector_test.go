@@ -0,0 +1,145 @@
+package selector_test
+
+import (
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/sven-seyfert/apiprobe/internal/selector"
+)
+
+func TestParseAndMatch(t *testing.T) {
+	expr, err := selector.Parse("(reqres or booker) and not slow and env-prod")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		tags     []string
+		expected bool
+	}{
+		{tags: []string{"reqres", "env-prod"}, expected: true},
+		{tags: []string{"booker", "env-prod"}, expected: true},
+		{tags: []string{"reqres", "env-prod", "slow"}, expected: false},
+		{tags: []string{"reqres"}, expected: false},
+		{tags: []string{"env-prod"}, expected: false},
+	}
+
+	for _, test := range tests {
+		if matched := expr.Match("ff00fceb61", test.tags); matched != test.expected {
+			t.Errorf("tags %v: matched %t, expected %t", test.tags, matched, test.expected)
+		}
+	}
+}
+
+func TestParsePrecedenceAndIDPrefix(t *testing.T) {
+	// 'and' binds stronger than 'or', 'not' stronger than 'and'.
+	expr, err := selector.Parse("a or b and not c or id:ff00")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if !expr.Match("bb5599abcd", []string{"a", "c"}) {
+		t.Error(`expected "a" to match`)
+	}
+
+	if expr.Match("bb5599abcd", []string{"b", "c"}) {
+		t.Error(`expected "b and c" not to match`)
+	}
+
+	if !expr.Match("ff00fceb61", nil) {
+		t.Error("expected id prefix to match")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, source := range []string{"", "a and", "(a or b", "a b", "or a", "not", "id:"} {
+		if _, err := selector.Parse(source); err == nil {
+			t.Errorf("expected error for %q", source)
+		}
+	}
+}
+
+func TestSelectionCombinesCriteria(t *testing.T) {
+	selection, err := selector.NewSelection("ff00, bb55", "reqres", "not slow")
+	if err != nil {
+		t.Fatalf("selection: %v", err)
+	}
+
+	if !selection.Match("ff00fceb61", []string{"reqres"}) {
+		t.Error("expected id prefix and tag to match")
+	}
+
+	if selection.Match("ff00fceb61", []string{"reqres", "slow"}) {
+		t.Error("expected expression to exclude slow requests")
+	}
+
+	if selection.Match("aa11223344", []string{"reqres"}) {
+		t.Error("expected other ids not to match")
+	}
+}
+
+func TestSQLConditionMatchesLikeMatch(t *testing.T) {
+	conn, err := sqlite.OpenConn(":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	defer conn.Close()
+
+	requests := []struct {
+		id   string
+		tags []string
+	}{
+		{"ff00fceb61", []string{"reqres", "env-prod"}},
+		{"FF00aa1122", []string{"booker", "env-prod", "slow"}},
+		{"bb5599abcd", []string{"reqres-v2"}},
+		{"0a1b2c3d4e", nil},
+	}
+
+	selections := []struct{ ids, tags, expression string }{
+		{"", "", ""},
+		{"ff00", "", ""},
+		{"", "reqres,booker", ""},
+		{"", "", "(reqres or booker) and not slow"},
+		{"", "", "not reqres and not id:ff"},
+		{"ff00,bb", "env-prod", "not slow or id:bb"},
+	}
+
+	for _, sel := range selections {
+		selection, err := selector.NewSelection(sel.ids, sel.tags, sel.expression)
+		if err != nil {
+			t.Fatalf("selection: %v", err)
+		}
+
+		for _, req := range requests {
+			joinedTags := ""
+			if len(req.tags) > 0 {
+				joinedTags = "," + strings.Join(req.tags, ",") + ","
+			}
+
+			// Bind the columns as values (the ID and tags placeholders).
+			sqlCondition, sqlArgs := selection.SQLCondition("'"+req.id+"'", "'"+joinedTags+"'")
+
+			var matched bool
+
+			err = sqlitex.ExecuteTransient(conn, "SELECT "+sqlCondition, &sqlitex.ExecOptions{ //nolint:exhaustruct
+				Args: sqlArgs,
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					matched = stmt.ColumnBool(0)
+
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", sqlCondition, err)
+			}
+
+			if want := selection.Match(req.id, req.tags); matched != want {
+				t.Errorf("selection %v, request %s %v: SQL matched %t, expected %t", sel, req.id, req.tags, matched, want)
+			}
+		}
+	}
+}
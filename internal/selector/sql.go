@@ -0,0 +1,86 @@
+package selector
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// sqlColumns holds the columns of the request ID and the joined tags (like
+// ',a,b,') and collects the arguments of the SQL condition.
+type sqlColumns struct {
+	id   string
+	tags string
+	args []any
+}
+
+// SQLCondition returns the selection as SQL condition (SQLite) with its
+// arguments, which matches the same requests as Match, over the column of
+// the request ID and the column of the tags enclosed by commas (like
+// ',a,b,'). An empty selection is the condition "1" (all requests).
+func (s *Selection) SQLCondition(idColumn string, tagsColumn string) (string, []any) {
+	columns := &sqlColumns{id: idColumn, tags: tagsColumn, args: nil}
+	conditions := make([]string, 0, 3) //nolint:mnd
+
+	if len(s.IDs) > 0 {
+		conditions = append(conditions, columns.any(s.IDs, columns.idPrefix))
+	}
+
+	if len(s.Tags) > 0 {
+		conditions = append(conditions, columns.any(s.Tags, columns.tag))
+	}
+
+	if s.Expr != nil {
+		conditions = append(conditions, s.Expr.root.sql(columns))
+	}
+
+	if len(conditions) == 0 {
+		return "1", nil
+	}
+
+	return strings.Join(conditions, " AND "), columns.args
+}
+
+// any returns the condition which matches any of the values.
+func (c *sqlColumns) any(values []string, condition func(value string) string) string {
+	alternatives := make([]string, 0, len(values))
+
+	for _, value := range values {
+		alternatives = append(alternatives, condition(value))
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// idPrefix returns the condition of a request ID prefix (ignoring the case).
+func (c *sqlColumns) idPrefix(prefix string) string {
+	c.args = append(c.args, utf8.RuneCountInString(prefix), strings.ToLower(prefix))
+
+	return "lower(substr(" + c.id + ", 1, ?)) = ?"
+}
+
+// tag returns the condition of a tag (exact match within the joined tags).
+func (c *sqlColumns) tag(tag string) string {
+	c.args = append(c.args, ","+tag+",")
+
+	return "instr(" + c.tags + ", ?) > 0"
+}
+
+func (n andNode) sql(columns *sqlColumns) string {
+	return "(" + n.left.sql(columns) + " AND " + n.right.sql(columns) + ")"
+}
+
+func (n orNode) sql(columns *sqlColumns) string {
+	return "(" + n.left.sql(columns) + " OR " + n.right.sql(columns) + ")"
+}
+
+func (n notNode) sql(columns *sqlColumns) string {
+	return "NOT (" + n.operand.sql(columns) + ")"
+}
+
+func (n tagNode) sql(columns *sqlColumns) string {
+	return columns.tag(n.tag)
+}
+
+func (n idNode) sql(columns *sqlColumns) string {
+	return columns.idPrefix(n.prefix)
+}
@@ -3,185 +3,363 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sven-seyfert/apiprobe/internal/auth"
 	"github.com/sven-seyfert/apiprobe/internal/config"
 	"github.com/sven-seyfert/apiprobe/internal/crypto"
 	"github.com/sven-seyfert/apiprobe/internal/db"
 	"github.com/sven-seyfert/apiprobe/internal/exec"
+	"github.com/sven-seyfert/apiprobe/internal/exitcode"
 	"github.com/sven-seyfert/apiprobe/internal/flags"
 	"github.com/sven-seyfert/apiprobe/internal/loader"
 	"github.com/sven-seyfert/apiprobe/internal/logger"
 	"github.com/sven-seyfert/apiprobe/internal/report"
+	"github.com/sven-seyfert/apiprobe/internal/selector"
 
 	"zombiezen.com/go/sqlite"
 )
 
-// main initializes the logger and database, parses command-line flags loads
-// configuration and seeds the database. It then loads and filters API request
-// definitions, injects secrets, establishes a cancellation-aware context,
-// processes each request and finally sends notifications based on errors
-// or detected changes.
+// main runs APIProbe, prints the summary line on every exit and exits with
+// the exit code of the run (see exitcode package).
 func main() {
-	dbConn, cliFlags, err := initializeServices()
+	startTime := time.Now()
+
+	session, exitCode, err := run()
 	if err != nil {
 		logger.Fatalf("Program exits: %v", err)
+	}
 
-		return
+	res, runID, executed := &report.Result{}, int64(0), 0 //nolint:exhaustruct
+	if session != nil {
+		res, runID, executed = session.Result, session.RunID, session.ExecutedCount
 	}
-	defer dbConn.Close()
 
-	cfg, err := config.Load("./config/apiprobe.json")
-	if err != nil {
-		logger.Fatalf("Program exits: Failed to load config file.")
+	// Final summary on stdout, e.g. to gate CI pipelines.
+	fmt.Println(report.SummaryLine(res, runID, executed, exitCode, time.Since(startTime))) //nolint:forbidigo
 
-		return
-	}
+	os.Exit(exitCode)
+}
 
-	// Handle command-line flags.
-	complete, err := flags.IsNewID(*cliFlags.NewID)
-	if complete || err != nil {
-		return
-	}
+// run parses command-line flags, loads the configuration of the workspace,
+// initializes the logger and database and seeds the database. It then loads and filters API request
+// definitions, injects secrets, establishes a cancellation-aware context,
+// processes each request and finally sends notifications based on errors
+// or detected changes. Returns the session of an executed run (nil for
+// commands, listings and dry runs), the exit code and the error which
+// aborted the run (exit code ConfigError or RuntimeError).
+func run() (*exec.Session, int, error) { //nolint:funlen
+	cliFlags := flags.Init()
 
-	complete, err = flags.IsNewFile(*cliFlags.NewFile)
-	if complete || err != nil {
-		return
+	// Load config of the workspace and resolve the paths (flags, env vars, config keys).
+	cfg, err := config.LoadWorkspace(config.Workspace(*cliFlags.Workspace), cliFlags.Paths())
+	if err != nil {
+		return nil, exitcode.ConfigError, errors.New("failed to load config file")
 	}
 
-	complete, err = flags.IsAddSecret(*cliFlags.AddSecret, dbConn)
-	if complete || err != nil {
-		return
+	dbConn, err := initializeServices(cfg, *cliFlags.DryRun)
+	if err != nil {
+		return nil, exitcode.RuntimeError, err
 	}
+	defer dbConn.Close()
 
-	// Fill database with default seed data.
-	err = db.InsertSeedData(dbConn)
+	// Select requests by IDs (or prefixes), tags and the selection expression.
+	selection, err := selector.NewSelection(*cliFlags.ID, *cliFlags.Tags, *cliFlags.Select)
 	if err != nil {
-		logger.Fatalf("Program exits: Failed to fill database with seed default data.")
+		return nil, exitcode.ConfigError, err
+	}
 
-		return
+	// Handle command-line flags.
+	if complete, err := handleCommands(cliFlags, cfg, selection, dbConn); complete {
+		if err != nil {
+			return nil, exitcode.ConfigError, err
+		}
+
+		return nil, exitcode.Success, nil
+	}
+
+	// Fill database with default seed data (not on a dry run, which writes no files).
+	if !*cliFlags.DryRun {
+		if err = db.InsertSeedData(dbConn, cfg.Locations.Seed); err != nil {
+			return nil, exitcode.RuntimeError, errors.New("failed to fill database with seed default data")
+		}
 	}
 
 	// Load requests from JSON files in the input directory.
-	requests, err := loader.LoadAllRequests()
+	requests, err := loader.LoadAllRequests(cfg.Locations.Input)
 	if err != nil {
-		logger.Fatalf("Program exits: Failed to load API request definitions.")
+		return nil, exitcode.ConfigError, errors.New("failed to load API request definitions")
+	}
 
-		return
+	// Separate the setup and teardown hooks (no probes) from the requests.
+	requests, hooks, err := loader.SplitHooks(requests)
+	if err != nil {
+		return nil, exitcode.ConfigError, errors.New("invalid hooks")
 	}
 
 	// Exclude requests based on IDs and tags.
 	filteredRequests := loader.ExcludeRequestsByID(requests, *cliFlags.ExcludeIDs)
 	filteredRequests = loader.ExcludeRequestsByTags(filteredRequests, *cliFlags.ExcludeTags)
 
-	// Filter requests based on single id (ten character long hex hash) or by flags.
-	filteredRequests, notFound := loader.FilterRequests(filteredRequests, *cliFlags.ID, *cliFlags.Tags)
+	// Filter requests based on the selection (ids or id prefixes, tags and expression).
+	filteredRequests, notFound := loader.FilterRequests(filteredRequests, selection)
 	if notFound {
-		return
+		return nil, exitcode.ConfigError, errors.New("no request matches the selection")
 	}
 
 	// Merge possible pre-requests (prepend) with the filtered requests.
 	preparedRequests, err := loader.MergePreRequests(requests, filteredRequests)
 	if err != nil {
-		logger.Fatalf("Program exits: Failed to gather pre-requests.")
+		return nil, exitcode.ConfigError, errors.New("failed to gather pre-requests")
+	}
 
-		return
+	// Resolve the steps of scenarios (referenced or inline requests).
+	if err = loader.ResolveScenarios(requests, preparedRequests); err != nil {
+		return nil, exitcode.ConfigError, errors.New("failed to resolve scenarios")
 	}
 
 	// Prepare the requests by compacting the JSON POST body,
 	// handling "x-www-form-urlencoded" and POST body test cases.
-	for _, req := range preparedRequests {
-		if err = req.PreparePostBody(); err != nil {
-			logger.Fatalf("Program exits: Failed to prepare the POST body.")
+	if err = prepareRequests(preparedRequests, hooks); err != nil {
+		return nil, exitcode.ConfigError, err
+	}
+
+	// Only list the requests which would be executed (dry-run listing).
+	if *cliFlags.List {
+		flags.PrintRequests(preparedRequests, filteredRequests)
+
+		return nil, exitcode.Success, nil
+	}
+
+	// Execute the templates before the secret placeholders are replaced, so
+	// secrets are never parsed as template (see template function 'secret').
+	if err = prepareTemplates(preparedRequests, hooks, crypto.TemplateSecret(dbConn, *cliFlags.DryRun)); err != nil {
+		return nil, exitcode.ConfigError, err
+	}
+
+	// Only print the execution plan with redacted secrets (dry run).
+	if *cliFlags.DryRun {
+		if _, err = crypto.RedactSecrets(preparedRequests, dbConn); err != nil {
+			return nil, exitcode.RuntimeError, errors.New("failed to resolve secrets in requests")
 		}
 
-		if err = req.PreparePostBodyData(); err != nil {
-			logger.Fatalf("Program exits: Failed to prepare the POST body test cases.")
+		if _, err = crypto.RedactSecrets(hooks.All(), dbConn); err != nil {
+			return nil, exitcode.RuntimeError, errors.New("failed to resolve secrets in hooks")
 		}
+
+		flags.PrintPlan(preparedRequests, filteredRequests, hooks, cfg.Locations.Output)
+
+		return nil, exitcode.Success, nil
 	}
 
 	// Replace secrets placeholders in the requests with actual values.
 	finalRequests, err := crypto.HandleSecrets(preparedRequests, dbConn)
 	if err != nil {
-		logger.Fatalf("Program exits: Failed to handle secrets in requests.")
+		return nil, exitcode.RuntimeError, errors.New("failed to handle secrets in requests")
+	}
 
-		return
+	if _, err = crypto.HandleSecrets(hooks.All(), dbConn); err != nil {
+		return nil, exitcode.RuntimeError, errors.New("failed to handle secrets in hooks")
 	}
 
 	// Only once requests are loaded successfully, set up signal-cancellation context.
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Initializes token store.
-	tokenStore := auth.NewTokenStore()
+	// Initializes the session (result, report, token store and run history entry).
+	session, err := exec.NewSession(dbConn, cfg, *cliFlags.Name)
+	if err != nil {
+		return nil, exitcode.RuntimeError, errors.New("failed to start run history")
+	}
+
+	// Process each API request, optionally with test case variations,
+	// between the setup and teardown hooks.
+	processRequests(ctx, finalRequests, exec.NewHookRunner(hooks, finalRequests, session), session)
+	session.Finish()
 
-	// Process each API request, optionally with test case variations.
-	res, rep := processRequests(ctx, finalRequests, tokenStore, cfg.DebugMode)
+	exitCode := session.Result.ExitCode()
 
 	// Send notification on error case or on changes.
-	report.Notification(ctx, cfg, dbConn, res, rep, *cliFlags.Name, *cliFlags.NotifyChannel)
+	err = report.Notification(ctx, cfg, dbConn, session.Result, session.Report, *cliFlags.Name, *cliFlags.NotifyChannel)
+	if err != nil && exitCode != exitcode.Failures {
+		exitCode = exitcode.DeliveryFailed
+	}
+
+	if ctx.Err() != nil {
+		exitCode = exitcode.Interrupted
+	}
+
+	return session, exitCode, nil
 }
 
-// initializeServices initializes logger, database and CLI flags.
-// Returns database connection, CLI flags and error if initialization fails.
-func initializeServices() (*sqlite.Conn, *flags.CLIFlags, error) {
-	if err := logger.Init(); err != nil {
-		return nil, nil, errors.Join(errors.New("failed to initialize logger: "), err)
+// handleCommands executes the command-line flags which are commands (like
+// --new-id or --history). Returns whether a command was executed and the
+// error of a failed command.
+func handleCommands(
+	cliFlags *flags.CLIFlags,
+	cfg *config.Config,
+	selection *selector.Selection,
+	dbConn *sqlite.Conn,
+) (bool, error) {
+	commands := []func() (bool, error){
+		func() (bool, error) { return flags.IsNewID(*cliFlags.NewID) },
+		func() (bool, error) { return flags.IsNewFile(*cliFlags.NewFile, cfg.Locations.Input) },
+		func() (bool, error) { return flags.IsConvert(*cliFlags.Convert) },
+		func() (bool, error) { return flags.IsAddSecret(*cliFlags.AddSecret, dbConn) },
+		func() (bool, error) { return flags.IsHistory(*cliFlags.History, selection, dbConn) },
+		func() (bool, error) { return flags.IsSnapshotCommand(cliFlags, dbConn, cfg.Locations.Output) },
 	}
 
-	conn, err := db.Init()
-	if err != nil {
-		return nil, nil, errors.Join(errors.New("failed to initialize database: "), err)
+	for _, command := range commands {
+		complete, err := command()
+		if err != nil {
+			return true, fmt.Errorf("command failed: %w", err)
+		}
+
+		if complete {
+			return true, nil
+		}
 	}
 
-	cliFlags := flags.Init()
+	return false, nil
+}
+
+// prepareRequests prepares the POST bodies (and POST body test cases) and
+// the scenario steps of the requests and the POST bodies of the hooks.
+func prepareRequests(requests []*loader.APIRequest, hooks *loader.Hooks) error {
+	for _, req := range requests {
+		if err := req.PreparePostBody(); err != nil {
+			return fmt.Errorf(`failed to prepare the POST body of request "%s"`, req.ID)
+		}
+
+		if err := req.PreparePostBodyData(); err != nil {
+			return fmt.Errorf(`failed to prepare the POST body test cases of request "%s"`, req.ID)
+		}
+
+		if err := req.PrepareScenarioSteps(); err != nil {
+			return fmt.Errorf(`failed to prepare the steps of scenario "%s"`, req.ID)
+		}
+	}
+
+	for _, hook := range hooks.All() {
+		if err := hook.PreparePostBody(); err != nil {
+			return fmt.Errorf(`failed to prepare the POST body of hook "%s"`, hook.ID)
+		}
+	}
+
+	return nil
+}
+
+// prepareTemplates executes the templates of the requests and hooks with the
+// secret lookup (see loader.PrepareTemplates). Returns the error of the first
+// invalid template.
+func prepareTemplates(requests []*loader.APIRequest, hooks *loader.Hooks, secret loader.SecretFunc) error {
+	for _, req := range requests {
+		if err := req.PrepareTemplates(secret); err != nil {
+			return fmt.Errorf(`failed to prepare the templates of request "%s": %w`, req.ID, err)
+		}
+	}
+
+	for _, hook := range hooks.All() {
+		if err := hook.PrepareTemplates(secret); err != nil {
+			return fmt.Errorf(`failed to prepare the templates of hook "%s": %w`, hook.ID, err)
+		}
+	}
 
-	return conn, cliFlags, nil
+	return nil
+}
+
+// initializeServices initializes logger and database at the paths of the config.
+// On a dry run, the logger writes to the console only and the existing database
+// is opened read-only (in a fresh workspace an empty in-memory database).
+// Returns database connection and error if initialization fails.
+func initializeServices(cfg *config.Config, isDryRun bool) (*sqlite.Conn, error) {
+	if isDryRun {
+		conn, err := db.OpenReadOnly(cfg.Locations.Database)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to open database: "), err)
+		}
+
+		return conn, nil
+	}
+
+	if err := logger.Init(cfg.Locations.Logs); err != nil {
+		return nil, errors.Join(errors.New("failed to initialize logger: "), err)
+	}
+
+	conn, err := db.Init(cfg.Locations.Database)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to initialize database: "), err)
+	}
+
+	return conn, nil
 }
 
 // processRequests iterates over the APIRequests, executes
-// each (including test cases), and writes the results. It returns
-// the aggregated Result and Report.
+// each (including test cases), and writes the results into
+// the Result and Report of the session. The teardown hooks
+// run in any case (also after a cancellation).
 func processRequests(
 	ctx context.Context,
 	requests []*loader.APIRequest,
-	tokenStore *auth.TokenStore,
-	debugMode bool,
-) (*report.Result, *report.Report) {
-	res := &report.Result{}
-	rep := &report.Report{}
+	hookRunner *exec.HookRunner,
+	session *exec.Session,
+) {
+	defer hookRunner.Finish(ctx)
+
+	if !hookRunner.Start(ctx) {
+		logger.Warnf("Skip all requests due to failed suite setup.")
+
+		return
+	}
 
 	for idx, req := range requests {
 		if ctx.Err() != nil {
 			logger.Debugf("Received cancellation signal. Stopping request processing.")
 
-			return res, rep
+			return
 		}
 
 		if !req.IsActive {
 			continue
 		}
 
+		if !hookRunner.BeforeRequest(ctx, req) {
+			hookRunner.AfterRequest(ctx, idx, req)
+
+			continue
+		}
+
 		if idx > 0 {
 			logger.NewLine()
 		}
 
 		logger.Infof(`Run: %d, Test case: %d, File: "%s"`, idx+1, 0, req.JSONFilePath)
 
-		if req.PreRequestID != "" {
-			auth.RepaceAuthTokenPlaceholderInRequestHeader(req, tokenStore)
+		if req.PreRequestID != "" || req.IsScenario() {
+			auth.RepaceAuthTokenPlaceholderInRequestHeader(req, session.TokenStore)
+		}
+
+		if req.IsScenario() {
+			exec.ProcessScenario(ctx, idx+1, req, session)
+			hookRunner.AfterRequest(ctx, idx, req)
+
+			continue
 		}
 
 		// Execute first (main) request, regardless of whether additional test cases exist.
-		exec.ProcessFirstRequest(ctx, idx+1, req, nil, res, rep, tokenStore, debugMode)
+		// The request of a data file is a template for the rows (not executed).
+		if !req.IsDataDriven() {
+			exec.ProcessFirstRequest(ctx, idx+1, req, nil, session)
+		}
 
 		// Execute additional requests of the same JSON definition file,
 		// depending on the number of defined test cases.
-		exec.ProcessTestCasesRequests(ctx, req, idx, res, rep, tokenStore, debugMode)
+		exec.ProcessTestCasesRequests(ctx, req, idx, session)
+		hookRunner.AfterRequest(ctx, idx, req)
 	}
-
-	return res, rep
 }